@@ -5,8 +5,12 @@ import (
 	"log"
 	"tatapps/internal/config"
 	"tatapps/internal/database"
+	"tatapps/internal/handlers"
 	"tatapps/internal/routes"
+	"tatapps/internal/services/jobs"
 	"tatapps/internal/services/notification"
+	"tatapps/internal/services/realtime"
+	"tatapps/internal/services/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -36,6 +40,11 @@ func main() {
 		}
 	}
 
+	// Make sure every permission used by the routes exists
+	if err := handlers.SyncPermissions(db); err != nil {
+		log.Fatal("Failed to sync permissions:", err)
+	}
+
 	// Set Gin mode
 	if cfg.AppEnv == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -43,9 +52,13 @@ func main() {
 
 	// Shared services
 	notifService := notification.NewNotificationService(cfg, db)
+	stream := realtime.NewHub(db, database.DSN(cfg))
+	notifService.Inbox.SetStream(stream)
 	scheduler := notification.NewLowStockScheduler(db, notifService)
 	scheduler.Start()
 	defer scheduler.Stop(context.Background())
+	jobRunner := jobs.NewRunner(db, notifService, cfg.JobWorkers, cfg.JobStorageDir)
+	webhooks := webhook.NewDispatcher(db, cfg)
 
 	// Create Gin router
 	router := gin.Default()
@@ -54,7 +67,17 @@ func main() {
 	router.Static("/uploads", "./uploads")
 
 	// Setup routes
-	routes.SetupRoutes(router, db, cfg, notifService)
+	routes.SetupRoutes(router, db, cfg, notifService, jobRunner, webhooks, stream)
+
+	// Start background job workers once all job types are registered
+	jobRunner.Start()
+	defer jobRunner.Stop(context.Background())
+	webhooks.Start()
+	defer webhooks.Stop(context.Background())
+	notifService.Outbox.Start()
+	defer notifService.Outbox.Stop(context.Background())
+	stream.Start()
+	defer stream.Stop(context.Background())
 
 	// Start server
 	log.Printf("Server starting on port %s...", cfg.AppPort)
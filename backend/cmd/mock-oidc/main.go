@@ -0,0 +1,43 @@
+// Command mock-oidc is a minimal OpenID Connect provider for local development
+// and manual testing of SSO login. It signs in whoever submits its login form
+// and must never be exposed publicly.
+//
+// Environment:
+//
+//	MOCK_OIDC_ADDR      listen address (default :9090)
+//	MOCK_OIDC_ISSUER    issuer URL (default http://localhost:9090)
+//	MOCK_OIDC_CLIENT_ID accepted client ID (default tatapps)
+//	MOCK_OIDC_GROUPS    default groups offered on the login form
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"tatapps/internal/services/oidc/oidctest"
+)
+
+func main() {
+	s, err := oidctest.New(
+		getEnv("MOCK_OIDC_ISSUER", "http://localhost:9090"),
+		getEnv("MOCK_OIDC_CLIENT_ID", "tatapps"),
+		os.Getenv("MOCK_OIDC_GROUPS"),
+	)
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	addr := getEnv("MOCK_OIDC_ADDR", ":9090")
+	log.Printf("Mock OIDC provider %s listening on %s (client_id=%s)", s.Issuer, addr, s.ClientID)
+	if err := http.ListenAndServe(addr, s); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"tatapps/internal/config"
 	"tatapps/internal/routes"
+	"tatapps/internal/services/jobs"
 	"tatapps/internal/services/notification"
+	"tatapps/internal/services/realtime"
+	"tatapps/internal/services/webhook"
 )
 
 func main() {
@@ -14,7 +17,11 @@ func main() {
 	cfg := config.LoadConfig()
 	notifService := notification.NewNotificationService(cfg, nil)
 
-	routes.SetupRoutes(router, nil, cfg, notifService)
+	jobRunner := jobs.NewRunner(nil, notifService, cfg.JobWorkers, cfg.JobStorageDir)
+
+	webhooks := webhook.NewDispatcher(nil, cfg)
+
+	routes.SetupRoutes(router, nil, cfg, notifService, jobRunner, webhooks, realtime.NewHub(nil, ""))
 
 	for _, r := range router.Routes() {
 		fmt.Printf("%s %s\n", r.Method, r.Path)
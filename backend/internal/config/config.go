@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -21,8 +22,41 @@ type Config struct {
 	DBSSLMode  string
 
 	// JWT
-	JWTSecret     string
-	JWTExpiration int
+	JWTSecret       string
+	JWTAccessTTL    int // minutes
+	RefreshTokenTTL int // hours
+
+	// Registration
+	AllowRegistration bool   // public self-registration
+	RegistrationRole  string // role given to self-registered users
+	InviteTTL         int    // hours
+
+	// Password reset
+	PasswordResetTTL int // minutes
+
+	// Two-factor authentication
+	TwoFactorEnforced             bool     // require 2FA for roles holding a sensitive permission
+	TwoFactorSensitivePermissions []string // permissions that trigger the requirement
+
+	// Login throttling
+	LoginMaxFailedAttempts int // failures before an account is locked
+	LoginLockoutMinutes    int // first lockout; doubles with each further failure
+	LoginMaxFailedPerIP    int // failures per IP within LoginIPWindowMinutes
+	LoginIPWindowMinutes   int
+
+	// OpenID Connect single sign-on
+	OIDCEnabled          bool
+	OIDCProviderName     string // label of the SSO button
+	OIDCIssuerURL        string
+	OIDCClientID         string
+	OIDCClientSecret     string // optional; PKCE is always used
+	OIDCRedirectURL      string // backend callback registered at the provider
+	OIDCScopes           []string
+	OIDCGroupsClaim      string
+	OIDCRoleMapping      []ClaimMapping // group -> role name, first match wins
+	OIDCWarehouseMapping []ClaimMapping // group -> warehouse codes, "*" for all
+	OIDCDefaultRole      string         // role for users without a mapped group; empty denies them
+	OIDCAutoProvision    bool
 
 	// WhatsApp API
 	WAApiURL   string
@@ -39,11 +73,31 @@ type Config struct {
 
 	// Frontend
 	FrontendURL string
+
+	// Background jobs
+	JobWorkers    int
+	JobStorageDir string
+
+	// Outbound webhooks
+	WebhookWorkers        int
+	WebhookMaxAttempts    int
+	WebhookTimeoutSeconds int
 }
 
 func LoadConfig() *Config {
-	jwtExp, _ := strconv.Atoi(getEnv("JWT_EXPIRATION", "24"))
+	jwtAccessTTL, _ := strconv.Atoi(getEnv("JWT_ACCESS_TTL_MINUTES", "15"))
+	refreshTokenTTL, _ := strconv.Atoi(getEnv("REFRESH_TOKEN_TTL_HOURS", "720"))
+	inviteTTL, _ := strconv.Atoi(getEnv("INVITE_TTL_HOURS", "72"))
+	passwordResetTTL, _ := strconv.Atoi(getEnv("PASSWORD_RESET_TTL_MINUTES", "30"))
+	loginMaxFailedAttempts, _ := strconv.Atoi(getEnv("LOGIN_MAX_FAILED_ATTEMPTS", "5"))
+	loginLockoutMinutes, _ := strconv.Atoi(getEnv("LOGIN_LOCKOUT_MINUTES", "15"))
+	loginMaxFailedPerIP, _ := strconv.Atoi(getEnv("LOGIN_MAX_FAILED_PER_IP", "20"))
+	loginIPWindowMinutes, _ := strconv.Atoi(getEnv("LOGIN_IP_WINDOW_MINUTES", "15"))
 	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	jobWorkers, _ := strconv.Atoi(getEnv("JOB_WORKERS", "2"))
+	webhookWorkers, _ := strconv.Atoi(getEnv("WEBHOOK_WORKERS", "2"))
+	webhookMaxAttempts, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	webhookTimeoutSeconds, _ := strconv.Atoi(getEnv("WEBHOOK_TIMEOUT_SECONDS", "10"))
 
 	return &Config{
 		AppName: getEnv("APP_NAME", "TatApps"),
@@ -58,8 +112,23 @@ func LoadConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiration: jwtExp,
+		JWTSecret:       getEnv("JWT_SECRET", "your-secret-key"),
+		JWTAccessTTL:    jwtAccessTTL,
+		RefreshTokenTTL: refreshTokenTTL,
+
+		AllowRegistration: getEnv("ALLOW_REGISTRATION", "false") == "true",
+		RegistrationRole:  getEnv("REGISTRATION_ROLE", "employee"),
+		InviteTTL:         inviteTTL,
+
+		PasswordResetTTL: passwordResetTTL,
+
+		TwoFactorEnforced:             getEnv("TWO_FACTOR_ENFORCED", "false") == "true",
+		TwoFactorSensitivePermissions: splitList(getEnv("TWO_FACTOR_SENSITIVE_PERMISSIONS", "backup.run,settings.manage,role.manage")),
+
+		LoginMaxFailedAttempts: loginMaxFailedAttempts,
+		LoginLockoutMinutes:    loginLockoutMinutes,
+		LoginMaxFailedPerIP:    loginMaxFailedPerIP,
+		LoginIPWindowMinutes:   loginIPWindowMinutes,
 
 		WAApiURL:   getEnv("WA_API_URL", "https://wa.drpnet.my.id/send-message"),
 		WAApiKey:   getEnv("WA_API_KEY", ""),
@@ -73,9 +142,56 @@ func LoadConfig() *Config {
 		SMTPFromName:  getEnv("SMTP_FROM_NAME", "TatApps"),
 
 		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
+
+		JobWorkers:    jobWorkers,
+		JobStorageDir: getEnv("JOB_STORAGE_DIR", "./storage/jobs"),
+
+		WebhookWorkers:        webhookWorkers,
+		WebhookMaxAttempts:    webhookMaxAttempts,
+		WebhookTimeoutSeconds: webhookTimeoutSeconds,
+
+		OIDCEnabled:          getEnv("OIDC_ENABLED", "false") == "true",
+		OIDCProviderName:     getEnv("OIDC_PROVIDER_NAME", "SSO"),
+		OIDCIssuerURL:        strings.TrimRight(getEnv("OIDC_ISSUER_URL", ""), "/"),
+		OIDCClientID:         getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:     getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:      getEnv("OIDC_REDIRECT_URL", strings.TrimRight(getEnv("APP_URL", "http://localhost:8080"), "/")+"/api/v1/auth/oidc/callback"),
+		OIDCScopes:           splitList(getEnv("OIDC_SCOPES", "openid,email,profile,groups")),
+		OIDCGroupsClaim:      getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:      parseClaimMappings(getEnv("OIDC_ROLE_MAPPING", "")),
+		OIDCWarehouseMapping: parseClaimMappings(getEnv("OIDC_WAREHOUSE_MAPPING", "")),
+		OIDCDefaultRole:      getEnv("OIDC_DEFAULT_ROLE", "employee"),
+		OIDCAutoProvision:    getEnv("OIDC_AUTO_PROVISION", "true") == "true",
 	}
 }
 
+// ClaimMapping maps an identity-provider group to role names or warehouse codes.
+type ClaimMapping struct {
+	Group  string
+	Values []string
+}
+
+// parseClaimMappings parses "group=a|b,other=c" into mappings, keeping their order.
+func parseClaimMappings(value string) []ClaimMapping {
+	var mappings []ClaimMapping
+	for _, entry := range splitList(value) {
+		group, values, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(group) == "" {
+			continue
+		}
+		mapping := ClaimMapping{Group: strings.TrimSpace(group)}
+		for _, v := range strings.Split(values, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				mapping.Values = append(mapping.Values, v)
+			}
+		}
+		if len(mapping.Values) > 0 {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -83,3 +199,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// splitList parses a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -8,16 +8,20 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
-func InitDB(cfg *config.Config) *gorm.DB {
-	dsn := fmt.Sprintf(
+// DSN returns the Postgres connection string for cfg.
+func DSN(cfg *config.Config) string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Jakarta",
 		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode,
 	)
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+func InitDB(cfg *config.Config) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 
@@ -33,10 +37,23 @@ func AutoMigrate(db *gorm.DB) error {
 	// Migrate in correct order to handle foreign key dependencies
 	// Step 1: Base tables with no dependencies
 	log.Println("Migrating Role and Permission tables...")
-	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}); err != nil {
-		log.Println("Error migrating Role/Permission:", err)
+	hadWarehouseScope := db.Migrator().HasTable(&models.Role{}) && db.Migrator().HasColumn(&models.Role{}, "WarehouseScoped")
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.PermissionMigration{}); err != nil {
+		log.Println("Error migrating Role/Permission/PermissionMigration:", err)
 		return err
 	}
+	// Warehouse scoping used to be tied to the "employee" role name; keep that role
+	// restricted and grant the stock movements it was allowed through that name.
+	if !hadWarehouseScope {
+		if err := db.Model(&models.Role{}).Where("LOWER(name) = ?", "employee").Update("warehouse_scoped", true).Error; err != nil {
+			log.Println("Error backfilling Role warehouse scope:", err)
+			return err
+		}
+		if err := grantEmployeeStockPermissions(db); err != nil {
+			log.Println("Error granting employee stock permissions:", err)
+			return err
+		}
+	}
 	log.Println("Role and Permission tables migrated successfully")
 
 	// Step 2: User table (depends on Role)
@@ -47,6 +64,48 @@ func AutoMigrate(db *gorm.DB) error {
 	}
 	log.Println("User table migrated successfully")
 
+	log.Println("Migrating UserSession table...")
+	if err := db.AutoMigrate(&models.UserSession{}); err != nil {
+		log.Println("Error migrating UserSession:", err)
+		return err
+	}
+	log.Println("UserSession table migrated successfully")
+
+	log.Println("Migrating RecoveryCode table...")
+	if err := db.AutoMigrate(&models.RecoveryCode{}); err != nil {
+		log.Println("Error migrating RecoveryCode:", err)
+		return err
+	}
+	log.Println("RecoveryCode table migrated successfully")
+
+	log.Println("Migrating PasswordReset table...")
+	if err := db.AutoMigrate(&models.PasswordReset{}); err != nil {
+		log.Println("Error migrating PasswordReset:", err)
+		return err
+	}
+	log.Println("PasswordReset table migrated successfully")
+
+	log.Println("Migrating LoginAttempt table...")
+	if err := db.AutoMigrate(&models.LoginAttempt{}); err != nil {
+		log.Println("Error migrating LoginAttempt:", err)
+		return err
+	}
+	log.Println("LoginAttempt table migrated successfully")
+
+	log.Println("Migrating AuditLog table...")
+	if err := db.AutoMigrate(&models.AuditLog{}); err != nil {
+		log.Println("Error migrating AuditLog:", err)
+		return err
+	}
+	log.Println("AuditLog table migrated successfully")
+
+	log.Println("Migrating OIDCLogin table...")
+	if err := db.AutoMigrate(&models.OIDCLogin{}); err != nil {
+		log.Println("Error migrating OIDCLogin:", err)
+		return err
+	}
+	log.Println("OIDCLogin table migrated successfully")
+
 	// Step 3: Employee structure tables
 	log.Println("Migrating Employee division table...")
 	if err := db.AutoMigrate(&models.EmployeeDivision{}); err != nil {
@@ -83,6 +142,27 @@ func AutoMigrate(db *gorm.DB) error {
 	}
 	log.Println("UserWarehouse table migrated successfully")
 
+	log.Println("Migrating APIKey table...")
+	if err := db.AutoMigrate(&models.APIKey{}); err != nil {
+		log.Println("Error migrating APIKey:", err)
+		return err
+	}
+	log.Println("APIKey table migrated successfully")
+
+	log.Println("Migrating UserInvite table...")
+	if err := db.AutoMigrate(&models.UserInvite{}); err != nil {
+		log.Println("Error migrating UserInvite:", err)
+		return err
+	}
+	log.Println("UserInvite table migrated successfully")
+
+	log.Println("Migrating WarehouseLocation table...")
+	if err := db.AutoMigrate(&models.WarehouseLocation{}); err != nil {
+		log.Println("Error migrating WarehouseLocation:", err)
+		return err
+	}
+	log.Println("WarehouseLocation table migrated successfully")
+
 	// Step 4: Category table
 	log.Println("Migrating Category table...")
 	if err := db.AutoMigrate(&models.Category{}); err != nil {
@@ -98,17 +178,85 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
+	log.Println("Migrating UnitOfMeasure and ItemUnitConversion tables...")
+	if err := db.AutoMigrate(&models.UnitOfMeasure{}, &models.ItemUnitConversion{}); err != nil {
+		log.Println("Error migrating UnitOfMeasure/ItemUnitConversion:", err)
+		return err
+	}
+	if err := seedDefaultUnits(db); err != nil {
+		log.Println("Error seeding default units:", err)
+		return err
+	}
+
 	log.Println("Migrating PurchaseOrder and POItem tables...")
 	if err := db.AutoMigrate(&models.PurchaseOrder{}, &models.POItem{}); err != nil {
 		log.Println("Error migrating PurchaseOrder/POItem:", err)
 		return err
 	}
 
+	log.Println("Migrating StockReservation table...")
+	if err := db.AutoMigrate(&models.StockReservation{}); err != nil {
+		log.Println("Error migrating StockReservation:", err)
+		return err
+	}
+
 	log.Println("Migrating InventoryTransaction and Notification tables...")
+	hadReminderKey := db.Migrator().HasTable(&models.Notification{}) && db.Migrator().HasColumn(&models.Notification{}, "ReminderKey")
 	if err := db.AutoMigrate(&models.InventoryTransaction{}, &models.Notification{}); err != nil {
 		log.Println("Error migrating InventoryTransaction/Notification:", err)
 		return err
 	}
+	// Lead follow-up reminders kept their key in data; copy it to the unique
+	// column once, keeping the oldest row where instances sent one twice.
+	if !hadReminderKey {
+		if err := db.Exec(`UPDATE notifications SET reminder_key = 'follow_up:' || (data->>'reminder_key')
+			WHERE id IN (
+				SELECT MIN(id) FROM notifications
+				WHERE type = 'system' AND module = 'lead' AND data->>'reminder_key' IS NOT NULL
+				GROUP BY user_id, data->>'reminder_key'
+			)`).Error; err != nil {
+			log.Println("Error backfilling Notification reminder keys:", err)
+			return err
+		}
+	}
+
+	// Transactions recorded before unit conversions existed were entered in the base unit.
+	if err := db.Exec(`UPDATE inventory_transactions SET entry_quantity = quantity, entry_unit = COALESCE(items.unit, '')
+		FROM inventory_items AS items
+		WHERE items.id = inventory_transactions.item_id AND (inventory_transactions.entry_unit IS NULL OR inventory_transactions.entry_unit = '')`).Error; err != nil {
+		log.Println("Error backfilling InventoryTransaction entry units:", err)
+		return err
+	}
+
+	log.Println("Migrating WebhookSubscription and WebhookDelivery tables...")
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}); err != nil {
+		log.Println("Error migrating WebhookSubscription/WebhookDelivery:", err)
+		return err
+	}
+
+	log.Println("Migrating ImportBatch table...")
+	if err := db.AutoMigrate(&models.ImportBatch{}); err != nil {
+		log.Println("Error migrating ImportBatch:", err)
+		return err
+	}
+
+	log.Println("Migrating Job table...")
+	if err := db.AutoMigrate(&models.Job{}); err != nil {
+		log.Println("Error migrating Job:", err)
+		return err
+	}
+
+	log.Println("Migrating SerialNumber and SerialMovement tables...")
+	if err := db.AutoMigrate(&models.SerialNumber{}, &models.SerialMovement{}); err != nil {
+		log.Println("Error migrating SerialNumber/SerialMovement:", err)
+		return err
+	}
+
+	log.Println("Migrating InventoryBinStock table...")
+	if err := db.AutoMigrate(&models.InventoryBinStock{}); err != nil {
+		log.Println("Error migrating InventoryBinStock:", err)
+		return err
+	}
 
 	log.Println("Migrating NotificationSetting and NotificationHistory tables...")
 	if err := db.AutoMigrate(&models.NotificationSetting{}, &models.NotificationHistory{}); err != nil {
@@ -131,3 +279,49 @@ func AutoMigrate(db *gorm.DB) error {
 	log.Println("All tables migrated successfully")
 	return nil
 }
+
+// defaultUnits is the unit catalogue every installation starts with.
+var defaultUnits = []models.UnitOfMeasure{
+	{Code: "pcs", Name: "Pieces", IsActive: true},
+	{Code: "box", Name: "Box", IsActive: true},
+	{Code: "pack", Name: "Pack", IsActive: true},
+	{Code: "set", Name: "Set", IsActive: true},
+	{Code: "kg", Name: "Kilogram", IsActive: true},
+	{Code: "m", Name: "Meter", IsActive: true},
+	{Code: "roll", Name: "Roll", IsActive: true},
+}
+
+// seedDefaultUnits inserts the default units whose code is not taken yet, so
+// it is safe on every start and never brings back a unit an admin deleted.
+func seedDefaultUnits(db *gorm.DB) error {
+	units := make([]models.UnitOfMeasure, len(defaultUnits))
+	copy(units, defaultUnits)
+	return db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(&units).Error
+}
+
+// employeeStockPermissions let the employee role record transactions and
+// register serial numbers inside its warehouses.
+var employeeStockPermissions = []string{"inventory.create", "inventory.update"}
+
+func grantEmployeeStockPermissions(db *gorm.DB) error {
+	var roles []models.Role
+	if err := db.Where("LOWER(name) = ?", "employee").Find(&roles).Error; err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	var permissions []models.Permission
+	if err := db.Where("name IN ?", employeeStockPermissions).Find(&permissions).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	for i := range roles {
+		if err := db.Model(&roles[i]).Association("Permissions").Append(&permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -25,7 +25,7 @@ func SeedData(db *gorm.DB) error {
 	roles := []models.Role{
 		{Name: "admin", Description: "Administrator with full access"},
 		{Name: "manager", Description: "Manager with limited access"},
-		{Name: "employee", Description: "Employee with basic access"},
+		{Name: "employee", Description: "Employee with basic access", WarehouseScoped: true},
 	}
 
 	for i := range roles {
@@ -88,6 +88,16 @@ func SeedData(db *gorm.DB) error {
 		{Name: "po.update", Description: "Update purchase order", Module: "po", Action: "update"},
 		{Name: "po.approve", Description: "Approve purchase order", Module: "po", Action: "approve"},
 		{Name: "po.delete", Description: "Delete purchase order", Module: "po", Action: "delete"},
+
+		// Settings & administration permissions
+		{Name: "settings.manage", Description: "Manage site settings", Module: "settings", Action: "manage"},
+		{Name: "backup.run", Description: "Back up and restore the database", Module: "backup", Action: "run"},
+		{Name: "role.manage", Description: "Manage roles and their permissions", Module: "role", Action: "manage"},
+		{Name: "audit.view", Description: "View and export the audit log", Module: "audit", Action: "view"},
+		{Name: "api_key.manage", Description: "Manage API keys for integrations", Module: "api_key", Action: "manage"},
+		{Name: "webhook.manage", Description: "Manage outbound webhooks", Module: "webhook", Action: "manage"},
+		{Name: "notification.manage", Description: "View and requeue outgoing email and WhatsApp messages", Module: "notification", Action: "manage"},
+		{Name: "notification.send", Description: "Send test notifications and low stock summaries", Module: "notification", Action: "send"},
 	}
 
 	for _, permission := range permissions {
@@ -103,6 +113,26 @@ func SeedData(db *gorm.DB) error {
 	db.Find(&allPermissions)
 	db.Model(&adminRole).Association("Permissions").Append(&allPermissions)
 
+	// Assign warehouse maintenance and PO approval to manager role
+	var managerRole models.Role
+	db.Where("name = ?", "manager").First(&managerRole)
+	var managerPermissions []models.Permission
+	db.Where("name IN ?", []string{"warehouse.create", "warehouse.update", "po.approve"}).Find(&managerPermissions)
+	db.Model(&managerRole).Association("Permissions").Append(&managerPermissions)
+
+	// Let the warehouse-scoped employee role move stock in its warehouses
+	var employeeRole models.Role
+	db.Where("name = ?", "employee").First(&employeeRole)
+	var employeePermissions []models.Permission
+	db.Where("name IN ?", employeeStockPermissions).Find(&employeePermissions)
+	db.Model(&employeeRole).Association("Permissions").Append(&employeePermissions)
+
+	// Warehouse lists, purchase orders and notifications are open to every role
+	var sharedPermissions []models.Permission
+	db.Where("name IN ?", []string{"warehouse.view", "po.view", "po.create", "po.update", "notification.send"}).Find(&sharedPermissions)
+	db.Model(&managerRole).Association("Permissions").Append(&sharedPermissions)
+	db.Model(&employeeRole).Association("Permissions").Append(&sharedPermissions)
+
 	// Assign default menu visibility
 	menuAssignments := map[string][]string{
 		"admin": {
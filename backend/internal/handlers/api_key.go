@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
+	return &APIKeyHandler{db: db}
+}
+
+// apiKeyRequest binds a key to either a role or a permission subset. Updates
+// replace the whole definition.
+type apiKeyRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description"`
+	RoleID       *uint    `json:"role_id"`
+	Permissions  []string `json:"permissions"`
+	WarehouseIDs []uint   `json:"warehouse_ids"`
+	ExpiresAt    string   `json:"expires_at"` // RFC3339 or YYYY-MM-DD, empty for no expiry
+}
+
+// apiKeyResponse flattens the key's bindings and adds a derived status.
+type apiKeyResponse struct {
+	models.APIKey
+	RoleName      string   `json:"role_name,omitempty"`
+	Permissions   []string `json:"permissions"`
+	WarehouseIDs  []uint   `json:"warehouse_ids"`
+	CreatedByName string   `json:"created_by_name"`
+	Status        string   `json:"status"` // active, expired, revoked
+}
+
+func newAPIKeyResponse(key models.APIKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		APIKey:        key,
+		Permissions:   []string{},
+		WarehouseIDs:  []uint{},
+		CreatedByName: key.CreatedBy.FullName,
+	}
+	if key.Role != nil {
+		resp.RoleName = key.Role.Name
+	}
+	for _, permission := range key.Permissions {
+		resp.Permissions = append(resp.Permissions, permission.Name)
+	}
+	sort.Strings(resp.Permissions)
+	for _, warehouse := range key.Warehouses {
+		resp.WarehouseIDs = append(resp.WarehouseIDs, warehouse.ID)
+	}
+	sort.Slice(resp.WarehouseIDs, func(i, j int) bool { return resp.WarehouseIDs[i] < resp.WarehouseIDs[j] })
+	switch {
+	case key.RevokedAt != nil:
+		resp.Status = "revoked"
+	case key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt):
+		resp.Status = "expired"
+	default:
+		resp.Status = "active"
+	}
+	return resp
+}
+
+// apiKeyBinding is a validated apiKeyRequest.
+type apiKeyBinding struct {
+	role        *models.Role
+	permissions []models.Permission
+	warehouses  []models.Warehouse
+	expiresAt   *time.Time
+}
+
+// resolveAPIKeyBinding validates the request. Admins can only hand out
+// permissions they hold themselves and warehouses they are assigned to.
+func (h *APIKeyHandler) resolveAPIKeyBinding(c *gin.Context, req apiKeyRequest) (*apiKeyBinding, int, error) {
+	binding := &apiKeyBinding{}
+
+	permissionKeys, err := sanitizePermissionKeys(req.Permissions)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if (req.RoleID == nil) == (len(permissionKeys) == 0) {
+		return nil, http.StatusBadRequest, errors.New("provide either role_id or permissions")
+	}
+
+	warehouseScoped := false
+	if req.RoleID != nil {
+		var role models.Role
+		if err := h.db.Preload("Permissions").First(&role, *req.RoleID).Error; err != nil {
+			return nil, http.StatusBadRequest, errors.New("invalid role ID")
+		}
+		binding.role = &role
+		warehouseScoped = role.WarehouseScoped
+		for _, permission := range role.Permissions {
+			permissionKeys = append(permissionKeys, permission.Name)
+		}
+	} else {
+		if err := h.db.Where("name IN ?", permissionKeys).Find(&binding.permissions).Error; err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if len(binding.permissions) != len(permissionKeys) {
+			return nil, http.StatusBadRequest, errors.New("one or more permissions do not exist, sync permissions first")
+		}
+	}
+
+	roleID := c.GetUint("role_id")
+	for _, key := range permissionKeys {
+		held, err := middleware.RoleHasAnyPermission(h.db, roleID, key)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if !held {
+			return nil, http.StatusForbidden, fmt.Errorf("you cannot grant a permission you do not have: %s", key)
+		}
+	}
+
+	warehouseIDs := make([]uint, 0, len(req.WarehouseIDs))
+	for id := range buildUintSet(req.WarehouseIDs) {
+		warehouseIDs = append(warehouseIDs, id)
+	}
+	scope, err := resolveWarehouseScope(h.db, c)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if scope.restricted {
+		if len(warehouseIDs) == 0 {
+			return nil, http.StatusBadRequest, errors.New("warehouse_ids is required because your access is limited to assigned warehouses")
+		}
+		if !scope.allows(warehouseIDs...) {
+			return nil, http.StatusForbidden, errors.New("you cannot grant access to a warehouse you are not assigned to")
+		}
+	}
+	if len(warehouseIDs) > 0 {
+		if err := h.db.Where("id IN ?", warehouseIDs).Find(&binding.warehouses).Error; err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if len(binding.warehouses) != len(warehouseIDs) {
+			return nil, http.StatusBadRequest, errors.New("one or more warehouses do not exist")
+		}
+	} else if warehouseScoped {
+		return nil, http.StatusBadRequest, errors.New("warehouse_ids is required for a warehouse-scoped role")
+	}
+
+	expiresAt, err := parseISOTime(req.ExpiresAt)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid expires_at: %w", err)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, http.StatusBadRequest, errors.New("expires_at must be in the future")
+	}
+	binding.expiresAt = expiresAt
+
+	return binding, http.StatusOK, nil
+}
+
+// saveAPIKeyBinding replaces the role, permissions and warehouses of the key.
+func saveAPIKeyBinding(tx *gorm.DB, key *models.APIKey, binding *apiKeyBinding) error {
+	key.RoleID = nil
+	if binding.role != nil {
+		key.RoleID = &binding.role.ID
+	}
+	key.ExpiresAt = binding.expiresAt
+	if err := tx.Omit("Role", "Permissions", "Warehouses", "CreatedBy").Save(key).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(key).Association("Permissions").Replace(binding.permissions); err != nil {
+		return err
+	}
+	return tx.Model(key).Association("Warehouses").Replace(binding.warehouses)
+}
+
+func (h *APIKeyHandler) loadAPIKey(id interface{}) (models.APIKey, error) {
+	var key models.APIKey
+	err := h.db.Preload("Role").Preload("Permissions").Preload("Warehouses").Preload("CreatedBy").First(&key, id).Error
+	return key, err
+}
+
+// ListAPIKeys returns every API key, newest first. Plain keys are never shown again.
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	query := h.db.Preload("Role").Preload("Permissions").Preload("Warehouses").Preload("CreatedBy")
+	now := time.Now()
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+	case "revoked":
+		query = query.Where("revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("revoked_at IS NULL AND expires_at <= ?", now)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use active, revoked or expired"})
+		return
+	}
+
+	var keys []models.APIKey
+	if err := query.Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch API keys",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		data = append(data, newAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateAPIKey issues a new key. The plain key is only part of this response.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	binding, status, err := h.resolveAPIKeyBinding(c, req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+	plain := middleware.APIKeyPrefix + secret
+
+	key := models.APIKey{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Prefix:      plain[:len(middleware.APIKeyPrefix)+8],
+		KeyHash:     middleware.HashAPIKey(plain),
+		CreatedByID: c.GetUint("user_id"),
+	}
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		return saveAPIKeyBinding(tx, &key, binding)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create API key",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	key, err = h.loadAPIKey(key.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API key"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully. Store the key now, it will not be shown again",
+		"key":     plain,
+		"data":    newAPIKeyResponse(key),
+	})
+}
+
+// UpdateAPIKey replaces the name, bindings and expiry of an active key.
+func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
+	key, err := h.loadAPIKey(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API key"})
+		return
+	}
+	if key.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revoked API keys cannot be changed"})
+		return
+	}
+
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	binding, status, err := h.resolveAPIKeyBinding(c, req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	key.Name = strings.TrimSpace(req.Name)
+	key.Description = strings.TrimSpace(req.Description)
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		return saveAPIKeyBinding(tx, &key, binding)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update API key",
+			"message": err.Error(),
+		})
+		return
+	}
+	middleware.InvalidateAPIKeyCache(key.ID)
+
+	key, err = h.loadAPIKey(key.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API key"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key updated successfully",
+		"data":    newAPIKeyResponse(key),
+	})
+}
+
+// RevokeAPIKey disables a key for good. The row is kept for the audit trail.
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	var key models.APIKey
+	if err := h.db.First(&key, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API key"})
+		return
+	}
+	if key.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is already revoked"})
+		return
+	}
+
+	if err := h.db.Model(&key).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	middleware.InvalidateAPIKeyCache(key.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Audited entities, used by the routes to attach middleware.Audit.
+var (
+	AuditItem        = middleware.AuditEntity{Type: "item", Model: &models.InventoryItem{}}
+	AuditItemUnits   = middleware.AuditEntity{Type: "item", Model: &models.InventoryItem{}, Snapshot: itemUnitsAuditSnapshot}
+	AuditTransaction = middleware.AuditEntity{Type: "inventory_transaction", Model: &models.InventoryTransaction{}}
+	AuditItemTxn     = middleware.AuditEntity{Type: "inventory_transaction", Model: &models.InventoryTransaction{}, IDFromResponse: true}
+	AuditSerial      = middleware.AuditEntity{Type: "serial_number", Model: &models.SerialNumber{}}
+	AuditNewSerials  = middleware.AuditEntity{Type: "serial_number", Model: &models.SerialNumber{}, IDFromResponse: true}
+	AuditUnit        = middleware.AuditEntity{Type: "unit", Model: &models.UnitOfMeasure{}}
+	AuditReservation = middleware.AuditEntity{Type: "reservation", Model: &models.StockReservation{}}
+	AuditImport      = middleware.AuditEntity{Type: "item_import"}
+	AuditWarehouse   = middleware.AuditEntity{Type: "warehouse", Model: &models.Warehouse{}}
+	AuditLocation    = middleware.AuditEntity{Type: "warehouse_location", Model: &models.WarehouseLocation{}, Param: "locationId"}
+	AuditNewLocation = middleware.AuditEntity{Type: "warehouse_location", Model: &models.WarehouseLocation{}, IDFromResponse: true}
+	AuditCategory    = middleware.AuditEntity{Type: "category", Model: &models.Category{}}
+	AuditEmployee    = middleware.AuditEntity{Type: "employee", Model: &models.Employee{}}
+	AuditDivision    = middleware.AuditEntity{Type: "employee_division", Model: &models.EmployeeDivision{}}
+	AuditPosition    = middleware.AuditEntity{Type: "employee_position", Model: &models.EmployeePosition{}}
+	AuditUser        = middleware.AuditEntity{Type: "user", Model: &models.User{}, Snapshot: userAuditSnapshot}
+	AuditProfile     = middleware.AuditEntity{Type: "user", Model: &models.User{}, Snapshot: userAuditSnapshot, ContextKey: "user_id"}
+	AuditInvite      = middleware.AuditEntity{Type: "user_invite", Model: &models.UserInvite{}}
+	AuditRole        = middleware.AuditEntity{Type: "role", Model: &models.Role{}, Snapshot: roleAuditSnapshot}
+	AuditPO          = middleware.AuditEntity{Type: "purchase_order", Model: &models.PurchaseOrder{}, Snapshot: poAuditSnapshot}
+	AuditSite        = middleware.AuditEntity{Type: "site_settings", Model: &models.SiteSetting{}, Singleton: true}
+	AuditDatabase    = middleware.AuditEntity{Type: "database"}
+	AuditAPIKey      = middleware.AuditEntity{Type: "api_key", Model: &models.APIKey{}, Snapshot: apiKeyAuditSnapshot}
+	AuditNewAPIKey   = middleware.AuditEntity{Type: "api_key", Model: &models.APIKey{}, Snapshot: apiKeyAuditSnapshot, IDFromResponse: true}
+	AuditWebhook     = middleware.AuditEntity{Type: "webhook", Model: &models.WebhookSubscription{}}
+	AuditNewWebhook  = middleware.AuditEntity{Type: "webhook", Model: &models.WebhookSubscription{}, IDFromResponse: true}
+	AuditOutboxMsg   = middleware.AuditEntity{Type: "notification", Model: &models.Notification{}, Snapshot: outboxAuditSnapshot}
+	AuditOutbox      = middleware.AuditEntity{Type: "notification"}
+	// AuditNotificationSettings logs the caller's own settings under their user ID.
+	AuditNotificationSettings = middleware.AuditEntity{Type: "notification_settings", Model: &models.NotificationSetting{}, ContextKey: "user_id", Snapshot: notificationSettingsAuditSnapshot}
+)
+
+func itemUnitsAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.InventoryItem{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var conversions []models.ItemUnitConversion
+	if err := db.Where("item_id = ?", id).Order("unit_code").Find(&conversions).Error; err != nil {
+		return nil, err
+	}
+	units := make([]map[string]interface{}, 0, len(conversions))
+	for _, conversion := range conversions {
+		units = append(units, map[string]interface{}{"unit_code": conversion.UnitCode, "factor": conversion.Factor})
+	}
+	row["units"] = units
+	return row, nil
+}
+
+func userAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.User{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var warehouseIDs []uint
+	if err := db.Model(&models.UserWarehouse{}).Where("user_id = ?", id).Order("warehouse_id").Pluck("warehouse_id", &warehouseIDs).Error; err != nil {
+		return nil, err
+	}
+	row["warehouse_ids"] = warehouseIDs
+	return row, nil
+}
+
+func roleAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.Role{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var permissions []string
+	if err := db.Table("role_permissions").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("role_permissions.role_id = ?", id).
+		Pluck("permissions.name", &permissions).Error; err != nil {
+		return nil, err
+	}
+	var menus []string
+	if err := db.Model(&models.RoleMenu{}).Where("role_id = ?", id).Pluck("menu_key", &menus).Error; err != nil {
+		return nil, err
+	}
+	sort.Strings(permissions)
+	sort.Strings(menus)
+	row["permissions"] = permissions
+	row["menus"] = menus
+	return row, nil
+}
+
+// outboxAuditSnapshot keeps reset links, invite links and temporary passwords
+// out of the audit log the same way the outbox list does.
+func outboxAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.Notification{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var message models.Notification
+	if err := db.Select("id", "data").First(&message, id).Error; err != nil {
+		return nil, err
+	}
+	notification.Redact(&message)
+	if message.Message == notification.RedactedMessage {
+		row["message"] = notification.RedactedMessage
+	}
+	return row, nil
+}
+
+// notificationSettingsAuditSnapshot loads the settings row of the user with ID userID.
+func notificationSettingsAuditSnapshot(db *gorm.DB, userID string) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	result := db.Model(&models.NotificationSetting{}).Where("user_id = ?", userID).Limit(1).Find(&row)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return row, nil
+}
+
+func apiKeyAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.APIKey{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var permissions []string
+	if err := db.Table("api_key_permissions").
+		Joins("JOIN permissions ON permissions.id = api_key_permissions.permission_id").
+		Where("api_key_permissions.api_key_id = ?", id).
+		Pluck("permissions.name", &permissions).Error; err != nil {
+		return nil, err
+	}
+	var warehouseIDs []uint
+	if err := db.Table("api_key_warehouses").Where("api_key_id = ?", id).Order("warehouse_id").Pluck("warehouse_id", &warehouseIDs).Error; err != nil {
+		return nil, err
+	}
+	sort.Strings(permissions)
+	row["permissions"] = permissions
+	row["warehouse_ids"] = warehouseIDs
+	return row, nil
+}
+
+func poAuditSnapshot(db *gorm.DB, id string) (map[string]interface{}, error) {
+	row, err := middleware.SnapshotRow(db, &models.PurchaseOrder{}, id)
+	if err != nil || row == nil {
+		return row, err
+	}
+	var items []models.POItem
+	if err := db.Where("po_id = ?", id).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	lines := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, map[string]interface{}{
+			"item_id":    item.ItemID,
+			"item_name":  item.ItemName,
+			"unit":       item.Unit,
+			"quantity":   item.Quantity,
+			"unit_price": item.UnitPrice,
+		})
+	}
+	row["items"] = lines
+	return row, nil
+}
+
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+var auditListSort = listSort{
+	table:       "audit_logs",
+	fields:      map[string]string{"id": "audit_logs.id", "created_at": "audit_logs.created_at"},
+	defaultSort: "created_at",
+	defaultDesc: true,
+}
+
+// auditFilters applies the shared query filters of the list and export endpoints.
+func auditFilters(query *gorm.DB, c *gin.Context) (*gorm.DB, error) {
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if apiKeyID := c.Query("api_key_id"); apiKeyID != "" {
+		query = query.Where("api_key_id = ?", apiKeyID)
+	}
+	for _, field := range []string{"action", "entity_type", "entity_id"} {
+		if value := strings.TrimSpace(c.Query(field)); value != "" {
+			query = query.Where(field+" = ?", value)
+		}
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("(actor_email ILIKE ? OR path ILIKE ?)", like, like)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseISOTime(from)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid from, use RFC3339 or YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", *t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseISOTime(to)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid to, use RFC3339 or YYYY-MM-DD")
+		}
+		query = query.Where("created_at <= ?", *t)
+	}
+	return query, nil
+}
+
+// ListAuditLogs returns audit entries, newest first.
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	params, err := parseListParams(c, auditListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, err := auditFilters(h.db.Model(&models.AuditLog{}), c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := params.apply(query, auditListSort).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch audit logs",
+			"message": err.Error(),
+		})
+		return
+	}
+	keep, meta := params.finish(c, total, len(logs), func(i int) uint { return logs[i].ID })
+
+	c.JSON(http.StatusOK, gin.H{"data": logs[:keep], "meta": meta})
+}
+
+// GetEntityHistory returns every audit entry of one entity, newest first.
+func (h *AuditHandler) GetEntityHistory(c *gin.Context) {
+	var logs []models.AuditLog
+	if err := h.db.Where("entity_type = ? AND entity_id = ?", c.Param("type"), c.Param("id")).
+		Order("created_at DESC, id DESC").
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch entity history",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": logs})
+}
+
+// ExportAuditLogsToCSV exports the filtered audit entries as CSV.
+func (h *AuditHandler) ExportAuditLogsToCSV(c *gin.Context) {
+	query, err := auditFilters(h.db.Model(&models.AuditLog{}), c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch audit logs for export",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	headers := []string{"ID", "Date", "Actor ID", "Actor Email", "Action", "Entity Type", "Entity ID", "Changes", "Method", "Path", "IP Address", "User Agent"}
+	if err := writer.Write(headers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to write CSV header",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	for _, entry := range logs {
+		actorID := ""
+		if entry.ActorID != nil {
+			actorID = strconv.FormatUint(uint64(*entry.ActorID), 10)
+		}
+		record := []string{
+			strconv.FormatUint(uint64(entry.ID), 10),
+			entry.CreatedAt.Format("2006-01-02 15:04:05"),
+			actorID,
+			entry.ActorEmail,
+			entry.Action,
+			entry.EntityType,
+			entry.EntityID,
+			string(entry.Changes),
+			entry.Method,
+			entry.Path,
+			entry.IPAddress,
+			entry.UserAgent,
+		}
+		if err := writer.Write(record); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to write CSV record",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to finalize CSV",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/csv", buffer.Bytes())
+}
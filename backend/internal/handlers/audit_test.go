@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSelfServiceSecurityChangesAreAudited checks that turning off two-factor
+// authentication and changing notification settings leave an audit entry.
+func TestSelfServiceSecurityChangesAreAudited(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	hash, err := utils.HashPassword("password")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{Email: "user@example.com", Password: hash, FullName: "User", RoleID: role.ID, IsActive: true, TwoFactorEnabled: true, TwoFactorSecret: "JBSWY3DPEHPK3PXP"}
+	mustCreate(t, db, &user)
+	codes, err := replaceRecoveryCodes(db, user.ID)
+	if err != nil {
+		t.Fatalf("replace recovery codes: %v", err)
+	}
+
+	notif := notification.NewNotificationService(cfg, db)
+	router := gin.New()
+	api := router.Group("", asUser(user))
+	// Mirrors routes.go.
+	api.POST("/auth/2fa/disable", middleware.AuditAction(db, AuditProfile, "disable_two_factor"), NewAuthHandler(db, cfg, notif).DisableTwoFactor)
+	api.PUT("/settings/notifications", middleware.Audit(db, AuditNotificationSettings), NewSettingsHandler(db, notif, cfg).UpdateNotificationSettings)
+
+	if resp := serve(router, http.MethodPost, "/auth/2fa/disable", gin.H{"password": "password", "recovery_code": codes[0]}); resp.Code != http.StatusOK {
+		t.Fatalf("disable 2FA: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var disabled models.AuditLog
+	if err := db.Where("action = ? AND entity_type = ?", "disable_two_factor", "user").First(&disabled).Error; err != nil {
+		t.Fatalf("no audit entry for disabling 2FA: %v", err)
+	}
+	if !strings.Contains(string(disabled.Changes), "two_factor_enabled") || strings.Contains(string(disabled.Before), "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("unexpected 2FA audit entry: changes %s, before %s", disabled.Changes, disabled.Before)
+	}
+
+	settings := gin.H{"enabled": true, "threshold": 5, "email_enabled": true, "email_address": "alerts@example.com"}
+	if resp := serve(router, http.MethodPut, "/settings/notifications", settings); resp.Code != http.StatusOK {
+		t.Fatalf("update notification settings: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var updated models.AuditLog
+	if err := db.Where("action = ? AND entity_type = ?", "update", "notification_settings").First(&updated).Error; err != nil {
+		t.Fatalf("no audit entry for notification settings: %v", err)
+	}
+	if updated.ActorID == nil || *updated.ActorID != user.ID || !strings.Contains(string(updated.After), "alerts@example.com") {
+		t.Fatalf("unexpected notification settings audit entry: %+v", updated)
+	}
+}
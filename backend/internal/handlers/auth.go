@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"tatapps/internal/config"
 	"tatapps/internal/models"
 	"tatapps/internal/services/notification"
+	"tatapps/internal/services/oidc"
 	"tatapps/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -16,14 +20,19 @@ type AuthHandler struct {
 	db     *gorm.DB
 	config *config.Config
 	notif  *notification.NotificationService
+	oidc   *oidc.Provider // nil unless single sign-on is enabled
 }
 
 func NewAuthHandler(db *gorm.DB, cfg *config.Config, notif *notification.NotificationService) *AuthHandler {
-	return &AuthHandler{
+	h := &AuthHandler{
 		db:     db,
 		config: cfg,
 		notif:  notif,
 	}
+	if cfg.OIDCEnabled {
+		h.oidc = oidc.NewProvider(cfg)
+	}
+	return h
 }
 
 type LoginRequest struct {
@@ -36,12 +45,15 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	FullName string `json:"full_name" binding:"required"`
 	Phone    string `json:"phone"`
-	RoleID   uint   `json:"role_id" binding:"required"`
 }
 
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	sessionTokens
+	User models.User `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -51,32 +63,57 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	wait, err := h.ipLoginBlock(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if wait > 0 {
+		h.recordLoginAttempt(c, req.Email, nil, false, loginReasonIPBlocked)
+		h.respondLocked(c, wait, false)
+		return
+	}
+
 	var user models.User
 	if err := h.db.Preload("Role").Preload("Role.Menus").Preload("Role.Permissions").
 		Preload("Warehouses").Preload("Warehouses.Warehouse").
 		Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if !h.checkUnknownEmailAllowed(c, req.Email) {
+			return
+		}
+		h.recordLoginAttempt(c, req.Email, nil, false, loginReasonUnknownUser)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	if !h.checkLoginAllowed(c, &user) {
+		return
+	}
+
 	if !user.IsActive {
+		h.recordLoginAttempt(c, user.Email, &user.ID, false, loginReasonInactive)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is not active"})
 		return
 	}
 
 	if !utils.CheckPassword(req.Password, user.Password) {
+		h.registerLoginFailure(c, &user, loginReasonInvalidPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := utils.GenerateToken(
-		user.ID,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-		h.config.JWTSecret,
-		h.config.JWTExpiration,
-	)
+	challenge, err := h.loginChallenge(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	h.registerLoginSuccess(c, &user)
+	tokens, err := startSession(h.db, h.config, c, &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -85,18 +122,123 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	user.Password = ""
 
 	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User:  user,
+		sessionTokens: tokens,
+		User:          user,
 	})
 }
 
+// RefreshToken exchanges a refresh token for a new access/refresh pair.
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, _, err := rotateSession(h.db, h.config, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to refresh token",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the current session.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID := c.GetUint("session_id")
+	if err := revokeSessions(h.db, sessionRevokedLogout, "id = ?", sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to log out",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user, including this one.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if err := revokeUserSessions(h.db, userID, sessionRevokedLogoutAll, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to log out sessions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions logged out successfully"})
+}
+
+// ListSessions returns the active sessions of the current user.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	currentID := c.GetUint("session_id")
+
+	var sessions []models.UserSession
+	if err := h.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch sessions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, gin.H{
+			"id":           session.ID,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"current":      session.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// queueWelcomeEmail hands the welcome email to the notification outbox.
+func (h *AuthHandler) queueWelcomeEmail(user *models.User) {
+	if _, err := h.notif.Outbox.QueueEmail(notification.WelcomeEmail(user.Email, user.FullName), "user", &user.ID); err != nil {
+		log.Printf("[auth] failed to queue welcome email for user %d: %v", user.ID, err)
+	}
+}
+
+// Register creates an account with the configured registration role. It is
+// disabled unless ALLOW_REGISTRATION is true; otherwise users join by invite.
 func (h *AuthHandler) Register(c *gin.Context) {
+	if !h.config.AllowRegistration {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Public registration is disabled, ask an administrator for an invitation"})
+		return
+	}
+
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var role models.Role
+	if err := h.db.Where("name = ?", h.config.RegistrationRole).First(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Registration role is not configured"})
+		return
+	}
+
 	// Check if email already exists
 	var existingUser models.User
 	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
@@ -117,7 +259,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Password: hashedPassword,
 		FullName: req.FullName,
 		Phone:    req.Phone,
-		RoleID:   req.RoleID,
+		RoleID:   role.ID,
 		IsActive: true,
 	}
 
@@ -131,8 +273,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Preload("Warehouses").Preload("Warehouses.Warehouse").
 		First(&user, user.ID)
 
-	// Send welcome email
-	go h.notif.Email.SendWelcomeEmail(user.Email, user.FullName)
+	h.queueWelcomeEmail(&user)
 
 	user.Password = ""
 
@@ -266,6 +407,12 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Sign out every other device; this session stays valid.
+	if err := revokeUserSessions(h.db, user.ID, sessionRevokedPasswordChanged, c.GetUint("session_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Password changed successfully",
 	})
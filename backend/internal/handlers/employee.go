@@ -8,17 +8,19 @@ import (
 	"time"
 
 	"tatapps/internal/models"
+	"tatapps/internal/services/webhook"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type EmployeeHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	webhooks *webhook.Dispatcher
 }
 
-func NewEmployeeHandler(db *gorm.DB) *EmployeeHandler {
-	return &EmployeeHandler{db: db}
+func NewEmployeeHandler(db *gorm.DB, webhooks *webhook.Dispatcher) *EmployeeHandler {
+	return &EmployeeHandler{db: db, webhooks: webhooks}
 }
 
 type employeePayload struct {
@@ -129,12 +131,27 @@ type batchDeleteRequest struct {
 	IDs []uint `json:"ids"`
 }
 
+var employeeListSort = listSort{
+	table: "employees",
+	fields: map[string]string{
+		"id":            "employees.id",
+		"full_name":     "employees.full_name",
+		"employee_code": "employees.employee_code",
+		"join_date":     "employees.join_date",
+		"status":        "employees.status",
+		"created_at":    "employees.created_at",
+	},
+	defaultSort: "full_name",
+}
+
 func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
-	var employees []models.Employee
+	params, err := parseListParams(c, employeeListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	query := h.db.
-		Preload("Division").
-		Preload("Position")
+	query := h.db.Model(&models.Employee{})
 
 	search := strings.TrimSpace(c.Query("search"))
 	if search != "" {
@@ -161,17 +178,26 @@ func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
 		query = query.Where("employment_type = ?", employment)
 	}
 
-	if err := query.Order("full_name ASC").Find(&employees).Error; err != nil {
+	total, err := countList(query)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employees"})
 		return
 	}
 
+	var employees []models.Employee
+	if err := params.apply(query.Preload("Division").Preload("Position"), employeeListSort).Find(&employees).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employees"})
+		return
+	}
+	keep, meta := params.finish(c, total, len(employees), func(i int) uint { return employees[i].ID })
+	employees = employees[:keep]
+
 	responses := make([]employeeResponse, 0, len(employees))
 	for i := range employees {
 		responses = append(responses, buildEmployeeResponse(&employees[i]))
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": responses})
+	c.JSON(http.StatusOK, gin.H{"data": responses, "meta": meta})
 }
 
 func (h *EmployeeHandler) GetEmployee(c *gin.Context) {
@@ -212,6 +238,8 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventEmployeeCreated, webhookEmployeeData(employee))
+
 	c.JSON(http.StatusCreated, gin.H{"data": buildEmployeeResponse(&employee)})
 }
 
@@ -274,6 +302,8 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventEmployeeUpdated, webhookEmployeeData(employee))
+
 	c.JSON(http.StatusOK, gin.H{"data": buildEmployeeResponse(&employee)})
 }
 
@@ -286,6 +316,12 @@ func (h *EmployeeHandler) DeleteEmployee(c *gin.Context) {
 	}
 	id := uint(parsed)
 
+	var deleted []models.Employee
+	if err := h.db.Where("id = ?", id).Find(&deleted).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employee"})
+		return
+	}
+
 	if err := h.db.Transaction(func(tx *gorm.DB) error {
 		if err := h.cleanupAfterEmployeeDelete(tx, []uint{id}); err != nil {
 			return err
@@ -295,6 +331,8 @@ func (h *EmployeeHandler) DeleteEmployee(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete employee"})
 		return
 	}
+
+	h.publishEmployeesDeleted(deleted)
 	c.Status(http.StatusNoContent)
 }
 
@@ -309,6 +347,12 @@ func (h *EmployeeHandler) DeleteEmployeesBatch(c *gin.Context) {
 		return
 	}
 
+	var deleted []models.Employee
+	if err := h.db.Where("id IN ?", payload.IDs).Find(&deleted).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employees"})
+		return
+	}
+
 	if err := h.db.Transaction(func(tx *gorm.DB) error {
 		if err := h.cleanupAfterEmployeeDelete(tx, payload.IDs); err != nil {
 			return err
@@ -319,9 +363,17 @@ func (h *EmployeeHandler) DeleteEmployeesBatch(c *gin.Context) {
 		return
 	}
 
+	h.publishEmployeesDeleted(deleted)
 	c.Status(http.StatusNoContent)
 }
 
+// publishEmployeesDeleted sends one employee.deleted event per removed employee.
+func (h *EmployeeHandler) publishEmployeesDeleted(employees []models.Employee) {
+	for _, employee := range employees {
+		h.webhooks.Publish(webhook.EventEmployeeDeleted, webhookEmployeeData(employee))
+	}
+}
+
 func (h *EmployeeHandler) ListDivisions(c *gin.Context) {
 	var divisions []models.EmployeeDivision
 	if err := h.db.Order("name ASC").Find(&divisions).Error; err != nil {
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validationError reports a problem with the request found while applying it,
+// usually inside a transaction; it surfaces as a 400.
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+func newValidationError(format string, args ...any) error {
+	return &validationError{message: fmt.Sprintf(format, args...)}
+}
+
+// notFoundError reports a record the request refers to that does not exist; it
+// surfaces as a 404.
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func newNotFoundError(format string, args ...any) error {
+	return &notFoundError{message: fmt.Sprintf(format, args...)}
+}
+
+// respondError maps validation errors to 400, not-found errors to 404 and
+// everything else to 500 with fallback as the error.
+func respondError(c *gin.Context, err error, fallback string) {
+	var validationErr *validationError
+	var notFoundErr *notFoundError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+	case errors.As(err, &notFoundErr):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundErr.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB opens a private in-memory database with every table the handlers use.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=foreign_keys(0)", name)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.PermissionMigration{}, &models.RoleMenu{},
+		&models.User{}, &models.UserSession{}, &models.RecoveryCode{}, &models.PasswordReset{},
+		&models.LoginAttempt{}, &models.AuditLog{}, &models.OIDCLogin{},
+		&models.EmployeeDivision{}, &models.EmployeePosition{}, &models.Employee{},
+		&models.Warehouse{}, &models.Lead{}, &models.Project{}, &models.UserWarehouse{},
+		&models.APIKey{}, &models.UserInvite{}, &models.WarehouseLocation{}, &models.Category{},
+		&models.InventoryItem{}, &models.UnitOfMeasure{}, &models.ItemUnitConversion{},
+		&models.PurchaseOrder{}, &models.POItem{}, &models.StockReservation{},
+		&models.InventoryTransaction{}, &models.Notification{},
+		&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.ImportBatch{}, &models.Job{},
+		&models.SerialNumber{}, &models.SerialMovement{}, &models.InventoryBinStock{},
+		&models.NotificationSetting{}, &models.NotificationHistory{}, &models.SiteSetting{},
+	); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	middleware.InvalidatePermissionCache()
+	t.Cleanup(func() { middleware.InvalidatePermissionCache() })
+	return db
+}
+
+// mustCreate inserts the rows or fails the test.
+func mustCreate(t *testing.T, db *gorm.DB, values ...interface{}) {
+	t.Helper()
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("create %T: %v", value, err)
+		}
+	}
+}
+
+// grantPermissions creates the permissions when missing and attaches them to the role.
+func grantPermissions(t *testing.T, db *gorm.DB, role *models.Role, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		permission := models.Permission{Name: name}
+		if err := db.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+			t.Fatalf("create permission %s: %v", name, err)
+		}
+		if err := db.Model(role).Association("Permissions").Append(&permission); err != nil {
+			t.Fatalf("grant permission %s: %v", name, err)
+		}
+	}
+	middleware.InvalidatePermissionCache(role.ID)
+}
+
+// asUser stands in for AuthMiddleware and signs every request in as user.
+func asUser(user models.User) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Set("email", user.Email)
+		c.Set("role_id", user.RoleID)
+		c.Next()
+	}
+}
+
+type testResponse struct {
+	Code int
+	Body string
+}
+
+// decode unmarshals the JSON body or fails the test.
+func (r testResponse) decode(t *testing.T, dest interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(r.Body), dest); err != nil {
+		t.Fatalf("decode response %q: %v", r.Body, err)
+	}
+}
+
+// serve sends a request to router; a non-nil body is encoded as JSON unless it is an io.Reader.
+func serve(router http.Handler, method, path string, body interface{}, headers ...string) testResponse {
+	var reader io.Reader
+	switch value := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = value
+	default:
+		encoded, _ := json.Marshal(value)
+		reader = bytes.NewReader(encoded)
+		headers = append([]string{"Content-Type", "application/json"}, headers...)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return testResponse{Code: recorder.Code, Body: recorder.Body.String()}
+}
+
+// newAPIKey stores a key for creator that holds the permissions directly and
+// returns the plaintext key to send in X-API-Key.
+func newAPIKey(t *testing.T, db *gorm.DB, creator models.User, permissions ...string) string {
+	t.Helper()
+	plain := middleware.APIKeyPrefix + strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()) + fmt.Sprint(creator.ID)
+	key := models.APIKey{Name: "test key", Prefix: plain[:8], KeyHash: middleware.HashAPIKey(plain), CreatedByID: creator.ID}
+	mustCreate(t, db, &key)
+	for _, name := range permissions {
+		permission := models.Permission{Name: name}
+		if err := db.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+			t.Fatalf("create permission %s: %v", name, err)
+		}
+		if err := db.Model(&key).Association("Permissions").Append(&permission); err != nil {
+			t.Fatalf("grant key permission %s: %v", name, err)
+		}
+	}
+	return plain
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// InboxHandler serves the in-app notifications of the signed-in user.
+type InboxHandler struct {
+	db *gorm.DB
+}
+
+func NewInboxHandler(db *gorm.DB) *InboxHandler {
+	return &InboxHandler{db: db}
+}
+
+var inboxListSort = listSort{
+	table:       "notifications",
+	fields:      map[string]string{"id": "notifications.id", "created_at": "notifications.created_at"},
+	defaultSort: "created_at",
+	defaultDesc: true,
+}
+
+// ownInbox limits a query to the in-app notifications of the current user.
+func (h *InboxHandler) ownInbox(c *gin.Context) *gorm.DB {
+	return h.db.Model(&models.Notification{}).
+		Where("user_id = ? AND type = ?", c.GetUint("user_id"), notification.TypeSystem)
+}
+
+func (h *InboxHandler) findOwnNotification(c *gin.Context) (*models.Notification, bool) {
+	var item models.Notification
+	if err := h.ownInbox(c).First(&item, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification"})
+		return nil, false
+	}
+	return &item, true
+}
+
+// ListInbox returns the user's notifications, newest first. ?status=unread or read filters them.
+func (h *InboxHandler) ListInbox(c *gin.Context) {
+	params, err := parseListParams(c, inboxListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.ownInbox(c)
+	switch c.Query("status") {
+	case "":
+	case "unread":
+		query = query.Where("read_at IS NULL")
+	case "read":
+		query = query.Where("read_at IS NOT NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use unread or read"})
+		return
+	}
+	if module := strings.TrimSpace(c.Query("module")); module != "" {
+		query = query.Where("module = ?", module)
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	var items []models.Notification
+	if err := params.apply(query, inboxListSort).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch notifications",
+			"message": err.Error(),
+		})
+		return
+	}
+	keep, meta := params.finish(c, total, len(items), func(i int) uint { return items[i].ID })
+
+	c.JSON(http.StatusOK, gin.H{"data": items[:keep], "meta": meta})
+}
+
+// GetUnreadCount returns the number of unread notifications, for the header badge.
+func (h *InboxHandler) GetUnreadCount(c *gin.Context) {
+	var count int64
+	if err := h.ownInbox(c).Where("read_at IS NULL").Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"unread": count}})
+}
+
+// MarkRead marks one notification as read. Reading it again keeps the first read time.
+func (h *InboxHandler) MarkRead(c *gin.Context) {
+	item, ok := h.findOwnNotification(c)
+	if !ok {
+		return
+	}
+
+	if item.ReadAt == nil {
+		now := time.Now()
+		if err := h.db.Model(item).Updates(map[string]interface{}{
+			"status":  notification.StatusRead,
+			"read_at": now,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		item.Status = notification.StatusRead
+		item.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification marked as read",
+		"data":    item,
+	})
+}
+
+// MarkAllRead marks every unread notification of the user as read.
+func (h *InboxHandler) MarkAllRead(c *gin.Context) {
+	result := h.ownInbox(c).Where("read_at IS NULL").Updates(map[string]interface{}{
+		"status":  notification.StatusRead,
+		"read_at": time.Now(),
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All notifications marked as read",
+		"updated": result.RowsAffected,
+	})
+}
+
+// DeleteNotification removes a notification from the user's inbox.
+func (h *InboxHandler) DeleteNotification(c *gin.Context) {
+	item, ok := h.findOwnNotification(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted"})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+)
+
+// Inbox notifications are written after the change was committed. Failures are
+// logged only, the change itself already succeeded.
+
+func formatInboxQuantity(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// notifyLowStock tells the low stock subscribers that can see the item's
+// warehouse that it reached its minimum stock.
+func notifyLowStock(inbox *notification.InboxService, item models.InventoryItem) {
+	if inbox == nil {
+		return
+	}
+	recipients, err := inbox.LowStockSubscribers(item.WarehouseID)
+	if err != nil {
+		log.Printf("[inbox] failed to load low stock recipients for item %d: %v", item.ID, err)
+		return
+	}
+	if err := inbox.Notify(recipients, notification.InboxMessage{
+		Module:  "warehouse",
+		Subject: "Low stock: " + item.Name,
+		Message: fmt.Sprintf("%s is down to %s %s, at or below its minimum of %s.",
+			item.Name, formatInboxQuantity(item.Quantity), item.Unit, formatInboxQuantity(item.MinStock)),
+		Data: map[string]interface{}{
+			"item_id":      item.ID,
+			"warehouse_id": item.WarehouseID,
+			"quantity":     item.Quantity,
+			"min_stock":    item.MinStock,
+		},
+	}); err != nil {
+		log.Printf("[inbox] failed to notify low stock of item %d: %v", item.ID, err)
+	}
+}
+
+// notifyPOSubmitted asks every approver except the submitter to review a pending PO.
+func notifyPOSubmitted(inbox *notification.InboxService, po models.PurchaseOrder, submitterID uint) {
+	if inbox == nil {
+		return
+	}
+	approvers, err := inbox.UsersWithPermission(0, "po.approve")
+	if err != nil {
+		log.Printf("[inbox] failed to load approvers for PO %d: %v", po.ID, err)
+		return
+	}
+	recipients := make([]uint, 0, len(approvers))
+	for _, id := range approvers {
+		if id != submitterID {
+			recipients = append(recipients, id)
+		}
+	}
+	if err := inbox.Notify(recipients, notification.InboxMessage{
+		Module:  "po",
+		Subject: "PO " + po.PONumber + " awaits approval",
+		Message: fmt.Sprintf("Purchase order %s from %s (total %.2f) is waiting for approval.",
+			po.PONumber, po.SupplierName, po.TotalAmount),
+		Data: map[string]interface{}{"po_id": po.ID, "status": po.Status},
+	}); err != nil {
+		log.Printf("[inbox] failed to notify approvers of PO %d: %v", po.ID, err)
+	}
+}
+
+// notifyPODecision tells the requester that their PO was approved or rejected.
+func notifyPODecision(inbox *notification.InboxService, po models.PurchaseOrder) {
+	if inbox == nil {
+		return
+	}
+	message := fmt.Sprintf("Your purchase order %s was %s.", po.PONumber, po.Status)
+	if po.RejectionReason != "" && po.Status == "rejected" {
+		message += "\n\nReason: " + po.RejectionReason
+	}
+	if err := inbox.Notify([]uint{po.RequestedByID}, notification.InboxMessage{
+		Module:  "po",
+		Subject: "PO " + po.PONumber + " " + po.Status,
+		Message: message,
+		Data:    map[string]interface{}{"po_id": po.ID, "status": po.Status},
+	}); err != nil {
+		log.Printf("[inbox] failed to notify requester of PO %d: %v", po.ID, err)
+	}
+}
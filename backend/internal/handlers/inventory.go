@@ -5,8 +5,8 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -14,16 +14,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jung-kurt/gofpdf"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"tatapps/internal/models"
 	"tatapps/internal/services/notification"
+	"tatapps/internal/services/realtime"
+	"tatapps/internal/services/webhook"
 )
 
 type InventoryHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	webhooks *webhook.Dispatcher
+	inbox    *notification.InboxService
+	stream   *realtime.Hub
 }
 
-func NewInventoryHandler(db *gorm.DB) *InventoryHandler {
-	return &InventoryHandler{db: db}
+func NewInventoryHandler(db *gorm.DB, webhooks *webhook.Dispatcher, inbox *notification.InboxService, stream *realtime.Hub) *InventoryHandler {
+	return &InventoryHandler{db: db, webhooks: webhooks, inbox: inbox, stream: stream}
 }
 
 func toUint(value any) (uint, bool) {
@@ -77,41 +83,33 @@ func (h *InventoryHandler) contextUserID(c *gin.Context) (uint, bool) {
 	return id, true
 }
 
-func (h *InventoryHandler) getUserWarehouseIDs(userID uint) ([]uint, error) {
-	var ids []uint
-	if err := h.db.Model(&models.UserWarehouse{}).
-		Where("user_id = ?", userID).
-		Pluck("warehouse_id", &ids).Error; err != nil {
-		return nil, err
-	}
-	return ids, nil
+var itemListSort = listSort{
+	table: "inventory_items",
+	fields: map[string]string{
+		"id":         "inventory_items.id",
+		"name":       "inventory_items.name",
+		"sn":         "inventory_items.sku",
+		"category":   "inventory_items.category",
+		"quantity":   "inventory_items.quantity",
+		"min_stock":  "inventory_items.min_stock",
+		"unit_price": "inventory_items.unit_price",
+		"created_at": "inventory_items.created_at",
+		"updated_at": "inventory_items.updated_at",
+	},
+	defaultSort: "name",
 }
 
-func filterTransactionsByWarehouses(transactions []models.InventoryTransaction, allowed map[uint]struct{}) []models.InventoryTransaction {
-	if len(allowed) == 0 {
-		return []models.InventoryTransaction{}
-	}
-
-	filtered := make([]models.InventoryTransaction, 0, len(transactions))
-	for _, tx := range transactions {
-		if _, ok := allowed[tx.Item.WarehouseID]; ok {
-			filtered = append(filtered, tx)
-			continue
-		}
-		if tx.FromWarehouseID != nil {
-			if _, ok := allowed[*tx.FromWarehouseID]; ok {
-				filtered = append(filtered, tx)
-				continue
-			}
-		}
-		if tx.ToWarehouseID != nil {
-			if _, ok := allowed[*tx.ToWarehouseID]; ok {
-				filtered = append(filtered, tx)
-				continue
-			}
-		}
-	}
-	return filtered
+var transactionListSort = listSort{
+	table: "inventory_transactions",
+	fields: map[string]string{
+		"id":         "inventory_transactions.id",
+		"created_at": "inventory_transactions.created_at",
+		"type":       "inventory_transactions.type",
+		"quantity":   "inventory_transactions.quantity",
+		"reference":  "inventory_transactions.reference",
+	},
+	defaultSort: "created_at",
+	defaultDesc: true,
 }
 
 // GetAllItems godoc
@@ -121,48 +119,49 @@ func filterTransactionsByWarehouses(transactions []models.InventoryTransaction,
 // @Success 200 {object} map[string]interface{}
 // @Router /inventory [get]
 func (h *InventoryHandler) GetAllItems(c *gin.Context) {
-	var items []models.InventoryItem
+	params, err := parseListParams(c, itemListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.items(applyInventoryFilters(h.db.Model(&models.InventoryItem{}), c.Request.URL.Query()))
 
-	query := applyInventoryFilters(h.db.Preload("Warehouse"), c)
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to count inventory items",
+			"message": err.Error(),
+		})
+		return
+	}
 
-	if err := query.Find(&items).Error; err != nil {
+	var items []models.InventoryItem
+	if err := params.apply(query.Preload("Warehouse"), itemListSort).Find(&items).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch inventory items",
 			"message": err.Error(),
 		})
 		return
 	}
+	keep, meta := params.finish(c, total, len(items), func(i int) uint { return items[i].ID })
+	items = items[:keep]
 
-	if roleName, ok := c.Get("role_name"); ok {
-		if name, isString := roleName.(string); isString && strings.EqualFold(name, "employee") {
-			userID, ok := h.contextUserID(c)
-			if !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
-				return
-			}
-
-			allowedIDs, err := h.getUserWarehouseIDs(userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-				return
-			}
-			if len(allowedIDs) == 0 {
-				items = []models.InventoryItem{}
-			} else {
-				allowedSet := buildUintSet(allowedIDs)
-				filtered := make([]models.InventoryItem, 0, len(items))
-				for _, item := range items {
-					if _, ok := allowedSet[item.WarehouseID]; ok {
-						filtered = append(filtered, item)
-					}
-				}
-				items = filtered
-			}
-		}
+	if err := attachReservedQuantities(h.db, items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch reserved quantities",
+			"message": err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": items,
+		"meta": meta,
 	})
 }
 
@@ -180,8 +179,13 @@ func (h *InventoryHandler) GetItemByID(c *gin.Context) {
 		return
 	}
 
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
 	var item models.InventoryItem
-	if err := h.db.Preload("Warehouse").First(&item, id).Error; err != nil {
+	if err := scope.items(h.db.Preload("Warehouse")).First(&item, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
 			return
@@ -193,6 +197,14 @@ func (h *InventoryHandler) GetItemByID(c *gin.Context) {
 		return
 	}
 
+	if err := attachReservedQuantity(h.db, &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch reserved quantity",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": item,
 	})
@@ -218,6 +230,21 @@ func (h *InventoryHandler) CreateItem(c *gin.Context) {
 
 	item.SN = strings.TrimSpace(item.SN)
 
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	if !scope.allows(item.WarehouseID) {
+		respondWarehouseForbidden(c)
+		return
+	}
+
 	// Check if SN already exists
 	if item.SN != "" {
 		var existing models.InventoryItem
@@ -236,7 +263,15 @@ func (h *InventoryHandler) CreateItem(c *gin.Context) {
 		item.IsActive = false
 	}
 
-	if err := h.db.Create(&item).Error; err != nil {
+	// The opening quantity is booked as an adjustment so the transaction log
+	// accounts for it.
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		return recordStockCorrection(tx, &item, item.Quantity, stockCorrectionOpening, userID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create item",
 			"message": err.Error(),
@@ -246,6 +281,7 @@ func (h *InventoryHandler) CreateItem(c *gin.Context) {
 
 	// Reload with warehouse
 	h.db.Preload("Warehouse").First(&item, item.ID)
+	item.AvailableQuantity = item.Quantity
 
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    item,
@@ -275,6 +311,8 @@ type updateInventoryItemRequest struct {
 	Unit        *string  `json:"unit"`
 	Description *string  `json:"description"`
 	IsActive    *bool    `json:"is_active"`
+
+	IsSerialized *bool `json:"is_serialized"`
 }
 
 func (h *InventoryHandler) UpdateItem(c *gin.Context) {
@@ -284,16 +322,14 @@ func (h *InventoryHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
-	var item models.InventoryItem
-	if err := h.db.First(&item, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch item",
-			"message": err.Error(),
-		})
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
 		return
 	}
 
@@ -305,72 +341,124 @@ func (h *InventoryHandler) UpdateItem(c *gin.Context) {
 		})
 		return
 	}
+	if req.WarehouseID != nil && !scope.allows(*req.WarehouseID) {
+		respondWarehouseForbidden(c)
+		return
+	}
 
-	if req.SN != nil {
-		trimmed := strings.TrimSpace(*req.SN)
-		if trimmed != "" && trimmed != item.SN {
-			var existing models.InventoryItem
-			if err := h.db.Where("sku = ? AND id != ?", trimmed, id).First(&existing).Error; err == nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "SN already exists",
-				})
-				return
+	// The reservation check and the save share one transaction holding the
+	// item row, so no reservation can slip in between them. A quantity edit is
+	// booked as an adjustment.
+	var item models.InventoryItem
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := scope.items(tx.Clauses(clause.Locking{Strength: "UPDATE"})).First(&item, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return newNotFoundError("Item not found")
 			}
+			return err
 		}
-		item.SN = trimmed
-	}
+		quantityBefore := item.Quantity
 
-	if req.Name != nil {
-		item.Name = strings.TrimSpace(*req.Name)
-	}
-	if req.Category != nil {
-		item.Category = strings.TrimSpace(*req.Category)
-	}
-	if req.WarehouseID != nil {
-		item.WarehouseID = *req.WarehouseID
-	}
-	if req.Quantity != nil {
-		item.Quantity = *req.Quantity
-	}
-	if req.MinStock != nil {
-		item.MinStock = *req.MinStock
-	}
-	if req.MaxStock != nil {
-		item.MaxStock = *req.MaxStock
-	}
-	if req.UnitPrice != nil {
-		item.UnitPrice = *req.UnitPrice
-	}
-	if req.Unit != nil {
-		item.Unit = strings.TrimSpace(*req.Unit)
-	}
-	if req.Description != nil {
-		item.Description = *req.Description
-	}
-	statusProvided := req.IsActive != nil
-	if statusProvided && req.IsActive != nil {
-		item.IsActive = *req.IsActive
-	}
+		if req.SN != nil {
+			trimmed := strings.TrimSpace(*req.SN)
+			if trimmed != "" && trimmed != item.SN {
+				var existing models.InventoryItem
+				if err := tx.Where("sku = ? AND id != ?", trimmed, id).First(&existing).Error; err == nil {
+					return newValidationError("SN already exists")
+				}
+			}
+			item.SN = trimmed
+		}
+
+		if req.Name != nil {
+			item.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Category != nil {
+			item.Category = strings.TrimSpace(*req.Category)
+		}
+		// Bins belong to the item's warehouse, so binned stock pins the warehouse
+		// and is the floor for the quantity.
+		unassigned, err := unassignedBinQuantity(tx, &item)
+		if err != nil {
+			return err
+		}
+		binned := item.Quantity - unassigned
+		if req.WarehouseID != nil && *req.WarehouseID != item.WarehouseID {
+			if binned > driftTolerance {
+				return newValidationError("Item still holds %s %s in bins; move it out of its bins before changing the warehouse", formatFloat(binned), item.Unit)
+			}
+			item.WarehouseID = *req.WarehouseID
+		}
+		if req.IsSerialized != nil && *req.IsSerialized != item.IsSerialized {
+			var serialCount int64
+			tx.Model(&models.SerialNumber{}).Where("item_id = ?", item.ID).Count(&serialCount)
+			if !*req.IsSerialized && serialCount > 0 {
+				return newValidationError("Serial tracking cannot be disabled while serial numbers exist")
+			}
+			item.IsSerialized = *req.IsSerialized
+		}
+		if req.Quantity != nil {
+			if item.IsSerialized && *req.Quantity != item.Quantity {
+				return newValidationError("Quantity of a serialised item follows its serial numbers")
+			}
+			if *req.Quantity < item.Quantity {
+				if err := ensureUnreservedStock(tx, &item, item.Quantity-*req.Quantity); err != nil {
+					return err
+				}
+			}
+			if *req.Quantity < binned-driftTolerance {
+				return newValidationError("Quantity cannot be below the %s %s stored in bins; take stock out of its bins first", formatFloat(binned), item.Unit)
+			}
+			item.Quantity = *req.Quantity
+		}
+		if req.MinStock != nil {
+			item.MinStock = *req.MinStock
+		}
+		if req.MaxStock != nil {
+			item.MaxStock = *req.MaxStock
+		}
+		if req.UnitPrice != nil {
+			item.UnitPrice = *req.UnitPrice
+		}
+		if req.Unit != nil {
+			item.Unit = strings.TrimSpace(*req.Unit)
+		}
+		if req.Description != nil {
+			item.Description = *req.Description
+		}
+		statusProvided := req.IsActive != nil
+		if statusProvided && req.IsActive != nil {
+			item.IsActive = *req.IsActive
+		}
+
+		if !statusProvided {
+			if item.Quantity == 0 {
+				item.IsActive = false
+			} else if item.Quantity > 0 {
+				item.IsActive = true
+			}
+		}
 
-	if !statusProvided {
-		if item.Quantity == 0 {
-			item.IsActive = false
-		} else if item.Quantity > 0 {
-			item.IsActive = true
+		if err := tx.Save(&item).Error; err != nil {
+			return err
 		}
+		return recordStockCorrection(tx, &item, item.Quantity-quantityBefore, stockCorrectionEdit, userID)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update item")
+		return
 	}
 
-	if err := h.db.Save(&item).Error; err != nil {
+	if err := h.db.Preload("Warehouse").First(&item, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update item",
+			"error":   "Failed to reload item",
 			"message": err.Error(),
 		})
 		return
 	}
-
-	if err := h.db.Preload("Warehouse").First(&item, id).Error; err != nil {
+	if err := attachReservedQuantity(h.db, &item); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to reload item",
+			"error":   "Failed to fetch reserved quantity",
 			"message": err.Error(),
 		})
 		return
@@ -396,26 +484,29 @@ func (h *InventoryHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
-	var item models.InventoryItem
-	if err := h.db.First(&item, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch item",
-			"message": err.Error(),
-		})
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
 		return
 	}
 
-	if err := h.db.Delete(&item).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to delete item",
-			"message": err.Error(),
-		})
+	var item models.InventoryItem
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := scope.items(tx.Clauses(clause.Locking{Strength: "UPDATE"})).First(&item, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return newNotFoundError("Item not found")
+			}
+			return err
+		}
+		if err := ensureItemsDeletable(tx, []models.InventoryItem{item}); err != nil {
+			return err
+		}
+		return tx.Delete(&item).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete item")
 		return
 	}
+	streamItemsDeleted(h.stream, []models.InventoryItem{item})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Item deleted successfully",
@@ -443,13 +534,37 @@ func (h *InventoryHandler) DeleteItemsBatch(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", payload.IDs).Delete(&models.InventoryItem{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to delete items",
-			"message": err.Error(),
-		})
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	// Every requested item must be visible to the caller and free of reservations
+	// and bin stock; otherwise nothing is deleted.
+	var visible []models.InventoryItem
+	forbidden := false
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := scope.items(tx.Clauses(clause.Locking{Strength: "UPDATE"})).Where("id IN ?", payload.IDs).Order("id").Find(&visible).Error; err != nil {
+			return err
+		}
+		if len(visible) != len(buildUintSet(payload.IDs)) {
+			forbidden = true
+			return nil
+		}
+		if err := ensureItemsDeletable(tx, visible); err != nil {
+			return err
+		}
+		return scope.items(tx).Where("id IN ?", payload.IDs).Delete(&models.InventoryItem{}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete items")
+		return
+	}
+	if forbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Some items do not exist or belong to a warehouse you cannot access"})
 		return
 	}
+	streamItemsDeleted(h.stream, visible)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": fmt.Sprintf("%d items deleted successfully", len(payload.IDs)),
@@ -457,6 +572,40 @@ func (h *InventoryHandler) DeleteItemsBatch(c *gin.Context) {
 	})
 }
 
+// ensureItemsDeletable rejects deleting items that still have active
+// reservations or stock in bins; both would be left pointing at a deleted item.
+// The caller holds the item rows locked.
+func ensureItemsDeletable(tx *gorm.DB, items []models.InventoryItem) error {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	reserved, err := reservedQuantities(tx, ids)
+	if err != nil {
+		return err
+	}
+	var binned []uint
+	if err := tx.Model(&models.InventoryBinStock{}).
+		Where("item_id IN ? AND quantity <> 0", ids).
+		Distinct().
+		Pluck("item_id", &binned).Error; err != nil {
+		return err
+	}
+	inBins := buildUintSet(binned)
+
+	for _, item := range items {
+		if reserved[item.ID] > 0 {
+			return newValidationError("Item %s still has %s %s reserved; cancel or fulfil its reservations first",
+				item.SN, formatFloat(reserved[item.ID]), item.Unit)
+		}
+		if _, ok := inBins[item.ID]; ok {
+			return newValidationError("Item %s still holds stock in bins; move it out of its bins first", item.SN)
+		}
+	}
+	return nil
+}
+
 // GetItemTransactions godoc
 // @Summary Get transactions for an item
 // @Tags Inventory
@@ -471,45 +620,17 @@ func (h *InventoryHandler) GetItemTransactions(c *gin.Context) {
 		return
 	}
 
-	if roleName, ok := c.Get("role_name"); ok {
-		if name, isString := roleName.(string); isString && strings.EqualFold(name, "employee") {
-			userID, ok := h.contextUserID(c)
-			if !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
-				return
-			}
-
-			allowedIDs, err := h.getUserWarehouseIDs(userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-				return
-			}
-			if len(allowedIDs) == 0 {
-				c.JSON(http.StatusForbidden, gin.H{"error": "No warehouse access configured for this account"})
-				return
-			}
-
-			allowedSet := buildUintSet(allowedIDs)
-			var item models.InventoryItem
-			if err := h.db.Select("id", "warehouse_id").First(&item, id).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-					return
-				}
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
-				return
-			}
-			if _, ok := allowedSet[item.WarehouseID]; !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access transactions for this warehouse"})
-				return
-			}
-		}
+	if _, ok := findScopedItem(h.db, c, uint(id)); !ok {
+		return
 	}
 
 	var transactions []models.InventoryTransaction
 	if err := h.db.
 		Preload("FromWarehouse").
 		Preload("ToWarehouse").
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Preload("Reservation").
 		Preload("CreatedBy").
 		Where("item_id = ?", id).
 		Order("created_at DESC").
@@ -533,16 +654,36 @@ func (h *InventoryHandler) GetItemTransactions(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /inventory/transactions [get]
 func (h *InventoryHandler) GetAllTransactions(c *gin.Context) {
-	var transactions []models.InventoryTransaction
+	params, err := parseListParams(c, transactionListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	query := applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.transactions(applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c.Request.URL.Query()))
 
-	if err := query.
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to count transactions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var transactions []models.InventoryTransaction
+	if err := params.apply(query, transactionListSort).
 		Preload("Item").
 		Preload("FromWarehouse").
 		Preload("ToWarehouse").
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Preload("Reservation").
 		Preload("CreatedBy").
-		Order("created_at DESC").
 		Find(&transactions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch transactions",
@@ -550,30 +691,11 @@ func (h *InventoryHandler) GetAllTransactions(c *gin.Context) {
 		})
 		return
 	}
-
-	if roleName, ok := c.Get("role_name"); ok {
-		if name, isString := roleName.(string); isString && strings.EqualFold(name, "employee") {
-			userID, ok := h.contextUserID(c)
-			if !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
-				return
-			}
-			allowedIDs, err := h.getUserWarehouseIDs(userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-				return
-			}
-			if len(allowedIDs) == 0 {
-				transactions = []models.InventoryTransaction{}
-			} else {
-				allowedSet := buildUintSet(allowedIDs)
-				transactions = filterTransactionsByWarehouses(transactions, allowedSet)
-			}
-		}
-	}
+	keep, meta := params.finish(c, total, len(transactions), func(i int) uint { return transactions[i].ID })
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": transactions,
+		"data": transactions[:keep],
+		"meta": meta,
 	})
 }
 
@@ -625,26 +747,9 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 	}
 	transaction.CreatedByID = userID
 
-	roleName := ""
-	if roleValue, exists := c.Get("role_name"); exists {
-		if name, isString := roleValue.(string); isString {
-			roleName = name
-		}
-	}
-
-	isEmployee := strings.EqualFold(roleName, "employee")
-	var allowedSet map[uint]struct{}
-	if isEmployee {
-		allowedIDs, err := h.getUserWarehouseIDs(userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-			return
-		}
-		if len(allowedIDs) == 0 {
-			c.JSON(http.StatusForbidden, gin.H{"error": "No warehouse access configured for this account"})
-			return
-		}
-		allowedSet = buildUintSet(allowedIDs)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
 	}
 
 	// First, check if item exists (before starting DB transaction)
@@ -668,11 +773,9 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 
 	println("Item found:", item.Name, "SN:", item.SN, "Current quantity:", item.Quantity)
 
-	if isEmployee {
-		if _, exists := allowedSet[item.WarehouseID]; !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to record transactions for this warehouse"})
-			return
-		}
+	if !scope.allows(item.WarehouseID) || !scope.allowsOptional(transaction.ToWarehouseID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to record transactions for this warehouse"})
+		return
 	}
 
 	// Begin transaction
@@ -683,8 +786,8 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 		}
 	}()
 
-	// Get the item again within transaction
-	if err := tx.Preload("Warehouse").First(&item, transaction.ItemID).Error; err != nil {
+	// Get the item again within transaction, locked against concurrent movements and reservations
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Warehouse").First(&item, transaction.ItemID).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to lock item",
@@ -692,13 +795,47 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 		})
 		return
 	}
+	quantityBefore := item.Quantity
+
+	// Serialised stock only moves through the serial endpoints so statuses stay in sync
+	if item.IsSerialized && transaction.Type != "bin_move" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Item is serialised; record movements through its serial numbers",
+		})
+		return
+	}
+
+	// Quantities may be entered in any unit defined for the item
+	if err := normalizeTransactionUnit(tx, &item, &transaction); err != nil {
+		tx.Rollback()
+		respondError(c, err, "Failed to record transaction")
+		return
+	}
+
+	if transaction.ReservationID != nil && transaction.Type != "out" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Reservations can only be referenced by out transactions",
+		})
+		return
+	}
 
 	// Validate and update quantity based on transaction type
 	switch transaction.Type {
 	case "in":
+		transaction.FromLocationID = nil
+		if transaction.ToLocationID != nil {
+			if err := adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.ToLocationID, transaction.Quantity); err != nil {
+				tx.Rollback()
+				respondError(c, err, "Failed to record transaction")
+				return
+			}
+		}
 		item.Quantity += transaction.Quantity
 
 	case "out":
+		transaction.ToLocationID = nil
 		if item.Quantity < transaction.Quantity {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -706,6 +843,23 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 			})
 			return
 		}
+		// Reserved stock may only be issued against its reservation
+		if transaction.ReservationID != nil {
+			if err := consumeReservation(tx, *transaction.ReservationID, item.ID, transaction.Quantity); err != nil {
+				tx.Rollback()
+				respondError(c, err, "Failed to record transaction")
+				return
+			}
+		} else if err := ensureUnreservedStock(tx, &item, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
+		if err := takeFromBinOrUnassigned(tx, &item, transaction.FromLocationID, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
 		item.Quantity -= transaction.Quantity
 
 	case "transfer":
@@ -732,6 +886,17 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 			})
 			return
 		}
+		if err := ensureUnreservedStock(tx, &item, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
+
+		if err := takeFromBinOrUnassigned(tx, &item, transaction.FromLocationID, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
 
 		// Reduce from source warehouse
 		item.Quantity -= transaction.Quantity
@@ -772,6 +937,14 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 				})
 				return
 			}
+			if err := copyUnitConversions(tx, item.ID, destItem.ID); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to copy unit conversions",
+					"message": err.Error(),
+				})
+				return
+			}
 		case err == nil:
 			destItem.Name = item.Name
 			destItem.Description = item.Description
@@ -803,6 +976,14 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 			return
 		}
 
+		if transaction.ToLocationID != nil {
+			if err := adjustBinStock(tx, destItem.ID, destItem.WarehouseID, *transaction.ToLocationID, transaction.Quantity); err != nil {
+				tx.Rollback()
+				respondError(c, err, "Failed to record transaction")
+				return
+			}
+		}
+
 		transaction.FromWarehouseID = &item.WarehouseID
 
 	case "adjustment":
@@ -815,8 +996,67 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 			})
 			return
 		}
+		if transaction.Quantity < 0 {
+			if err := ensureUnreservedStock(tx, &item, -transaction.Quantity); err != nil {
+				tx.Rollback()
+				respondError(c, err, "Failed to record transaction")
+				return
+			}
+		}
+		// Negative adjustments draw from from_location_id, positive ones land in to_location_id.
+		var binErr error
+		if transaction.Quantity < 0 {
+			transaction.ToLocationID = nil
+			binErr = takeFromBinOrUnassigned(tx, &item, transaction.FromLocationID, -transaction.Quantity)
+		} else {
+			transaction.FromLocationID = nil
+			if transaction.ToLocationID != nil {
+				binErr = adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.ToLocationID, transaction.Quantity)
+			}
+		}
+		if binErr != nil {
+			tx.Rollback()
+			respondError(c, binErr, "Failed to record transaction")
+			return
+		}
 		item.Quantity = newQuantity
 
+	case "bin_move":
+		// Moves stock between bins of the same warehouse; the item total is unchanged.
+		if transaction.ToLocationID == nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Destination bin is required for bin move",
+			})
+			return
+		}
+		if transaction.Quantity <= 0 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Quantity must be greater than zero",
+			})
+			return
+		}
+		if transaction.FromLocationID != nil && *transaction.FromLocationID == *transaction.ToLocationID {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Source and destination bins must differ",
+			})
+			return
+		}
+		if err := takeFromBinOrUnassigned(tx, &item, transaction.FromLocationID, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
+		if err := adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.ToLocationID, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to record transaction")
+			return
+		}
+		transaction.FromWarehouseID = &item.WarehouseID
+		transaction.ToWarehouseID = &item.WarehouseID
+
 	default:
 		tx.Rollback()
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -874,9 +1114,14 @@ func (h *InventoryHandler) RecordTransaction(c *gin.Context) {
 	h.db.
 		Preload("FromWarehouse").
 		Preload("ToWarehouse").
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Preload("Reservation").
 		Preload("CreatedBy").
 		First(&transaction, transaction.ID)
 
+	h.publishStockChange(webhook.EventTransactionCreated, transaction, item, quantityBefore)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    transaction,
 		"message": "Transaction recorded successfully",
@@ -897,8 +1142,13 @@ func (h *InventoryHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
 	var transaction models.InventoryTransaction
-	if err := h.db.Preload("Item").First(&transaction, id).Error; err != nil {
+	if err := scope.transactions(h.db.Preload("Item")).First(&transaction, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
 			return
@@ -910,6 +1160,25 @@ func (h *InventoryHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
+	// Reverting touches every warehouse the transaction moved stock between.
+	if !scope.allows(transaction.Item.WarehouseID) || !scope.allowsOptional(transaction.FromWarehouseID) || !scope.allowsOptional(transaction.ToWarehouseID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to delete transactions for this warehouse"})
+		return
+	}
+
+	if linked, err := serialMovementTransaction(h.db, transaction.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to check serial movements",
+			"message": err.Error(),
+		})
+		return
+	} else if linked {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Transaction was created by a serial movement and cannot be deleted",
+		})
+		return
+	}
+
 	tx := h.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -918,7 +1187,7 @@ func (h *InventoryHandler) DeleteTransaction(c *gin.Context) {
 	}()
 
 	var item models.InventoryItem
-	itemResult := tx.Unscoped().First(&item, transaction.ItemID)
+	itemResult := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, transaction.ItemID)
 	if itemResult.Error != nil {
 		if errors.Is(itemResult.Error, gorm.ErrRecordNotFound) {
 			// Item no longer exists; delete transaction without stock rollback.
@@ -937,6 +1206,11 @@ func (h *InventoryHandler) DeleteTransaction(c *gin.Context) {
 				})
 				return
 			}
+			h.webhooks.Publish(webhook.EventTransactionDeleted, gin.H{"transaction": webhookTransactionData(transaction)})
+			h.stream.Publish(realtime.EventStockTransaction, gin.H{
+				"action":      "deleted",
+				"transaction": webhookTransactionData(transaction),
+			}, realtime.Target{WarehouseIDs: stockEventWarehouses(transaction, item)})
 			c.JSON(http.StatusOK, gin.H{
 				"message": "Transaction deleted (related inventory item already removed)",
 			})
@@ -950,481 +1224,182 @@ func (h *InventoryHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
-	switch transaction.Type {
-	case "in":
-		if item.Quantity < transaction.Quantity {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Cannot delete transaction because stock would become negative",
-			})
-			return
-		}
-		item.Quantity -= transaction.Quantity
-
-	case "out":
-		item.Quantity += transaction.Quantity
-
-	case "transfer":
-		item.Quantity += transaction.Quantity
-
-		if transaction.ToWarehouseID != nil {
-			var destItem models.InventoryItem
-			destResult := tx.Unscoped().Where("sku = ? AND warehouse_id = ?", item.SN, *transaction.ToWarehouseID).First(&destItem)
-			if destResult.Error != nil {
-				if !errors.Is(destResult.Error, gorm.ErrRecordNotFound) {
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Failed to fetch destination inventory item",
-						"message": destResult.Error.Error(),
-					})
-					return
-				}
-				// Destination item missing; continue without rollback.
-			} else {
-				if destItem.Quantity < transaction.Quantity {
-					tx.Rollback()
-					c.JSON(http.StatusBadRequest, gin.H{
-						"error": "Destination stock is lower than transferred quantity",
-					})
-					return
-				}
-
-				destItem.Quantity -= transaction.Quantity
-				if err := tx.Save(&destItem).Error; err != nil {
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Failed to update destination inventory item",
-						"message": err.Error(),
-					})
-					return
-				}
-			}
-		}
-
-	case "adjustment":
-		item.Quantity -= transaction.Quantity
-		if item.Quantity < 0 {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Deleting this adjustment would result in negative stock",
-			})
-			return
-		}
-
-	default:
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Unsupported transaction type",
-		})
-		return
-	}
-
-	if err := tx.Save(&item).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update inventory item",
-			"message": err.Error(),
-		})
-		return
-	}
+	quantityBefore := item.Quantity
 
-	if err := tx.Delete(&models.InventoryTransaction{}, transaction.ID).Error; err != nil {
+	if err := revertBinMovement(tx, &item, &transaction); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to delete transaction",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to commit delete transaction",
-			"message": err.Error(),
-		})
+		respondError(c, err, "Failed to delete transaction")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Transaction deleted successfully",
-	})
-}
-
-// GetLowStockItems godoc
-// @Summary Get items with low stock
-// @Tags Inventory
-// @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Router /inventory/low-stock [get]
-func (h *InventoryHandler) GetLowStockItems(c *gin.Context) {
-	var allItems []models.InventoryItem
-	if err := h.db.
-		Preload("Warehouse").
-		Where("is_active = ?", true).
-		Find(&allItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch low stock items",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	lowStock := notification.ComputeLowStockEntries(allItems)
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": lowStock,
-	})
-}
-
-// ImportItemsFromCSV handles bulk inventory import from CSV file
-func (h *InventoryHandler) ImportItemsFromCSV(c *gin.Context) {
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "CSV file is required",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Unable to open uploaded file",
-			"message": err.Error(),
-		})
-		return
-	}
-	defer src.Close()
-
-	reader := csv.NewReader(src)
-	reader.TrimLeadingSpace = true
-	reader.FieldsPerRecord = -1
-
-	header, err := reader.Read()
-	if err == io.EOF {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "CSV file is empty",
-		})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Failed to read CSV header",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	if len(header) > 0 {
-		header[0] = strings.TrimPrefix(header[0], "\ufeff")
-	}
-
-	headerMap := make(map[string]int, len(header))
-	warehouseCodeKey := ""
-	warehouseNameKey := ""
-	for idx, column := range header {
-		normalized := strings.ToLower(strings.TrimSpace(column))
-		switch normalized {
-		case "sn", "serial number", "serial", "sku", "kode sn":
-			headerMap["sn"] = idx
-		case "name", "item name", "nama item", "nama barang", "item":
-			headerMap["name"] = idx
-		case "category", "kategori":
-			headerMap["category"] = idx
-		case "warehouse code", "warehouse_code", "kode_gudang", "kode warehouse", "kode gudang":
-			warehouseCodeKey = normalized
-			headerMap["warehouse_code"] = idx
-		case "warehouse name", "warehouse", "nama_gudang", "nama warehouse", "gudang":
-			warehouseNameKey = normalized
-			headerMap["warehouse_name"] = idx
-		case "quantity", "qty", "stok", "stock", "kuantitas", "kuantiti":
-			headerMap["quantity"] = idx
-		case "min stock", "minimum stock", "minstok", "minimum stok", "min stok":
-			headerMap["min_stock"] = idx
-		case "max stock", "maximum stock", "maxstok", "maks stok":
-			headerMap["max_stock"] = idx
-		case "unit", "satuan":
-			headerMap["unit"] = idx
-		case "unit price", "price", "harga":
-			headerMap["unit_price"] = idx
-		case "description", "deskripsi", "keterangan":
-			headerMap["description"] = idx
-		case "status", "aktif", "status item", "status aktif":
-			headerMap["status"] = idx
-		default:
-			if normalized != "" {
-				headerMap[normalized] = idx
-			}
-		}
-	}
-
-	requiredColumns := []string{"sn", "name"}
-	for _, column := range requiredColumns {
-		if _, ok := headerMap[column]; !ok {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("Missing required column '%s' in CSV", column),
-			})
-			return
-		}
-	}
-
-	// Check if either warehouse_code or warehouse_name is present
-	if warehouseCodeKey == "" && warehouseNameKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Missing required column for warehouse (e.g. 'warehouse_code', 'Warehouse Code', 'warehouse_name', 'Warehouse Name') in CSV",
-		})
-		return
-	}
-
-	type importSummary struct {
-		Inserted int      `json:"inserted"`
-		Updated  int      `json:"updated"`
-		Errors   []string `json:"errors"`
-	}
-
-	summary := importSummary{}
-
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to start database transaction",
-			"message": tx.Error.Error(),
-		})
-		return
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		}
-	}()
-
-	lineNumber := 1
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		lineNumber++
-
-		if err != nil {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: %v", lineNumber, err))
-			continue
-		}
-
-		if isCSVRecordEmpty(record) {
-			continue
-		}
-
-		var warehouse models.Warehouse
-
-		if idx, ok := headerMap["warehouse_code"]; ok && idx < len(record) {
-			if code := strings.TrimSpace(record[idx]); code != "" {
-				if err := tx.Where("code = ?", code).First(&warehouse).Error; err != nil {
-					if errors.Is(err, gorm.ErrRecordNotFound) {
-						summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: warehouse with code '%s' not found", lineNumber, code))
-						continue
-					}
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Failed to fetch warehouse by code",
-						"message": err.Error(),
-					})
-					return
-				}
-			}
+	switch transaction.Type {
+	case "in":
+		if item.Quantity < transaction.Quantity {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Cannot delete transaction because stock would become negative",
+			})
+			return
 		}
+		if err := ensureUnreservedStock(tx, &item, transaction.Quantity); err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to delete transaction")
+			return
+		}
+		item.Quantity -= transaction.Quantity
 
-		if warehouse.ID == 0 {
-			warehouseName := csvValue(record, headerMap, "warehouse_name")
-			if warehouseName == "" {
-				summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: warehouse name is required", lineNumber))
-				continue
-			}
-
-			if err := tx.Where("name = ?", warehouseName).First(&warehouse).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: warehouse with name '%s' not found", lineNumber, warehouseName))
-					continue
-				}
+	case "out":
+		if transaction.ReservationID != nil {
+			if err := releaseReservation(tx, *transaction.ReservationID, transaction.Quantity); err != nil {
 				tx.Rollback()
 				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Failed to fetch warehouse by name",
+					"error":   "Failed to release reservation",
 					"message": err.Error(),
 				})
 				return
 			}
 		}
+		item.Quantity += transaction.Quantity
 
-		sn := csvValue(record, headerMap, "sn")
-
-		name := csvValue(record, headerMap, "name")
-		if name == "" {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: name is required", lineNumber))
-			continue
-		}
-
-		category := csvValue(record, headerMap, "category")
-		description := csvValue(record, headerMap, "description")
-		unit := csvValue(record, headerMap, "unit")
-		if unit == "" {
-			unit = "pcs"
-		}
-
-		quantity, quantityProvided, err := parseOptionalFloat(csvValue(record, headerMap, "quantity"))
-		if err != nil {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: invalid quantity value", lineNumber))
-			continue
-		}
+	case "bin_move":
+		// Bin moves never changed the item total.
 
-		minStock, minStockProvided, err := parseOptionalFloat(csvValue(record, headerMap, "min_stock"))
-		if err != nil {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: invalid min_stock value", lineNumber))
-			continue
-		}
+	case "transfer":
+		item.Quantity += transaction.Quantity
 
-		maxStock, maxStockProvided, err := parseOptionalFloat(csvValue(record, headerMap, "max_stock"))
-		if err != nil {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: invalid max_stock value", lineNumber))
-			continue
-		}
+		if transaction.ToWarehouseID != nil {
+			var destItem models.InventoryItem
+			destResult := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("sku = ? AND warehouse_id = ?", item.SN, *transaction.ToWarehouseID).First(&destItem)
+			if destResult.Error != nil {
+				if !errors.Is(destResult.Error, gorm.ErrRecordNotFound) {
+					tx.Rollback()
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"error":   "Failed to fetch destination inventory item",
+						"message": destResult.Error.Error(),
+					})
+					return
+				}
+				// Destination item missing; continue without rollback.
+			} else {
+				if destItem.Quantity < transaction.Quantity {
+					tx.Rollback()
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error": "Destination stock is lower than transferred quantity",
+					})
+					return
+				}
+				if err := ensureUnreservedStock(tx, &destItem, transaction.Quantity); err != nil {
+					tx.Rollback()
+					respondError(c, err, "Failed to delete transaction")
+					return
+				}
 
-		unitPrice, unitPriceProvided, err := parseOptionalFloat(csvValue(record, headerMap, "unit_price"))
-		if err != nil {
-			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: invalid unit_price value", lineNumber))
-			continue
-		}
+				if transaction.ToLocationID != nil {
+					if err := adjustBinStock(tx, destItem.ID, destItem.WarehouseID, *transaction.ToLocationID, -transaction.Quantity); err != nil {
+						tx.Rollback()
+						respondError(c, err, "Failed to delete transaction")
+						return
+					}
+				}
 
-		statusProvided := false
-		isActive := true
-		if _, ok := headerMap["status"]; ok {
-			statusValue := strings.ToLower(csvValue(record, headerMap, "status"))
-			if statusValue != "" {
-				statusProvided = true
-				switch statusValue {
-				case "active", "aktif", "1", "true", "ya", "yes":
-					isActive = true
-				case "inactive", "nonaktif", "tidak aktif", "0", "false", "tidak", "no":
-					isActive = false
-				default:
-					summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: invalid status value '%s' (use Active/Inactive)", lineNumber, statusValue))
-					continue
+				destItem.Quantity -= transaction.Quantity
+				if err := tx.Save(&destItem).Error; err != nil {
+					tx.Rollback()
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"error":   "Failed to update destination inventory item",
+						"message": err.Error(),
+					})
+					return
 				}
 			}
 		}
 
-		var existing models.InventoryItem
-		itemQuery := tx.Where("warehouse_id = ?", warehouse.ID)
-		if sn != "" {
-			itemQuery = itemQuery.Where("sku = ?", sn)
-		} else {
-			itemQuery = itemQuery.Where("name = ?", name)
-		}
-
-		err = itemQuery.First(&existing).Error
-
-		switch {
-		case errors.Is(err, gorm.ErrRecordNotFound):
-			newItem := models.InventoryItem{
-				WarehouseID: warehouse.ID,
-				SN:          sn,
-				Name:        name,
-				Description: description,
-				Category:    category,
-				Unit:        unit,
-				Quantity:    quantity,
-				MinStock:    minStock,
-				MaxStock:    maxStock,
-				UnitPrice:   unitPrice,
-				IsActive:    isActive,
-			}
-
-			if !quantityProvided {
-				newItem.Quantity = 0
-			}
-			if !minStockProvided {
-				newItem.MinStock = 0
-			}
-			if !maxStockProvided {
-				newItem.MaxStock = 0
-			}
-			if !unitPriceProvided {
-				newItem.UnitPrice = 0
-			}
-			if !statusProvided {
-				newItem.IsActive = quantity > 0
-			}
-
-			if err := tx.Create(&newItem).Error; err != nil {
-				summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: failed to create item (%v)", lineNumber, err))
-				continue
-			}
-			summary.Inserted++
-
-		case err != nil:
+	case "adjustment":
+		if transaction.Quantity > item.Quantity {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to check existing inventory item",
-				"message": err.Error(),
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Deleting this adjustment would result in negative stock",
 			})
 			return
-
-		default:
-			if category != "" {
-				existing.Category = category
-			}
-			if description != "" {
-				existing.Description = description
-			}
-			if unit != "" {
-				existing.Unit = unit
+		}
+		if transaction.Quantity > 0 {
+			if err := ensureUnreservedStock(tx, &item, transaction.Quantity); err != nil {
+				tx.Rollback()
+				respondError(c, err, "Failed to delete transaction")
+				return
 			}
+		}
+		item.Quantity -= transaction.Quantity
 
-			existing.Name = name
+	default:
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported transaction type",
+		})
+		return
+	}
 
-			if quantityProvided {
-				existing.Quantity = quantity
-			}
-			if minStockProvided {
-				existing.MinStock = minStock
-			}
-			if maxStockProvided {
-				existing.MaxStock = maxStock
-			}
-			if unitPriceProvided {
-				existing.UnitPrice = unitPrice
-			}
-			if statusProvided {
-				existing.IsActive = isActive
-			}
+	if err := tx.Save(&item).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update inventory item",
+			"message": err.Error(),
+		})
+		return
+	}
 
-			if err := tx.Save(&existing).Error; err != nil {
-				summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: failed to update item (%v)", lineNumber, err))
-				continue
-			}
-			summary.Updated++
-		}
+	if err := tx.Delete(&models.InventoryTransaction{}, transaction.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete transaction",
+			"message": err.Error(),
+		})
+		return
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to commit import transaction",
+			"error":   "Failed to commit delete transaction",
 			"message": err.Error(),
 		})
 		return
 	}
 
-	status := http.StatusOK
-	message := fmt.Sprintf("Import completed. Inserted: %d, Updated: %d", summary.Inserted, summary.Updated)
-	if len(summary.Errors) > 0 {
-		status = http.StatusMultiStatus
+	h.publishStockChange(webhook.EventTransactionDeleted, transaction, item, quantityBefore)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Transaction deleted successfully",
+	})
+}
+
+// GetLowStockItems godoc
+// @Summary Get items with low stock
+// @Tags Inventory
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /inventory/low-stock [get]
+func (h *InventoryHandler) GetLowStockItems(c *gin.Context) {
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var allItems []models.InventoryItem
+	if err := scope.items(h.db).
+		Preload("Warehouse").
+		Where("is_active = ?", true).
+		Find(&allItems).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch low stock items",
+			"message": err.Error(),
+		})
+		return
 	}
 
-	c.JSON(status, gin.H{
-		"message": message,
-		"summary": summary,
+	lowStock := notification.ComputeLowStockEntries(allItems)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": lowStock,
 	})
 }
 
@@ -1432,7 +1407,11 @@ func (h *InventoryHandler) ImportItemsFromCSV(c *gin.Context) {
 func (h *InventoryHandler) ExportItemsToCSV(c *gin.Context) {
 	var items []models.InventoryItem
 
-	query := applyInventoryFilters(h.db.Preload("Warehouse"), c)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.items(applyInventoryFilters(h.db.Preload("Warehouse").Preload("Conversions"), c.Request.URL.Query()))
 	if err := query.Order("name ASC").Find(&items).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch inventory items for export",
@@ -1451,6 +1430,7 @@ func (h *InventoryHandler) ExportItemsToCSV(c *gin.Context) {
 		"Warehouse",
 		"Quantity",
 		"Unit",
+		"Unit Conversions",
 		"Min Stock",
 		"Status",
 	}
@@ -1475,6 +1455,7 @@ func (h *InventoryHandler) ExportItemsToCSV(c *gin.Context) {
 			item.Warehouse.Name,
 			formatFloat(item.Quantity),
 			item.Unit,
+			formatConversions(item.Conversions),
 			formatFloat(item.MinStock),
 			status,
 		}
@@ -1508,7 +1489,11 @@ func (h *InventoryHandler) ExportItemsToCSV(c *gin.Context) {
 func (h *InventoryHandler) ExportItemsToPDF(c *gin.Context) {
 	var items []models.InventoryItem
 
-	query := applyInventoryFilters(h.db.Preload("Warehouse"), c)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.items(applyInventoryFilters(h.db.Preload("Warehouse"), c.Request.URL.Query()))
 	if err := query.Order("name ASC").Find(&items).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch inventory items for export",
@@ -1517,6 +1502,24 @@ func (h *InventoryHandler) ExportItemsToPDF(c *gin.Context) {
 		return
 	}
 
+	data, err := renderItemsPDF(items, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate PDF file",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("inventory-%s.pdf", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// renderItemsPDF lays out the inventory report; progress, when set, receives a percentage.
+func renderItemsPDF(items []models.InventoryItem, progress func(int)) ([]byte, error) {
 	pdf := gofpdf.NewCustom(&gofpdf.InitType{
 		OrientationStr: "L",
 		UnitStr:        "mm",
@@ -1553,6 +1556,9 @@ func (h *InventoryHandler) ExportItemsToPDF(c *gin.Context) {
 	usableHeight := pageHeight - bottomMargin
 
 	for idx, item := range items {
+		if progress != nil && len(items) > 0 {
+			progress((idx * 100) / len(items))
+		}
 		row := []string{
 			strconv.Itoa(idx + 1),
 			item.SN,
@@ -1627,18 +1633,9 @@ func (h *InventoryHandler) ExportItemsToPDF(c *gin.Context) {
 
 	var buffer bytes.Buffer
 	if err := pdf.Output(&buffer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate PDF file",
-			"message": err.Error(),
-		})
-		return
+		return nil, err
 	}
-
-	filename := fmt.Sprintf("inventory-%s.pdf", time.Now().Format("20060102-150405"))
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Header("Content-Type", "application/pdf")
-	c.Header("Cache-Control", "no-store")
-	c.Data(http.StatusOK, "application/pdf", buffer.Bytes())
+	return buffer.Bytes(), nil
 }
 
 // DownloadImportTemplate returns a ready-to-use CSV template for inventory imports
@@ -1700,57 +1697,64 @@ func (h *InventoryHandler) DownloadImportTemplate(c *gin.Context) {
 	c.Data(http.StatusOK, "text/csv", buffer.Bytes())
 }
 
-func applyInventoryFilters(query *gorm.DB, c *gin.Context) *gorm.DB {
+func applyInventoryFilters(query *gorm.DB, filters url.Values) *gorm.DB {
 	// Filter by warehouse
-	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+	if warehouseID := filters.Get("warehouse_id"); warehouseID != "" {
 		query = query.Where("warehouse_id = ?", warehouseID)
 	}
 
 	// Filter by category
-	if category := c.Query("category"); category != "" {
+	if category := filters.Get("category"); category != "" {
 		query = query.Where("category = ?", category)
 	}
 
 	// Search by name or SN
-	if search := c.Query("search"); search != "" {
+	if search := filters.Get("search"); search != "" {
 		query = query.Where("name ILIKE ? OR sku ILIKE ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	// Low stock filter
-	if c.Query("low_stock") == "true" {
+	if filters.Get("low_stock") == "true" {
 		query = query.Where("quantity <= min_stock")
 	}
 
 	return query
 }
 
-func applyTransactionFilters(query *gorm.DB, c *gin.Context) *gorm.DB {
-	if transType := c.Query("type"); transType != "" {
+func applyTransactionFilters(query *gorm.DB, filters url.Values) *gorm.DB {
+	if transType := filters.Get("type"); transType != "" {
 		query = query.Where("type = ?", transType)
 	}
 
-	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+	if warehouseID := filters.Get("warehouse_id"); warehouseID != "" {
 		query = query.Where("(from_warehouse_id = ? OR to_warehouse_id = ?)", warehouseID, warehouseID)
 	}
 
-	if startDate := c.Query("start_date"); startDate != "" {
+	if startDate := filters.Get("start_date"); startDate != "" {
 		query = query.Where("created_at >= ?", startDate)
 	}
 
-	if endDate := c.Query("end_date"); endDate != "" {
+	if endDate := filters.Get("end_date"); endDate != "" {
 		query = query.Where("created_at <= ?", endDate)
 	}
 
-	if search := strings.TrimSpace(c.Query("search")); search != "" {
+	if search := strings.TrimSpace(filters.Get("search")); search != "" {
 		like := "%" + search + "%"
-		query = query.Joins("LEFT JOIN inventory_items AS export_items ON export_items.id = inventory_transactions.item_id").
-			Where("export_items.name ILIKE ? OR export_items.sku ILIKE ? OR inventory_transactions.reference ILIKE ? OR COALESCE(inventory_transactions.notes, '') ILIKE ?", like, like, like, like).
-			Distinct()
+		query = query.Where("(inventory_transactions.item_id IN (SELECT id FROM inventory_items WHERE name ILIKE ? OR sku ILIKE ?) OR inventory_transactions.reference ILIKE ? OR COALESCE(inventory_transactions.notes, '') ILIKE ?)", like, like, like, like)
 	}
 
 	return query
 }
 
+// transactionEntry returns the quantity and unit a transaction was entered in,
+// falling back to the base quantity for rows recorded before unit conversions.
+func transactionEntry(t models.InventoryTransaction, baseUnit string) (float64, string) {
+	if strings.TrimSpace(t.EntryUnit) == "" {
+		return t.Quantity, baseUnit
+	}
+	return t.EntryQuantity, t.EntryUnit
+}
+
 func csvValue(record []string, header map[string]int, key string) string {
 	idx, ok := header[key]
 	if !ok || idx >= len(record) {
@@ -1790,7 +1794,11 @@ func isCSVRecordEmpty(record []string) bool {
 func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 	var transactions []models.InventoryTransaction
 
-	query := applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.transactions(applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c.Request.URL.Query()))
 
 	if err := query.
 		Preload("Item").
@@ -1807,29 +1815,6 @@ func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 		return
 	}
 
-	if roleName, ok := c.Get("role_name"); ok {
-		if name, isString := roleName.(string); isString && strings.EqualFold(name, "employee") {
-			userID, ok := h.contextUserID(c)
-			if !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
-				return
-			}
-
-			allowedIDs, err := h.getUserWarehouseIDs(userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-				return
-			}
-
-			if len(allowedIDs) == 0 {
-				transactions = []models.InventoryTransaction{}
-			} else {
-				allowedSet := buildUintSet(allowedIDs)
-				transactions = filterTransactionsByWarehouses(transactions, allowedSet)
-			}
-		}
-	}
-
 	var buffer bytes.Buffer
 	writer := csv.NewWriter(&buffer)
 
@@ -1841,6 +1826,8 @@ func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 		"Item Name",
 		"Quantity",
 		"Unit",
+		"Entry Quantity",
+		"Entry Unit",
 		"From Warehouse",
 		"To Warehouse",
 		"Reference",
@@ -1871,6 +1858,8 @@ func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 			}
 		}
 
+		entryQuantity, entryUnit := transactionEntry(t, unit)
+
 		fromWarehouse := "-"
 		if t.FromWarehouse != nil && strings.TrimSpace(t.FromWarehouse.Name) != "" {
 			fromWarehouse = t.FromWarehouse.Name
@@ -1904,6 +1893,8 @@ func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 			itemName,
 			fmt.Sprintf("%.2f", t.Quantity),
 			unit,
+			fmt.Sprintf("%.2f", entryQuantity),
+			entryUnit,
 			fromWarehouse,
 			toWarehouse,
 			reference,
@@ -1939,7 +1930,11 @@ func (h *InventoryHandler) ExportTransactionsToCSV(c *gin.Context) {
 func (h *InventoryHandler) ExportTransactionsToPDF(c *gin.Context) {
 	var transactions []models.InventoryTransaction
 
-	query := applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c)
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.transactions(applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c.Request.URL.Query()))
 
 	if err := query.
 		Preload("Item").
@@ -1955,29 +1950,23 @@ func (h *InventoryHandler) ExportTransactionsToPDF(c *gin.Context) {
 		return
 	}
 
-	if roleName, ok := c.Get("role_name"); ok {
-		if name, isString := roleName.(string); isString && strings.EqualFold(name, "employee") {
-			userID, ok := h.contextUserID(c)
-			if !ok {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
-				return
-			}
-
-			allowedIDs, err := h.getUserWarehouseIDs(userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
-				return
-			}
-
-			if len(allowedIDs) == 0 {
-				transactions = []models.InventoryTransaction{}
-			} else {
-				allowedSet := buildUintSet(allowedIDs)
-				transactions = filterTransactionsByWarehouses(transactions, allowedSet)
-			}
-		}
+	data, err := renderTransactionsPDF(transactions, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate PDF",
+			"message": err.Error(),
+		})
+		return
 	}
 
+	filename := fmt.Sprintf("inventory-transactions-%s.pdf", time.Now().Format("20060102-150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// renderTransactionsPDF lays out the transaction report; progress, when set, receives a percentage.
+func renderTransactionsPDF(transactions []models.InventoryTransaction, progress func(int)) ([]byte, error) {
 	pdf := gofpdf.New("L", "mm", "A4", "")
 	pdf.SetTitle("Inventory Transactions", false)
 	pdf.AddPage()
@@ -2023,6 +2012,9 @@ func (h *InventoryHandler) ExportTransactionsToPDF(c *gin.Context) {
 	usableHeight := pageHeight - bottomMargin
 
 	for idx, t := range transactions {
+		if progress != nil && len(transactions) > 0 {
+			progress((idx * 100) / len(transactions))
+		}
 		itemName := "-"
 		itemSN := ""
 		unit := "-"
@@ -2068,12 +2060,17 @@ func (h *InventoryHandler) ExportTransactionsToPDF(c *gin.Context) {
 			createdBy = t.CreatedBy.FullName
 		}
 
+		quantityDisplay := fmt.Sprintf("%.2f %s", t.Quantity, unit)
+		if entryQuantity, entryUnit := transactionEntry(t, unit); !strings.EqualFold(entryUnit, unit) {
+			quantityDisplay = fmt.Sprintf("%s\n(%.2f %s)", quantityDisplay, entryQuantity, entryUnit)
+		}
+
 		row := []string{
 			strconv.Itoa(idx + 1),
 			t.CreatedAt.Format("2006-01-02 15:04"),
 			strings.ToUpper(t.Type),
 			itemDisplay,
-			fmt.Sprintf("%.2f %s", t.Quantity, unit),
+			quantityDisplay,
 			fromWarehouse,
 			toWarehouse,
 			reference,
@@ -2139,15 +2136,7 @@ func (h *InventoryHandler) ExportTransactionsToPDF(c *gin.Context) {
 
 	var buffer bytes.Buffer
 	if err := pdf.Output(&buffer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate PDF",
-			"message": err.Error(),
-		})
-		return
+		return nil, err
 	}
-
-	filename := fmt.Sprintf("inventory-transactions-%s.pdf", time.Now().Format("20060102-150405"))
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Data(http.StatusOK, "application/pdf", buffer.Bytes())
+	return buffer.Bytes(), nil
 }
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const driftTolerance = 1e-6
+
+type stockAsOfLine struct {
+	ItemID        uint    `json:"item_id"`
+	SN            string  `json:"sn"`
+	Name          string  `json:"name"`
+	Unit          string  `json:"unit"`
+	WarehouseID   uint    `json:"warehouse_id"`
+	WarehouseName string  `json:"warehouse_name"`
+	Quantity      float64 `json:"quantity"`
+	Reconstructed float64 `json:"reconstructed_quantity"`
+	Stored        float64 `json:"stored_quantity"`
+	Drift         float64 `json:"drift"`
+}
+
+type stockAsOfWarehouse struct {
+	WarehouseID   uint    `json:"warehouse_id"`
+	WarehouseName string  `json:"warehouse_name"`
+	ItemCount     int     `json:"item_count"`
+	TotalQuantity float64 `json:"total_quantity"`
+	DriftCount    int     `json:"drift_count"`
+}
+
+// itemMovementSums holds the transaction effect on one item, split at the as-of instant.
+type itemMovementSums struct {
+	ItemID uint
+	AsOf   float64
+	Total  float64
+}
+
+// parseAsOf accepts RFC3339 or a plain date, which is taken as the end of that day.
+func parseAsOf(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Now(), true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.Add(24*time.Hour - time.Nanosecond), true
+	}
+	return time.Time{}, false
+}
+
+// Stock corrections are the adjustments booked for quantities set outside
+// RecordTransaction; the reference tells them apart in the transaction log.
+const (
+	stockCorrectionOpening = "Opening stock"
+	stockCorrectionEdit    = "Quantity edit"
+	stockCorrectionImport  = "Import"
+)
+
+// recordStockCorrection books delta as an adjustment on item so the transaction
+// log keeps matching the stored quantity. A zero delta books nothing.
+func recordStockCorrection(tx *gorm.DB, item *models.InventoryItem, delta float64, reference string, userID uint) error {
+	if math.Abs(delta) < driftTolerance {
+		return nil
+	}
+	return tx.Create(&models.InventoryTransaction{
+		ItemID:        item.ID,
+		Type:          "adjustment",
+		Quantity:      delta,
+		EntryUnit:     item.Unit,
+		EntryQuantity: delta,
+		Reference:     reference,
+		CreatedByID:   userID,
+	}).Error
+}
+
+// stockMovementSums rebuilds per-item stock deltas of itemIDs from the
+// transaction log. Outgoing effects are booked on the transaction item;
+// incoming transfers are booked on the item with the same SN in the
+// destination warehouse.
+func (h *InventoryHandler) stockMovementSums(asOf time.Time, itemIDs []uint) (map[uint]itemMovementSums, error) {
+	sums := make(map[uint]itemMovementSums)
+	if len(itemIDs) == 0 {
+		return sums, nil
+	}
+
+	var own []itemMovementSums
+	if err := h.db.Model(&models.InventoryTransaction{}).
+		Select(`item_id,
+			COALESCE(SUM(CASE WHEN created_at <= ? THEN CASE type
+				WHEN 'in' THEN quantity WHEN 'adjustment' THEN quantity
+				WHEN 'out' THEN -quantity WHEN 'transfer' THEN -quantity ELSE 0 END ELSE 0 END), 0) AS as_of,
+			COALESCE(SUM(CASE type
+				WHEN 'in' THEN quantity WHEN 'adjustment' THEN quantity
+				WHEN 'out' THEN -quantity WHEN 'transfer' THEN -quantity ELSE 0 END), 0) AS total`, asOf).
+		Where("item_id IN ?", itemIDs).
+		Group("item_id").
+		Scan(&own).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range own {
+		sums[row.ItemID] = row
+	}
+
+	var incoming []itemMovementSums
+	if err := h.db.Table("inventory_transactions AS t").
+		Select(`dst.id AS item_id,
+			COALESCE(SUM(CASE WHEN t.created_at <= ? THEN t.quantity ELSE 0 END), 0) AS as_of,
+			COALESCE(SUM(t.quantity), 0) AS total`, asOf).
+		Joins("JOIN inventory_items AS src ON src.id = t.item_id").
+		Joins("JOIN inventory_items AS dst ON dst.sku = src.sku AND dst.warehouse_id = t.to_warehouse_id AND dst.deleted_at IS NULL").
+		Where("t.deleted_at IS NULL AND t.type = ? AND t.to_warehouse_id IS NOT NULL AND dst.id IN ?", "transfer", itemIDs).
+		Group("dst.id").
+		Scan(&incoming).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range incoming {
+		current := sums[row.ItemID]
+		current.ItemID = row.ItemID
+		current.AsOf += row.AsOf
+		current.Total += row.Total
+		sums[row.ItemID] = current
+	}
+
+	return sums, nil
+}
+
+// GetStockAsOf godoc
+// @Summary Stock per item and warehouse at a point in time, rebuilt from transactions
+// @Tags Inventory
+// @Produce json
+// @Param date query string false "Date (YYYY-MM-DD) or RFC3339 timestamp; defaults to now"
+// @Param warehouse_id query int false "Warehouse ID"
+// @Param item_id query int false "Item ID"
+// @Param drift_only query bool false "Only items whose reconstructed quantity differs from the stored one"
+// @Success 200 {object} map[string]interface{}
+// @Router /inventory/as-of [get]
+func (h *InventoryHandler) GetStockAsOf(c *gin.Context) {
+	asOf, ok := parseAsOf(c.Query("date"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, use YYYY-MM-DD or RFC3339"})
+		return
+	}
+	driftOnly := c.Query("drift_only") == "true"
+
+	query := h.db.Preload("Warehouse")
+	if warehouseID := strings.TrimSpace(c.Query("warehouse_id")); warehouseID != "" {
+		if _, err := strconv.Atoi(warehouseID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse ID"})
+			return
+		}
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	if itemID := strings.TrimSpace(c.Query("item_id")); itemID != "" {
+		if _, err := strconv.Atoi(itemID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+			return
+		}
+		query = query.Where("id = ?", itemID)
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query = scope.items(query)
+
+	var items []models.InventoryItem
+	if err := query.Order("warehouse_id ASC, name ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch inventory items",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	sums, err := h.stockMovementSums(asOf, itemIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rebuild stock from transactions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	lines := make([]stockAsOfLine, 0, len(items))
+	summaries := make(map[uint]*stockAsOfWarehouse)
+	driftCount := 0
+	for _, item := range items {
+		movement := sums[item.ID]
+		drift := item.Quantity - movement.Total
+		if math.Abs(drift) < driftTolerance {
+			drift = 0
+		}
+
+		if driftOnly && drift == 0 {
+			continue
+		}
+		// Items created after the as-of instant are only listed when they drift.
+		if !driftOnly && item.CreatedAt.After(asOf) && movement.AsOf == 0 && drift == 0 {
+			continue
+		}
+
+		lines = append(lines, stockAsOfLine{
+			ItemID:        item.ID,
+			SN:            item.SN,
+			Name:          item.Name,
+			Unit:          item.Unit,
+			WarehouseID:   item.WarehouseID,
+			WarehouseName: item.Warehouse.Name,
+			Quantity:      movement.AsOf,
+			Reconstructed: movement.Total,
+			Stored:        item.Quantity,
+			Drift:         drift,
+		})
+
+		summary, exists := summaries[item.WarehouseID]
+		if !exists {
+			summary = &stockAsOfWarehouse{WarehouseID: item.WarehouseID, WarehouseName: item.Warehouse.Name}
+			summaries[item.WarehouseID] = summary
+		}
+		summary.ItemCount++
+		summary.TotalQuantity += movement.AsOf
+		if drift != 0 {
+			summary.DriftCount++
+			driftCount++
+		}
+	}
+
+	warehouses := make([]stockAsOfWarehouse, 0, len(summaries))
+	for _, summary := range summaries {
+		warehouses = append(warehouses, *summary)
+	}
+	sort.Slice(warehouses, func(i, j int) bool {
+		return warehouses[i].WarehouseID < warehouses[j].WarehouseID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"as_of":       asOf,
+		"data":        lines,
+		"warehouses":  warehouses,
+		"drift_count": driftCount,
+	})
+}
@@ -0,0 +1,800 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	importActionInsert = "insert"
+	importActionUpdate = "update"
+	importActionError  = "error"
+
+	importBatchTTL = time.Hour
+)
+
+// importRecord is one raw line of an import file.
+type importRecord struct {
+	Line   int
+	Fields []string
+	Err    error
+}
+
+// importRow is a parsed and validated import line. Optional numeric fields are
+// pointers so "not provided" and zero stay distinguishable.
+type importRow struct {
+	Line          int      `json:"line"`
+	WarehouseCode string   `json:"warehouse_code,omitempty"`
+	WarehouseName string   `json:"warehouse_name,omitempty"`
+	SN            string   `json:"sn"`
+	Name          string   `json:"name"`
+	Category      string   `json:"category,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Unit          string   `json:"unit"`
+	Quantity      *float64 `json:"quantity,omitempty"`
+	MinStock      *float64 `json:"min_stock,omitempty"`
+	MaxStock      *float64 `json:"max_stock,omitempty"`
+	UnitPrice     *float64 `json:"unit_price,omitempty"`
+	IsActive      *bool    `json:"is_active,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+type importFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type importPlanRow struct {
+	Line        int                 `json:"line"`
+	Action      string              `json:"action"`
+	ItemID      uint                `json:"item_id,omitempty"`
+	WarehouseID uint                `json:"warehouse_id,omitempty"`
+	Warehouse   string              `json:"warehouse,omitempty"`
+	SN          string              `json:"sn"`
+	Name        string              `json:"name"`
+	Changes     []importFieldChange `json:"changes,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+type importSummary struct {
+	Inserted int      `json:"inserted"`
+	Updated  int      `json:"updated"`
+	Errors   []string `json:"errors"`
+
+	// warehouses and lowStock feed the stream event sent after commit.
+	warehouses map[uint]struct{}
+	lowStock   []models.InventoryItem
+}
+
+// readCSVRecords returns the header and data lines of a CSV upload. Lines that
+// fail to parse are kept with their error so they show up in the result.
+func readCSVRecords(src io.Reader) ([]string, []importRecord, error) {
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	var records []importRecord
+	lineNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		lineNumber++
+		records = append(records, importRecord{Line: lineNumber, Fields: record, Err: err})
+	}
+	return header, records, nil
+}
+
+// mapImportHeader resolves the accepted column aliases to canonical keys.
+func mapImportHeader(header []string) (map[string]int, error) {
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	headerMap := make(map[string]int, len(header))
+	hasWarehouse := false
+	for idx, column := range header {
+		normalized := strings.ToLower(strings.TrimSpace(column))
+		switch normalized {
+		case "sn", "serial number", "serial", "sku", "kode sn":
+			headerMap["sn"] = idx
+		case "name", "item name", "nama item", "nama barang", "item":
+			headerMap["name"] = idx
+		case "category", "kategori":
+			headerMap["category"] = idx
+		case "warehouse code", "warehouse_code", "kode_gudang", "kode warehouse", "kode gudang":
+			hasWarehouse = true
+			headerMap["warehouse_code"] = idx
+		case "warehouse name", "warehouse", "nama_gudang", "nama warehouse", "gudang":
+			hasWarehouse = true
+			headerMap["warehouse_name"] = idx
+		case "quantity", "qty", "stok", "stock", "kuantitas", "kuantiti":
+			headerMap["quantity"] = idx
+		case "min stock", "minimum stock", "minstok", "minimum stok", "min stok":
+			headerMap["min_stock"] = idx
+		case "max stock", "maximum stock", "maxstok", "maks stok":
+			headerMap["max_stock"] = idx
+		case "unit", "satuan":
+			headerMap["unit"] = idx
+		case "unit price", "price", "harga":
+			headerMap["unit_price"] = idx
+		case "description", "deskripsi", "keterangan":
+			headerMap["description"] = idx
+		case "status", "aktif", "status item", "status aktif":
+			headerMap["status"] = idx
+		default:
+			if normalized != "" {
+				headerMap[normalized] = idx
+			}
+		}
+	}
+
+	for _, column := range []string{"sn", "name"} {
+		if _, ok := headerMap[column]; !ok {
+			return nil, fmt.Errorf("Missing required column '%s' in import file", column)
+		}
+	}
+	if !hasWarehouse {
+		return nil, errors.New("Missing required column for warehouse (e.g. 'warehouse_code', 'Warehouse Code', 'warehouse_name', 'Warehouse Name') in import file")
+	}
+	return headerMap, nil
+}
+
+// parseImportRecords validates every line without touching the database.
+func parseImportRecords(header []string, records []importRecord) ([]importRow, error) {
+	headerMap, err := mapImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, 0, len(records))
+	for _, record := range records {
+		if record.Err != nil {
+			rows = append(rows, importRow{Line: record.Line, Error: record.Err.Error()})
+			continue
+		}
+		if isCSVRecordEmpty(record.Fields) {
+			continue
+		}
+		rows = append(rows, parseImportRow(record.Line, record.Fields, headerMap))
+	}
+	return rows, nil
+}
+
+func parseImportRow(line int, record []string, headerMap map[string]int) importRow {
+	row := importRow{
+		Line:          line,
+		WarehouseCode: csvValue(record, headerMap, "warehouse_code"),
+		WarehouseName: csvValue(record, headerMap, "warehouse_name"),
+		SN:            csvValue(record, headerMap, "sn"),
+		Name:          csvValue(record, headerMap, "name"),
+		Category:      csvValue(record, headerMap, "category"),
+		Description:   csvValue(record, headerMap, "description"),
+		Unit:          csvValue(record, headerMap, "unit"),
+	}
+	if row.Unit == "" {
+		row.Unit = "pcs"
+	}
+
+	if row.WarehouseCode == "" && row.WarehouseName == "" {
+		row.Error = "warehouse name is required"
+		return row
+	}
+	if row.Name == "" {
+		row.Error = "name is required"
+		return row
+	}
+
+	numbers := []struct {
+		key    string
+		target **float64
+	}{
+		{"quantity", &row.Quantity},
+		{"min_stock", &row.MinStock},
+		{"max_stock", &row.MaxStock},
+		{"unit_price", &row.UnitPrice},
+	}
+	for _, number := range numbers {
+		value, provided, err := parseOptionalFloat(csvValue(record, headerMap, number.key))
+		if err != nil {
+			row.Error = fmt.Sprintf("invalid %s value", number.key)
+			return row
+		}
+		if provided {
+			v := value
+			*number.target = &v
+		}
+	}
+
+	if _, ok := headerMap["status"]; ok {
+		statusValue := strings.ToLower(csvValue(record, headerMap, "status"))
+		if statusValue != "" {
+			var active bool
+			switch statusValue {
+			case "active", "aktif", "1", "true", "ya", "yes":
+				active = true
+			case "inactive", "nonaktif", "tidak aktif", "0", "false", "tidak", "no":
+				active = false
+			default:
+				row.Error = fmt.Sprintf("invalid status value '%s' (use Active/Inactive)", statusValue)
+				return row
+			}
+			row.IsActive = &active
+		}
+	}
+
+	return row
+}
+
+// resolveImportWarehouse looks a row's warehouse up by code first, then by name.
+// Warehouses outside scope are reported like missing ones.
+func resolveImportWarehouse(db *gorm.DB, row importRow, cache map[string]*models.Warehouse, scope warehouseScope) (*models.Warehouse, string, error) {
+	key := "code:" + row.WarehouseCode
+	if row.WarehouseCode == "" {
+		key = "name:" + row.WarehouseName
+	}
+	if warehouse, ok := cache[key]; ok {
+		if warehouse == nil {
+			return nil, importWarehouseMissing(row), nil
+		}
+		if !scope.allows(warehouse.ID) {
+			return nil, importWarehouseForbidden(warehouse), nil
+		}
+		return warehouse, "", nil
+	}
+
+	var warehouse models.Warehouse
+	query := db.Where("code = ?", row.WarehouseCode)
+	if row.WarehouseCode == "" {
+		query = db.Where("name = ?", row.WarehouseName)
+	}
+	if err := query.First(&warehouse).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cache[key] = nil
+			return nil, importWarehouseMissing(row), nil
+		}
+		return nil, "", err
+	}
+	cache[key] = &warehouse
+	if !scope.allows(warehouse.ID) {
+		return nil, importWarehouseForbidden(&warehouse), nil
+	}
+	return &warehouse, "", nil
+}
+
+func importWarehouseForbidden(warehouse *models.Warehouse) string {
+	return fmt.Sprintf("warehouse '%s' is not assigned to you", warehouse.Code)
+}
+
+func importWarehouseMissing(row importRow) string {
+	if row.WarehouseCode != "" {
+		return fmt.Sprintf("warehouse with code '%s' not found", row.WarehouseCode)
+	}
+	return fmt.Sprintf("warehouse with name '%s' not found", row.WarehouseName)
+}
+
+func findImportTarget(db *gorm.DB, warehouseID uint, row importRow) (*models.InventoryItem, error) {
+	var existing models.InventoryItem
+	query := db.Where("warehouse_id = ?", warehouseID)
+	if row.SN != "" {
+		query = query.Where("sku = ?", row.SN)
+	} else {
+		query = query.Where("name = ?", row.Name)
+	}
+	if err := query.First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// ensureImportKeepsReservations rejects a row that lowers an item's quantity
+// below what its reservations hold.
+func ensureImportKeepsReservations(db *gorm.DB, item *models.InventoryItem, row importRow) error {
+	if row.Quantity == nil || *row.Quantity >= item.Quantity {
+		return nil
+	}
+	return ensureUnreservedStock(db, item, item.Quantity-*row.Quantity)
+}
+
+// newItemFromImport builds the item a row inserts.
+func newItemFromImport(warehouseID uint, row importRow) models.InventoryItem {
+	item := models.InventoryItem{
+		WarehouseID: warehouseID,
+		SN:          row.SN,
+		Name:        row.Name,
+		Description: row.Description,
+		Category:    row.Category,
+		Unit:        row.Unit,
+	}
+	if row.Quantity != nil {
+		item.Quantity = *row.Quantity
+	}
+	if row.MinStock != nil {
+		item.MinStock = *row.MinStock
+	}
+	if row.MaxStock != nil {
+		item.MaxStock = *row.MaxStock
+	}
+	if row.UnitPrice != nil {
+		item.UnitPrice = *row.UnitPrice
+	}
+	if row.IsActive != nil {
+		item.IsActive = *row.IsActive
+	} else {
+		item.IsActive = item.Quantity > 0
+	}
+	return item
+}
+
+// mergeImportRow applies a row onto an existing item and returns the field changes.
+func mergeImportRow(item *models.InventoryItem, row importRow) []importFieldChange {
+	var changes []importFieldChange
+	setString := func(field string, target *string, value string, onlyIfSet bool) {
+		if onlyIfSet && value == "" {
+			return
+		}
+		if *target != value {
+			changes = append(changes, importFieldChange{Field: field, From: *target, To: value})
+			*target = value
+		}
+	}
+	setFloat := func(field string, target *float64, value *float64) {
+		if value == nil || *target == *value {
+			return
+		}
+		changes = append(changes, importFieldChange{Field: field, From: *target, To: *value})
+		*target = *value
+	}
+
+	setString("category", &item.Category, row.Category, true)
+	setString("description", &item.Description, row.Description, true)
+	setString("unit", &item.Unit, row.Unit, true)
+	setString("name", &item.Name, row.Name, false)
+	setFloat("quantity", &item.Quantity, row.Quantity)
+	setFloat("min_stock", &item.MinStock, row.MinStock)
+	setFloat("max_stock", &item.MaxStock, row.MaxStock)
+	setFloat("unit_price", &item.UnitPrice, row.UnitPrice)
+	if row.IsActive != nil && item.IsActive != *row.IsActive {
+		changes = append(changes, importFieldChange{Field: "is_active", From: item.IsActive, To: *row.IsActive})
+		item.IsActive = *row.IsActive
+	}
+	return changes
+}
+
+// planImportRows resolves warehouses and existing items and reports what each row
+// would do. Rows repeating an earlier key are planned against that earlier row.
+func planImportRows(db *gorm.DB, rows []importRow, scope warehouseScope) ([]importPlanRow, error) {
+	plans := make([]importPlanRow, 0, len(rows))
+	warehouses := make(map[string]*models.Warehouse)
+	pending := make(map[string]*models.InventoryItem)
+
+	for _, row := range rows {
+		plan := importPlanRow{Line: row.Line, SN: row.SN, Name: row.Name}
+		if row.Error != "" {
+			plan.Action = importActionError
+			plan.Error = row.Error
+			plans = append(plans, plan)
+			continue
+		}
+
+		warehouse, missing, err := resolveImportWarehouse(db, row, warehouses, scope)
+		if err != nil {
+			return nil, err
+		}
+		if warehouse == nil {
+			plan.Action = importActionError
+			plan.Error = missing
+			plans = append(plans, plan)
+			continue
+		}
+		plan.WarehouseID = warehouse.ID
+		plan.Warehouse = warehouse.Name
+
+		key := fmt.Sprintf("%d|%s|%s", warehouse.ID, row.SN, row.Name)
+		if row.SN != "" {
+			key = fmt.Sprintf("%d|%s", warehouse.ID, row.SN)
+		}
+
+		target := pending[key]
+		if target == nil {
+			if target, err = findImportTarget(db, warehouse.ID, row); err != nil {
+				return nil, err
+			}
+		}
+
+		if target == nil {
+			item := newItemFromImport(warehouse.ID, row)
+			pending[key] = &item
+			plan.Action = importActionInsert
+			plans = append(plans, plan)
+			continue
+		}
+
+		if target.IsSerialized && row.Quantity != nil && *row.Quantity != target.Quantity {
+			plan.Action = importActionError
+			plan.ItemID = target.ID
+			plan.Error = "quantity of a serialised item follows its serial numbers"
+			plans = append(plans, plan)
+			continue
+		}
+		if target.ID != 0 {
+			if err := ensureImportKeepsReservations(db, target, row); err != nil {
+				var validationErr *validationError
+				if !errors.As(err, &validationErr) {
+					return nil, err
+				}
+				plan.Action = importActionError
+				plan.ItemID = target.ID
+				plan.Error = err.Error()
+				plans = append(plans, plan)
+				continue
+			}
+		}
+
+		copyItem := *target
+		plan.Action = importActionUpdate
+		plan.ItemID = target.ID
+		plan.Changes = mergeImportRow(&copyItem, row)
+		pending[key] = &copyItem
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// applyImportRows writes the rows inside tx, reporting row problems in the summary.
+// Rows for warehouses outside scope are rejected. Quantity changes are booked as
+// adjustments by userID. progress, when set, receives the percentage of rows
+// processed.
+func applyImportRows(tx *gorm.DB, rows []importRow, scope warehouseScope, userID uint, progress func(int)) (importSummary, error) {
+	summary := importSummary{warehouses: map[uint]struct{}{}}
+	warehouses := make(map[string]*models.Warehouse)
+
+	for idx, row := range rows {
+		if progress != nil {
+			progress((idx * 100) / len(rows))
+		}
+		if row.Error != "" {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: %s", row.Line, row.Error))
+			continue
+		}
+
+		warehouse, missing, err := resolveImportWarehouse(tx, row, warehouses, scope)
+		if err != nil {
+			return summary, err
+		}
+		if warehouse == nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: %s", row.Line, missing))
+			continue
+		}
+
+		// Locked so reservations cannot be taken while the row lowers the quantity.
+		existing, err := findImportTarget(tx.Clauses(clause.Locking{Strength: "UPDATE"}), warehouse.ID, row)
+		if err != nil {
+			return summary, err
+		}
+
+		if existing == nil {
+			newItem := newItemFromImport(warehouse.ID, row)
+			if err := tx.Create(&newItem).Error; err != nil {
+				summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: failed to create item (%v)", row.Line, err))
+				continue
+			}
+			if err := recordStockCorrection(tx, &newItem, newItem.Quantity, stockCorrectionImport, userID); err != nil {
+				return summary, err
+			}
+			summary.Inserted++
+			summary.warehouses[warehouse.ID] = struct{}{}
+			continue
+		}
+
+		if existing.IsSerialized && row.Quantity != nil && *row.Quantity != existing.Quantity {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: quantity of a serialised item follows its serial numbers", row.Line))
+			continue
+		}
+		if err := ensureImportKeepsReservations(tx, existing, row); err != nil {
+			var validationErr *validationError
+			if !errors.As(err, &validationErr) {
+				return summary, err
+			}
+			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: %s", row.Line, err.Error()))
+			continue
+		}
+
+		quantityBefore := existing.Quantity
+		mergeImportRow(existing, row)
+		if err := tx.Save(existing).Error; err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: failed to update item (%v)", row.Line, err))
+			continue
+		}
+		if err := recordStockCorrection(tx, existing, existing.Quantity-quantityBefore, stockCorrectionImport, userID); err != nil {
+			return summary, err
+		}
+		summary.Updated++
+		summary.warehouses[warehouse.ID] = struct{}{}
+		if lowStockCrossed(quantityBefore, existing.Quantity, existing.MinStock) {
+			summary.lowStock = append(summary.lowStock, *existing)
+		}
+	}
+	return summary, nil
+}
+
+// parseUploadedImport reads an uploaded CSV or XLSX file into validated rows.
+func parseUploadedImport(file *multipart.FileHeader) ([]importRow, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	readRecords := readCSVRecords
+	if isXLSXFilename(file.Filename) {
+		readRecords = readXLSXRecords
+	}
+
+	header, records, err := readRecords(src)
+	if err != nil {
+		return nil, err
+	}
+	return parseImportRecords(header, records)
+}
+
+// respondImportSummary writes the import result; row errors yield 207 Multi-Status.
+func respondImportSummary(c *gin.Context, summary importSummary, extra gin.H) {
+	status := http.StatusOK
+	if len(summary.Errors) > 0 {
+		status = http.StatusMultiStatus
+	}
+	body := gin.H{
+		"message": fmt.Sprintf("Import completed. Inserted: %d, Updated: %d", summary.Inserted, summary.Updated),
+		"summary": summary,
+	}
+	for key, value := range extra {
+		body[key] = value
+	}
+	c.JSON(status, body)
+}
+
+func (h *InventoryHandler) runImport(rows []importRow, scope warehouseScope, userID uint) (importSummary, error) {
+	var summary importSummary
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = applyImportRows(tx, rows, scope, userID, nil)
+		return err
+	})
+	if err == nil {
+		streamImport(h.stream, summary)
+	}
+	return summary, err
+}
+
+// ImportItemsFromCSV handles bulk inventory import from CSV file
+func (h *InventoryHandler) ImportItemsFromCSV(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "CSV file is required",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rows, err := parseUploadedImport(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.runImport(rows, scope, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import inventory items",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondImportSummary(c, summary, nil)
+}
+
+func newImportToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// PreviewImport validates an import file without writing items and stores the
+// parsed batch so it can be committed later by token.
+func (h *InventoryHandler) PreviewImport(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "CSV or XLSX file is required",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rows, err := parseUploadedImport(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondImportPreview(c, file.Filename, rows)
+}
+
+func (h *InventoryHandler) respondImportPreview(c *gin.Context, filename string, rows []importRow) {
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	plans, err := planImportRows(h.db, rows, scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to plan import",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	counts := map[string]int{importActionInsert: 0, importActionUpdate: 0, importActionError: 0}
+	for _, plan := range plans {
+		counts[plan.Action]++
+	}
+
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode import rows"})
+		return
+	}
+	token, err := newImportToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate import token"})
+		return
+	}
+
+	batch := models.ImportBatch{
+		Token:       token,
+		Filename:    filename,
+		Status:      "pending",
+		Rows:        string(encoded),
+		Summary:     "{}",
+		ExpiresAt:   time.Now().Add(importBatchTTL),
+		CreatedByID: userID,
+	}
+	if err := h.db.Create(&batch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to store import preview",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      batch.Token,
+		"expires_at": batch.ExpiresAt,
+		"counts":     counts,
+		"rows":       plans,
+	})
+}
+
+// CommitImport applies a previewed batch. Rows are re-resolved against the current
+// data, so items changed since the preview are updated from their latest state.
+func (h *InventoryHandler) CommitImport(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var summary importSummary
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var batch models.ImportBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ? AND created_by_id = ?", strings.TrimSpace(req.Token), userID).
+			First(&batch).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Import preview not found")
+			}
+			return err
+		}
+		if batch.Status != "pending" {
+			return newValidationError("Import preview was already committed")
+		}
+		if time.Now().After(batch.ExpiresAt) {
+			return newValidationError("Import preview has expired; upload the file again")
+		}
+
+		var rows []importRow
+		if err := json.Unmarshal([]byte(batch.Rows), &rows); err != nil {
+			return err
+		}
+
+		var err error
+		if summary, err = applyImportRows(tx, rows, scope, userID, nil); err != nil {
+			return err
+		}
+
+		encoded, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(&batch).Updates(map[string]interface{}{
+			"status":       "committed",
+			"summary":      string(encoded),
+			"committed_at": now,
+		}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to commit import")
+		return
+	}
+	streamImport(h.stream, summary)
+
+	respondImportSummary(c, summary, gin.H{"token": req.Token})
+}
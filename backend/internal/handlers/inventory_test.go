@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestItemsWithBinStockAreNotDeleted checks that deleting an item, alone or in a
+// batch, is refused while any of its stock still sits in a bin.
+func TestItemsWithBinStockAreNotDeleted(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	warehouse := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	mustCreate(t, db, &warehouse)
+	bin := models.WarehouseLocation{WarehouseID: warehouse.ID, Type: "bin", Code: "A-01", Path: "A-01", IsActive: true}
+	mustCreate(t, db, &bin)
+
+	binned := models.InventoryItem{WarehouseID: warehouse.ID, SN: "BINNED", Name: "Binned item", Unit: "pcs", Quantity: 5, IsActive: true}
+	loose := models.InventoryItem{WarehouseID: warehouse.ID, SN: "LOOSE", Name: "Loose item", Unit: "pcs", Quantity: 5, IsActive: true}
+	mustCreate(t, db, &binned, &loose)
+	mustCreate(t, db, &models.InventoryBinStock{ItemID: binned.ID, LocationID: bin.ID, Quantity: 3})
+
+	inventory := NewInventoryHandler(db, nil, nil, nil)
+	router := gin.New()
+	api := router.Group("", asUser(user))
+	api.DELETE("/inventory/items/:id", inventory.DeleteItem)
+	api.DELETE("/inventory/items", inventory.DeleteItemsBatch)
+
+	if resp := serve(router, http.MethodDelete, fmt.Sprintf("/inventory/items/%d", binned.ID), nil); resp.Code != http.StatusBadRequest {
+		t.Fatalf("deleting a binned item: expected 400, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := serve(router, http.MethodDelete, "/inventory/items", gin.H{"ids": []uint{loose.ID, binned.ID}}); resp.Code != http.StatusBadRequest {
+		t.Fatalf("batch delete with a binned item: expected 400, got %d: %s", resp.Code, resp.Body)
+	}
+	var remaining int64
+	db.Model(&models.InventoryItem{}).Where("id IN ?", []uint{binned.ID, loose.ID}).Count(&remaining)
+	if remaining != 2 {
+		t.Fatalf("expected both items to survive, %d left", remaining)
+	}
+
+	// Once the bin is emptied the item can go.
+	db.Model(&models.InventoryBinStock{}).Where("item_id = ?", binned.ID).Update("quantity", 0)
+	if resp := serve(router, http.MethodDelete, "/inventory/items", gin.H{"ids": []uint{loose.ID, binned.ID}}); resp.Code != http.StatusOK {
+		t.Fatalf("batch delete after emptying the bin: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+// TestQuantityChangesOutsideTransactionsDoNotDrift checks that opening stock,
+// quantity edits and imports are booked in the transaction log, so the as-of
+// report rebuilds the stored quantity without drift.
+func TestQuantityChangesOutsideTransactionsDoNotDrift(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	warehouse := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	mustCreate(t, db, &warehouse)
+
+	inventory := NewInventoryHandler(db, nil, nil, nil)
+	router := gin.New()
+	api := router.Group("", asUser(user))
+	api.POST("/inventory", inventory.CreateItem)
+	api.PUT("/inventory/items/:id", inventory.UpdateItem)
+	api.GET("/inventory/as-of", inventory.GetStockAsOf)
+
+	resp := serve(router, http.MethodPost, "/inventory", gin.H{"warehouse_id": warehouse.ID, "sn": "EDITED", "name": "Edited item", "unit": "pcs", "quantity": 5})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create item: expected 201, got %d: %s", resp.Code, resp.Body)
+	}
+	var created struct {
+		Data models.InventoryItem `json:"data"`
+	}
+	resp.decode(t, &created)
+	if resp := serve(router, http.MethodPut, fmt.Sprintf("/inventory/items/%d", created.Data.ID), gin.H{"quantity": 8}); resp.Code != http.StatusOK {
+		t.Fatalf("edit quantity: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+
+	three, four := 3.0, 4.0
+	rows := []importRow{
+		{Line: 2, WarehouseCode: "MAIN", SN: "EDITED", Name: "Edited item", Unit: "pcs", Quantity: &three},
+		{Line: 3, WarehouseCode: "MAIN", SN: "IMPORTED", Name: "Imported item", Unit: "pcs", Quantity: &four},
+	}
+	summary, err := applyImportRows(db, rows, warehouseScope{}, user.ID, nil)
+	if err != nil || len(summary.Errors) > 0 {
+		t.Fatalf("import: %v %v", err, summary.Errors)
+	}
+
+	var bookings int64
+	db.Model(&models.InventoryTransaction{}).Where("type = ?", "adjustment").Count(&bookings)
+	if bookings != 4 {
+		t.Fatalf("expected 4 adjustments (opening, edit, two imports), got %d", bookings)
+	}
+
+	resp = serve(router, http.MethodGet, "/inventory/as-of?drift_only=true", nil)
+	var report struct {
+		DriftCount int `json:"drift_count"`
+	}
+	resp.decode(t, &report)
+	if resp.Code != http.StatusOK || report.DriftCount != 0 {
+		t.Fatalf("expected no drift, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+// TestBinnedStockLimitsItemEdits checks that an item holding stock in bins can
+// neither move to another warehouse nor drop below its binned quantity.
+func TestBinnedStockLimitsItemEdits(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	main := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	backup := models.Warehouse{Code: "BACKUP", Name: "Backup", IsActive: true}
+	mustCreate(t, db, &main, &backup)
+	bin := models.WarehouseLocation{WarehouseID: main.ID, Type: "bin", Code: "A-01", Path: "A-01", IsActive: true}
+	mustCreate(t, db, &bin)
+	item := models.InventoryItem{WarehouseID: main.ID, SN: "BINNED", Name: "Binned item", Unit: "pcs", Quantity: 10, IsActive: true}
+	mustCreate(t, db, &item)
+	mustCreate(t, db, &models.InventoryBinStock{ItemID: item.ID, LocationID: bin.ID, Quantity: 6})
+
+	router := gin.New()
+	router.PUT("/inventory/items/:id", asUser(user), NewInventoryHandler(db, nil, nil, nil).UpdateItem)
+	path := fmt.Sprintf("/inventory/items/%d", item.ID)
+
+	for _, body := range []gin.H{{"warehouse_id": backup.ID}, {"quantity": 5}} {
+		if resp := serve(router, http.MethodPut, path, body); resp.Code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d: %s", body, resp.Code, resp.Body)
+		}
+	}
+	var reloaded models.InventoryItem
+	db.First(&reloaded, item.ID)
+	if reloaded.WarehouseID != main.ID || reloaded.Quantity != 10 {
+		t.Fatalf("item changed to warehouse %d, quantity %v", reloaded.WarehouseID, reloaded.Quantity)
+	}
+
+	// Unbinned stock can still be removed.
+	if resp := serve(router, http.MethodPut, path, gin.H{"quantity": 6}); resp.Code != http.StatusOK {
+		t.Fatalf("quantity down to the binned total: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+}
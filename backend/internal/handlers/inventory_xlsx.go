@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxContentType   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	xlsxTemplateRows  = 1000
+	xlsxListsSheet    = "Lists"
+	xlsxItemsSheet    = "Items"
+	xlsxDateTimeFmt   = "yyyy-mm-dd hh:mm"
+	xlsxQuantityFmt   = "#,##0.##"
+	xlsxCurrencyFmt   = "#,##0"
+	xlsxTextNumFmtID  = 49 // "@", keeps leading zeros in SNs
+	xlsxMaxColumnName = 40
+)
+
+// readXLSXRecords returns the header and data lines of the first worksheet (or
+// the "Items" sheet when present). Cells are read raw so numbers are not
+// re-formatted with the workbook locale.
+func readXLSXRecords(src io.Reader) ([]string, []importRecord, error) {
+	workbook, err := excelize.OpenReader(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read XLSX file: %w", err)
+	}
+	defer workbook.Close()
+
+	sheet := workbook.GetSheetName(0)
+	if index, err := workbook.GetSheetIndex(xlsxItemsSheet); err == nil && index >= 0 {
+		sheet = xlsxItemsSheet
+	}
+
+	rows, err := workbook.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read XLSX sheet: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("XLSX file is empty")
+	}
+
+	records := make([]importRecord, 0, len(rows)-1)
+	for idx, row := range rows[1:] {
+		records = append(records, importRecord{Line: idx + 2, Fields: row})
+	}
+	return rows[0], records, nil
+}
+
+func isXLSXFilename(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(filename)), ".xlsx")
+}
+
+// writeXLSX streams a workbook as a download.
+func writeXLSX(c *gin.Context, workbook *excelize.File, filename string) {
+	var buffer bytes.Buffer
+	if err := workbook.Write(&buffer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate XLSX file",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, xlsxContentType, buffer.Bytes())
+}
+
+// xlsxStyles holds the styles shared by the inventory workbooks.
+type xlsxStyles struct {
+	header   int
+	text     int
+	quantity int
+	currency int
+	dateTime int
+}
+
+func newXLSXStyles(workbook *excelize.File) (xlsxStyles, error) {
+	var styles xlsxStyles
+	var err error
+	if styles.header, err = workbook.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"4F46E5"}, Pattern: 1},
+	}); err != nil {
+		return styles, err
+	}
+	if styles.text, err = workbook.NewStyle(&excelize.Style{NumFmt: xlsxTextNumFmtID}); err != nil {
+		return styles, err
+	}
+	quantityFmt := xlsxQuantityFmt
+	if styles.quantity, err = workbook.NewStyle(&excelize.Style{CustomNumFmt: &quantityFmt}); err != nil {
+		return styles, err
+	}
+	currencyFmt := xlsxCurrencyFmt
+	if styles.currency, err = workbook.NewStyle(&excelize.Style{CustomNumFmt: &currencyFmt}); err != nil {
+		return styles, err
+	}
+	dateFmt := xlsxDateTimeFmt
+	if styles.dateTime, err = workbook.NewStyle(&excelize.Style{CustomNumFmt: &dateFmt}); err != nil {
+		return styles, err
+	}
+	return styles, nil
+}
+
+// writeXLSXSheet writes a header row plus typed rows and applies column styles.
+// columnStyles maps a zero-based column to a style ID.
+func writeXLSXSheet(workbook *excelize.File, sheet string, headers []string, rows [][]interface{}, styles xlsxStyles, columnStyles map[int]int) error {
+	if index, _ := workbook.GetSheetIndex(sheet); index < 0 {
+		if _, err := workbook.NewSheet(sheet); err != nil {
+			return err
+		}
+	}
+
+	headerRow := make([]interface{}, len(headers))
+	for i, header := range headers {
+		headerRow[i] = header
+	}
+	if err := workbook.SetSheetRow(sheet, "A1", &headerRow); err != nil {
+		return err
+	}
+	lastHeader, _ := excelize.CoordinatesToCellName(len(headers), 1)
+	if err := workbook.SetCellStyle(sheet, "A1", lastHeader, styles.header); err != nil {
+		return err
+	}
+
+	for idx, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, idx+2)
+		values := row
+		if err := workbook.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	lastRow := len(rows) + 1
+	if lastRow < 2 {
+		lastRow = 2
+	}
+	for column, style := range columnStyles {
+		start, _ := excelize.CoordinatesToCellName(column+1, 2)
+		end, _ := excelize.CoordinatesToCellName(column+1, lastRow)
+		if err := workbook.SetCellStyle(sheet, start, end, style); err != nil {
+			return err
+		}
+	}
+
+	for i, header := range headers {
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		width := float64(len(header) + 4)
+		for _, row := range rows {
+			if i < len(row) {
+				if value, ok := row[i].(string); ok && float64(len(value)+2) > width {
+					width = float64(len(value) + 2)
+				}
+			}
+		}
+		if width > xlsxMaxColumnName {
+			width = xlsxMaxColumnName
+		}
+		if err := workbook.SetColWidth(sheet, name, name, width); err != nil {
+			return err
+		}
+	}
+
+	return workbook.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// ImportItemsFromXLSX handles bulk inventory import from an XLSX workbook.
+func (h *InventoryHandler) ImportItemsFromXLSX(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "XLSX file is required",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !isXLSXFilename(file.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must have the .xlsx extension"})
+		return
+	}
+
+	rows, err := parseUploadedImport(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.contextUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.runImport(rows, scope, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import inventory items",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondImportSummary(c, summary, nil)
+}
+
+// DownloadImportTemplateXLSX builds an import workbook with dropdowns for
+// warehouse codes, categories, units and status backed by a hidden lists sheet.
+func (h *InventoryHandler) DownloadImportTemplateXLSX(c *gin.Context) {
+	var warehouses []models.Warehouse
+	if err := h.db.Where("is_active = ?", true).Order("code ASC").Find(&warehouses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch warehouses",
+			"message": err.Error(),
+		})
+		return
+	}
+	var categories []models.Category
+	if err := h.db.Where("is_active = ?", true).Order("name ASC").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch categories",
+			"message": err.Error(),
+		})
+		return
+	}
+	var units []models.UnitOfMeasure
+	if err := h.db.Where("is_active = ?", true).Order("code ASC").Find(&units).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch units",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	styles, err := newXLSXStyles(workbook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare template styles"})
+		return
+	}
+
+	if err := workbook.SetSheetName(workbook.GetSheetName(0), xlsxItemsSheet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare template"})
+		return
+	}
+
+	headers := []string{"SN", "Item Name", "Category", "Warehouse Code", "Quantity", "Unit", "Min Stock", "Max Stock", "Unit Price", "Description", "Status"}
+	sampleWarehouse := "WH-001"
+	if len(warehouses) > 0 {
+		sampleWarehouse = warehouses[0].Code
+	}
+	sampleCategory := "Laptop"
+	if len(categories) > 0 {
+		sampleCategory = categories[0].Name
+	}
+	sample := [][]interface{}{{"SN-0001", "Laptop Dell XPS 13", sampleCategory, sampleWarehouse, 10, "unit", 2, 20, 15000000, "", "Active"}}
+
+	if err := writeXLSXSheet(workbook, xlsxItemsSheet, headers, sample, styles, map[int]int{
+		0: styles.text,
+		4: styles.quantity,
+		6: styles.quantity,
+		7: styles.quantity,
+		8: styles.currency,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write template sheet", "message": err.Error()})
+		return
+	}
+	// Text format for the whole SN column so typed SNs keep leading zeros.
+	if err := workbook.SetCellStyle(xlsxItemsSheet, "A2", fmt.Sprintf("A%d", xlsxTemplateRows+1), styles.text); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to format template"})
+		return
+	}
+
+	lists := map[string][]string{
+		"A": make([]string, 0, len(warehouses)),
+		"B": make([]string, 0, len(categories)),
+		"C": make([]string, 0, len(units)),
+		"D": {"Active", "Inactive"},
+	}
+	for _, warehouse := range warehouses {
+		lists["A"] = append(lists["A"], warehouse.Code)
+	}
+	for _, category := range categories {
+		lists["B"] = append(lists["B"], category.Name)
+	}
+	for _, unit := range units {
+		lists["C"] = append(lists["C"], unit.Code)
+	}
+
+	if _, err := workbook.NewSheet(xlsxListsSheet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare template lists"})
+		return
+	}
+	listHeaders := map[string]string{"A": "Warehouse Code", "B": "Category", "C": "Unit", "D": "Status"}
+	for column, values := range lists {
+		workbook.SetCellValue(xlsxListsSheet, column+"1", listHeaders[column])
+		for idx, value := range values {
+			workbook.SetCellValue(xlsxListsSheet, fmt.Sprintf("%s%d", column, idx+2), value)
+		}
+	}
+
+	// Target column on the Items sheet for each list column.
+	targets := map[string]string{"A": "D", "B": "C", "C": "F", "D": "K"}
+	for listColumn, target := range targets {
+		count := len(lists[listColumn])
+		if count == 0 {
+			continue
+		}
+		validation := excelize.NewDataValidation(true)
+		validation.Sqref = fmt.Sprintf("%s2:%s%d", target, target, xlsxTemplateRows+1)
+		validation.SetSqrefDropList(fmt.Sprintf("%s!$%s$2:$%s$%d", xlsxListsSheet, listColumn, listColumn, count+1))
+		// Units and categories may still be typed freely; warehouses and status must match.
+		if listColumn == "B" || listColumn == "C" {
+			validation.ShowErrorMessage = false
+		}
+		if err := workbook.AddDataValidation(xlsxItemsSheet, validation); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add template dropdowns", "message": err.Error()})
+			return
+		}
+	}
+
+	if err := workbook.SetSheetVisible(xlsxListsSheet, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide template lists"})
+		return
+	}
+	workbook.SetActiveSheet(0)
+
+	writeXLSX(c, workbook, "inventory-import-template.xlsx")
+}
+
+// ExportItemsToXLSX exports inventory with an items sheet, a per-warehouse
+// summary sheet and a unit conversion sheet.
+func (h *InventoryHandler) ExportItemsToXLSX(c *gin.Context) {
+	var items []models.InventoryItem
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.items(applyInventoryFilters(h.db.Preload("Warehouse").Preload("Conversions"), c.Request.URL.Query()))
+	if err := query.Order("name ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch inventory items for export",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	styles, err := newXLSXStyles(workbook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare export styles"})
+		return
+	}
+	workbook.SetSheetName(workbook.GetSheetName(0), xlsxItemsSheet)
+
+	type warehouseSummary struct {
+		code, name string
+		items      int
+		quantity   float64
+		value      float64
+		lowStock   int
+	}
+	summaries := make(map[uint]*warehouseSummary)
+
+	itemRows := make([][]interface{}, 0, len(items))
+	var conversionRows [][]interface{}
+	for _, item := range items {
+		status := "Inactive"
+		if item.IsActive {
+			status = "Active"
+		}
+		itemRows = append(itemRows, []interface{}{
+			item.SN,
+			item.Name,
+			item.Category,
+			item.Warehouse.Code,
+			item.Warehouse.Name,
+			item.Quantity,
+			item.Unit,
+			item.MinStock,
+			item.MaxStock,
+			item.UnitPrice,
+			item.Quantity * item.UnitPrice,
+			item.Description,
+			status,
+			item.UpdatedAt,
+		})
+
+		for _, conversion := range item.Conversions {
+			conversionRows = append(conversionRows, []interface{}{
+				item.SN, item.Name, item.Warehouse.Code, conversion.UnitCode, conversion.Factor, item.Unit,
+			})
+		}
+
+		summary, ok := summaries[item.WarehouseID]
+		if !ok {
+			summary = &warehouseSummary{code: item.Warehouse.Code, name: item.Warehouse.Name}
+			summaries[item.WarehouseID] = summary
+		}
+		summary.items++
+		summary.quantity += item.Quantity
+		summary.value += item.Quantity * item.UnitPrice
+		if item.MinStock > 0 && item.Quantity <= item.MinStock {
+			summary.lowStock++
+		}
+	}
+
+	itemHeaders := []string{"SN", "Item Name", "Category", "Warehouse Code", "Warehouse Name", "Quantity", "Unit", "Min Stock", "Max Stock", "Unit Price", "Stock Value", "Description", "Status", "Updated At"}
+	if err := writeXLSXSheet(workbook, xlsxItemsSheet, itemHeaders, itemRows, styles, map[int]int{
+		0: styles.text, 5: styles.quantity, 7: styles.quantity, 8: styles.quantity,
+		9: styles.currency, 10: styles.currency, 13: styles.dateTime,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write items sheet", "message": err.Error()})
+		return
+	}
+
+	warehouseIDs := make([]uint, 0, len(summaries))
+	for id := range summaries {
+		warehouseIDs = append(warehouseIDs, id)
+	}
+	sort.Slice(warehouseIDs, func(i, j int) bool {
+		return summaries[warehouseIDs[i]].name < summaries[warehouseIDs[j]].name
+	})
+	summaryRows := make([][]interface{}, 0, len(warehouseIDs))
+	for _, id := range warehouseIDs {
+		summary := summaries[id]
+		summaryRows = append(summaryRows, []interface{}{summary.code, summary.name, summary.items, summary.quantity, summary.value, summary.lowStock})
+	}
+	if err := writeXLSXSheet(workbook, "Warehouses", []string{"Warehouse Code", "Warehouse Name", "Items", "Total Quantity", "Stock Value", "Low Stock Items"}, summaryRows, styles, map[int]int{
+		3: styles.quantity, 4: styles.currency,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write summary sheet", "message": err.Error()})
+		return
+	}
+
+	if err := writeXLSXSheet(workbook, "Unit Conversions", []string{"SN", "Item Name", "Warehouse Code", "Unit", "Factor", "Base Unit"}, conversionRows, styles, map[int]int{
+		0: styles.text, 4: styles.quantity,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write conversions sheet", "message": err.Error()})
+		return
+	}
+
+	workbook.SetActiveSheet(0)
+	writeXLSX(c, workbook, fmt.Sprintf("inventory-%s.xlsx", time.Now().Format("20060102-150405")))
+}
+
+// ExportTransactionsToXLSX exports transactions with a detail sheet and a
+// summary sheet grouped by type.
+func (h *InventoryHandler) ExportTransactionsToXLSX(c *gin.Context) {
+	var transactions []models.InventoryTransaction
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query := scope.transactions(applyTransactionFilters(h.db.Model(&models.InventoryTransaction{}), c.Request.URL.Query()))
+
+	if err := query.
+		Preload("Item").
+		Preload("Item.Warehouse").
+		Preload("FromWarehouse").
+		Preload("ToWarehouse").
+		Preload("CreatedBy").
+		Order("created_at DESC").
+		Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch transactions for export",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	styles, err := newXLSXStyles(workbook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare export styles"})
+		return
+	}
+	workbook.SetSheetName(workbook.GetSheetName(0), "Transactions")
+
+	type typeSummary struct {
+		count    int
+		quantity float64
+	}
+	byType := make(map[string]*typeSummary)
+
+	rows := make([][]interface{}, 0, len(transactions))
+	for _, t := range transactions {
+		fromWarehouse := ""
+		if t.FromWarehouse != nil {
+			fromWarehouse = t.FromWarehouse.Name
+		}
+		toWarehouse := ""
+		if t.ToWarehouse != nil {
+			toWarehouse = t.ToWarehouse.Name
+		}
+		entryQuantity, entryUnit := transactionEntry(t, t.Item.Unit)
+
+		rows = append(rows, []interface{}{
+			t.CreatedAt,
+			strings.ToUpper(t.Type),
+			t.Item.SN,
+			t.Item.Name,
+			t.Quantity,
+			t.Item.Unit,
+			entryQuantity,
+			entryUnit,
+			fromWarehouse,
+			toWarehouse,
+			t.Reference,
+			strings.ReplaceAll(t.Notes, "\n", " "),
+			t.CreatedBy.FullName,
+		})
+
+		summary, ok := byType[t.Type]
+		if !ok {
+			summary = &typeSummary{}
+			byType[t.Type] = summary
+		}
+		summary.count++
+		summary.quantity += t.Quantity
+	}
+
+	headers := []string{"Date", "Type", "Item SN", "Item Name", "Quantity", "Unit", "Entry Quantity", "Entry Unit", "From Warehouse", "To Warehouse", "Reference", "Notes", "Created By"}
+	if err := writeXLSXSheet(workbook, "Transactions", headers, rows, styles, map[int]int{
+		0: styles.dateTime, 2: styles.text, 4: styles.quantity, 6: styles.quantity,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write transactions sheet", "message": err.Error()})
+		return
+	}
+
+	types := make([]string, 0, len(byType))
+	for txType := range byType {
+		types = append(types, txType)
+	}
+	sort.Strings(types)
+	summaryRows := make([][]interface{}, 0, len(types))
+	for _, txType := range types {
+		summaryRows = append(summaryRows, []interface{}{strings.ToUpper(txType), byType[txType].count, byType[txType].quantity})
+	}
+	if err := writeXLSXSheet(workbook, "Summary", []string{"Type", "Transactions", "Total Quantity"}, summaryRows, styles, map[int]int{
+		2: styles.quantity,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write summary sheet", "message": err.Error()})
+		return
+	}
+
+	workbook.SetActiveSheet(0)
+	writeXLSX(c, workbook, fmt.Sprintf("inventory-transactions-%s.xlsx", time.Now().Format("20060102-150405")))
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errInviteUnavailable = errors.New("invitation is invalid, expired or already used")
+
+type inviteRequest struct {
+	Email        string `json:"email" binding:"required,email"`
+	RoleID       uint   `json:"role_id" binding:"required"`
+	WarehouseIDs []uint `json:"warehouse_ids"`
+}
+
+type acceptInviteRequest struct {
+	Token    string `json:"token" binding:"required"`
+	FullName string `json:"full_name" binding:"required"`
+	Phone    string `json:"phone"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// inviteResponse adds the decoded warehouse IDs and a derived status to an invite.
+type inviteResponse struct {
+	models.UserInvite
+	WarehouseIDs []uint `json:"warehouse_ids"`
+	Status       string `json:"status"` // pending, accepted, revoked, expired
+}
+
+func newInviteResponse(invite models.UserInvite) inviteResponse {
+	resp := inviteResponse{UserInvite: invite, WarehouseIDs: inviteWarehouseIDs(invite)}
+	switch {
+	case invite.AcceptedAt != nil:
+		resp.Status = "accepted"
+	case invite.RevokedAt != nil:
+		resp.Status = "revoked"
+	case time.Now().After(invite.ExpiresAt):
+		resp.Status = "expired"
+	default:
+		resp.Status = "pending"
+	}
+	return resp
+}
+
+func inviteWarehouseIDs(invite models.UserInvite) []uint {
+	ids := []uint{}
+	if invite.WarehouseIDs != "" {
+		_ = json.Unmarshal([]byte(invite.WarehouseIDs), &ids)
+	}
+	return ids
+}
+
+func (h *UserHandler) inviteLink(token string) string {
+	return fmt.Sprintf("%s/accept-invite?token=%s", strings.TrimRight(h.cfg.FrontendURL, "/"), url.QueryEscape(token))
+}
+
+// sendInvite issues a fresh token for the invite and emails it.
+func (h *UserHandler) sendInvite(invite *models.UserInvite, inviterName string) error {
+	token, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+	invite.TokenHash = hashSessionToken(token)
+	invite.ExpiresAt = time.Now().Add(time.Duration(h.cfg.InviteTTL) * time.Hour)
+	if err := h.db.Save(invite).Error; err != nil {
+		return err
+	}
+
+	email := notification.InviteEmail(invite.Email, inviterName, invite.Role.Name, h.inviteLink(token), invite.ExpiresAt)
+	_, err = h.notif.Outbox.QueueEmail(email, "user", nil)
+	return err
+}
+
+func (h *UserHandler) currentUserName(c *gin.Context) string {
+	var user models.User
+	if err := h.db.Select("id", "full_name").First(&user, c.GetUint("user_id")).Error; err != nil {
+		return "An administrator"
+	}
+	return user.FullName
+}
+
+// ListInvites returns invitations, newest first. Query: status.
+func (h *UserHandler) ListInvites(c *gin.Context) {
+	query := h.db.Preload("Role").Preload("InvitedBy")
+	now := time.Now()
+	switch c.Query("status") {
+	case "":
+	case "pending":
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case "accepted":
+		query = query.Where("accepted_at IS NOT NULL")
+	case "revoked":
+		query = query.Where("revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use pending, accepted, revoked or expired"})
+		return
+	}
+
+	var invites []models.UserInvite
+	if err := query.Order("created_at DESC").Find(&invites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch invitations",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data := make([]inviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		data = append(data, newInviteResponse(invite))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateInvite emails a single-use registration link with a fixed role and warehouse set.
+// Earlier pending invites for the same email are revoked.
+func (h *UserHandler) CreateInvite(c *gin.Context) {
+	var req inviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var existing int64
+	if err := h.db.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email sudah terdaftar"})
+		return
+	}
+
+	var role models.Role
+	if err := h.db.First(&role, req.RoleID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	warehouseIDs := make([]uint, 0, len(req.WarehouseIDs))
+	for id := range buildUintSet(req.WarehouseIDs) {
+		warehouseIDs = append(warehouseIDs, id)
+	}
+	if len(warehouseIDs) > 0 {
+		var count int64
+		if err := h.db.Model(&models.Warehouse{}).Where("id IN ?", warehouseIDs).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate warehouses"})
+			return
+		}
+		if count != int64(len(warehouseIDs)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more warehouses do not exist"})
+			return
+		}
+	}
+	encodedIDs, _ := json.Marshal(warehouseIDs)
+
+	if err := h.db.Model(&models.UserInvite{}).
+		Where("LOWER(email) = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke previous invitations"})
+		return
+	}
+
+	invite := models.UserInvite{
+		Email:        email,
+		RoleID:       role.ID,
+		Role:         role,
+		WarehouseIDs: string(encodedIDs),
+		InvitedByID:  c.GetUint("user_id"),
+	}
+	if err := h.sendInvite(&invite, h.currentUserName(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create invitation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.db.Preload("Role").Preload("InvitedBy").First(&invite, invite.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation sent successfully",
+		"data":    newInviteResponse(invite),
+	})
+}
+
+// ResendInvite issues a new token for an unaccepted invite, which also extends its expiry.
+func (h *UserHandler) ResendInvite(c *gin.Context) {
+	var invite models.UserInvite
+	if err := h.db.Preload("Role").First(&invite, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return
+	}
+	if invite.AcceptedAt != nil || invite.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending or expired invitations can be resent"})
+		return
+	}
+
+	if err := h.sendInvite(&invite, h.currentUserName(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to resend invitation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.db.Preload("Role").Preload("InvitedBy").First(&invite, invite.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation resent successfully",
+		"data":    newInviteResponse(invite),
+	})
+}
+
+// RevokeInvite invalidates an unaccepted invite.
+func (h *UserHandler) RevokeInvite(c *gin.Context) {
+	result := h.db.Model(&models.UserInvite{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", c.Param("id")).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or no longer pending"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+func findPendingInvite(db *gorm.DB, token string) (*models.UserInvite, error) {
+	var invite models.UserInvite
+	if err := db.Preload("Role").
+		Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", hashSessionToken(token), time.Now()).
+		First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInviteUnavailable
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// GetInvite shows the email and role of a pending invite so the signup form can be prefilled.
+func (h *AuthHandler) GetInvite(c *gin.Context) {
+	invite, err := findPendingInvite(h.db, c.Param("token"))
+	if err != nil {
+		if errors.Is(err, errInviteUnavailable) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid, expired or already used"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"email":      invite.Email,
+			"role":       invite.Role.Name,
+			"expires_at": invite.ExpiresAt,
+		},
+	})
+}
+
+// AcceptInvite creates the invited account and signs it in.
+func (h *AuthHandler) AcceptInvite(c *gin.Context) {
+	var req acceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var user models.User
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		invite, err := findPendingInvite(tx, req.Token)
+		if err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", invite.Email).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errInviteUnavailable
+		}
+
+		user = models.User{
+			Email:    invite.Email,
+			Password: hashedPassword,
+			FullName: strings.TrimSpace(req.FullName),
+			Phone:    strings.TrimSpace(req.Phone),
+			RoleID:   invite.RoleID,
+			IsActive: true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		for _, warehouseID := range inviteWarehouseIDs(*invite) {
+			if err := tx.Create(&models.UserWarehouse{UserID: user.ID, WarehouseID: warehouseID}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The conditions guard against the same token being accepted twice.
+		now := time.Now()
+		result := tx.Model(&models.UserInvite{}).
+			Where("id = ? AND accepted_at IS NULL", invite.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_user_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInviteUnavailable
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errInviteUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is invalid, expired or already used"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to accept invitation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.db.Preload("Role").Preload("Role.Menus").Preload("Role.Permissions").
+		Preload("Warehouses").Preload("Warehouses.Warehouse").
+		First(&user, user.ID)
+
+	h.queueWelcomeEmail(&user)
+
+	// The new account signs in like any other, so a role that requires 2FA
+	// gets the setup challenge instead of a session.
+	challenge, err := h.loginChallenge(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusCreated, challenge)
+		return
+	}
+
+	h.registerLoginSuccess(c, &user)
+	tokens, err := startSession(h.db, h.config, c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusCreated, LoginResponse{
+		sessionTokens: tokens,
+		User:          user,
+	})
+}
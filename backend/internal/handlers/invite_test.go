@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptInviteRequiresTwoFactorSetup(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{
+		JWTSecret:                     "test-secret",
+		TwoFactorEnforced:             true,
+		TwoFactorSensitivePermissions: []string{"role.manage"},
+	}
+	h := NewAuthHandler(db, cfg, notification.NewNotificationService(cfg, db))
+	router := gin.New()
+	router.POST("/api/v1/auth/invites/accept", h.AcceptInvite)
+
+	admin := models.Role{Name: "admin"}
+	mustCreate(t, db, &admin)
+	grantPermissions(t, db, &admin, "role.manage")
+	inviter := models.User{Email: "owner@example.com", Password: "x", FullName: "Owner", RoleID: admin.ID, IsActive: true}
+	mustCreate(t, db, &inviter)
+	mustCreate(t, db, &models.UserInvite{
+		Email:       "new@example.com",
+		TokenHash:   hashSessionToken("invite-token"),
+		RoleID:      admin.ID,
+		InvitedByID: inviter.ID,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+
+	resp := serve(router, http.MethodPost, "/api/v1/auth/invites/accept", gin.H{
+		"token": "invite-token", "full_name": "New Admin", "password": "secret123",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body)
+	}
+	var body map[string]interface{}
+	resp.decode(t, &body)
+	if body["two_factor_setup_required"] != true || body["pre_auth_token"] == "" || body["refresh_token"] != nil {
+		t.Fatalf("expected a 2FA setup challenge instead of a session, got %s", resp.Body)
+	}
+
+	var sessions int64
+	db.Model(&models.UserSession{}).Count(&sessions)
+	if sessions != 0 {
+		t.Fatalf("accepting the invite started %d session(s)", sessions)
+	}
+}
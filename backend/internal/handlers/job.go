@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/jobs"
+	"tatapps/internal/services/realtime"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	jobTypeInventoryImport    = "inventory_import"
+	jobTypeItemsPDF           = "inventory_items_pdf"
+	jobTypeTransactionsPDF    = "inventory_transactions_pdf"
+	jobTypeDatabaseBackup     = "database_backup"
+	jobProgressLoadedFraction = 20 // share of an export spent loading rows
+)
+
+// jobTypePermissions is the permission a job type's result still requires when
+// it is downloaded, matching the route that enqueues it.
+var jobTypePermissions = map[string]string{
+	jobTypeInventoryImport: "inventory.create",
+	jobTypeItemsPDF:        "inventory.view",
+	jobTypeTransactionsPDF: "inventory.view",
+	jobTypeDatabaseBackup:  "backup.run",
+}
+
+type JobHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	runner *jobs.Runner
+	stream *realtime.Hub
+}
+
+// NewJobHandler registers the inventory and backup job types on runner.
+func NewJobHandler(db *gorm.DB, cfg *config.Config, runner *jobs.Runner, stream *realtime.Hub) *JobHandler {
+	h := &JobHandler{db: db, cfg: cfg, runner: runner, stream: stream}
+	runner.Register(jobTypeInventoryImport, h.runInventoryImport)
+	runner.Register(jobTypeItemsPDF, h.runItemsPDF)
+	runner.Register(jobTypeTransactionsPDF, h.runTransactionsPDF)
+	runner.Register(jobTypeDatabaseBackup, h.runDatabaseBackup)
+	return h
+}
+
+// jobScope records the requester's warehouse scope at enqueue time.
+type jobScope struct {
+	Restricted   bool   `json:"restricted"`
+	WarehouseIDs []uint `json:"warehouse_ids,omitempty"`
+}
+
+func newJobScope(scope warehouseScope) jobScope {
+	return jobScope{Restricted: scope.restricted, WarehouseIDs: scope.ids}
+}
+
+func (s jobScope) scope() warehouseScope {
+	if !s.Restricted {
+		return warehouseScope{}
+	}
+	return newWarehouseScope(s.WarehouseIDs)
+}
+
+type importJobPayload struct {
+	jobScope
+	Filename string      `json:"filename"`
+	Rows     []importRow `json:"rows"`
+}
+
+type exportJobPayload struct {
+	jobScope
+	Filters url.Values `json:"filters"`
+}
+
+type jobResponse struct {
+	models.Job
+	Result      json.RawMessage `json:"result,omitempty"`
+	DownloadURL string          `json:"download_url,omitempty"`
+}
+
+func newJobResponse(job models.Job) jobResponse {
+	response := jobResponse{Job: job}
+	if strings.TrimSpace(job.Result) != "" && job.Result != "null" {
+		response.Result = json.RawMessage(job.Result)
+	}
+	if job.Status == jobs.StatusCompleted && job.FilePath != "" {
+		response.DownloadURL = fmt.Sprintf("/api/v1/jobs/%d/download", job.ID)
+	}
+	return response
+}
+
+func decodeJobPayload(job *models.Job, target interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), target); err != nil {
+		return fmt.Errorf("invalid job payload: %w", err)
+	}
+	return nil
+}
+
+var jobListSort = listSort{
+	table: "jobs",
+	fields: map[string]string{
+		"id":         "jobs.id",
+		"created_at": "jobs.created_at",
+		"status":     "jobs.status",
+		"type":       "jobs.type",
+	},
+	defaultSort: "id",
+	defaultDesc: true,
+}
+
+// ListJobs returns the current user's jobs, newest first
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	params, err := parseListParams(c, jobListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.Job{}).Where("created_by_id = ?", c.GetUint("user_id"))
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := strings.TrimSpace(c.Query("type")); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch jobs",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var list []models.Job
+	if err := params.apply(query, jobListSort).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch jobs",
+			"message": err.Error(),
+		})
+		return
+	}
+	keep, meta := params.finish(c, total, len(list), func(i int) uint { return list[i].ID })
+
+	data := make([]jobResponse, 0, keep)
+	for _, job := range list[:keep] {
+		data = append(data, newJobResponse(job))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "meta": meta})
+}
+
+func (h *JobHandler) findOwnJob(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return nil, false
+	}
+
+	var job models.Job
+	if err := h.db.Where("id = ? AND created_by_id = ?", id, c.GetUint("user_id")).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch job",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return &job, true
+}
+
+// GetJob returns status and progress of one job
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.findOwnJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": newJobResponse(*job)})
+}
+
+// DownloadJobResult streams the file produced by a completed job
+func (h *JobHandler) DownloadJobResult(c *gin.Context) {
+	job, ok := h.findOwnJob(c)
+	if !ok {
+		return
+	}
+	if permission, ok := jobTypePermissions[job.Type]; ok {
+		allowed, err := middleware.HasPermission(c, h.db, permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+	}
+	if job.Status != jobs.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job has not completed", "status": job.Status})
+		return
+	}
+	path := h.runner.ResultPath(job)
+	if path == "" {
+		c.JSON(http.StatusGone, gin.H{"error": "Job result is no longer available"})
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Job result is no longer available"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", job.FileName))
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", job.ContentType)
+	c.File(path)
+}
+
+func (h *JobHandler) enqueue(c *gin.Context, jobType string, payload interface{}) {
+	job, err := h.runner.Enqueue(jobType, c.GetUint("user_id"), payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue job",
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"data":    newJobResponse(*job),
+		"message": "Job queued",
+	})
+}
+
+// exportPayload captures the query filters and the caller's warehouse scope.
+func (h *JobHandler) exportPayload(c *gin.Context) (exportJobPayload, error) {
+	scope, err := resolveWarehouseScope(h.db, c)
+	if err != nil {
+		return exportJobPayload{}, err
+	}
+	return exportJobPayload{jobScope: newJobScope(scope), Filters: c.Request.URL.Query()}, nil
+}
+
+// EnqueueImport parses an uploaded CSV/XLSX file and queues it for import
+func (h *JobHandler) EnqueueImport(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Import file is required",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rows, err := parseUploadedImport(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	h.enqueue(c, jobTypeInventoryImport, importJobPayload{jobScope: newJobScope(scope), Filename: file.Filename, Rows: rows})
+}
+
+// EnqueueItemsPDF queues the inventory PDF export with the same filters as /inventory/export/pdf
+func (h *JobHandler) EnqueueItemsPDF(c *gin.Context) {
+	payload, err := h.exportPayload(c)
+	if err != nil {
+		respondWarehouseScopeError(c, err)
+		return
+	}
+	h.enqueue(c, jobTypeItemsPDF, payload)
+}
+
+// EnqueueTransactionsPDF queues the transaction PDF export with the same filters as /inventory/transactions/export/pdf
+func (h *JobHandler) EnqueueTransactionsPDF(c *gin.Context) {
+	payload, err := h.exportPayload(c)
+	if err != nil {
+		respondWarehouseScopeError(c, err)
+		return
+	}
+	h.enqueue(c, jobTypeTransactionsPDF, payload)
+}
+
+// EnqueueDatabaseBackup queues a pg_dump of the database
+func (h *JobHandler) EnqueueDatabaseBackup(c *gin.Context) {
+	h.enqueue(c, jobTypeDatabaseBackup, struct{}{})
+}
+
+func (h *JobHandler) runInventoryImport(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) (*jobs.Result, error) {
+	var payload importJobPayload
+	if err := decodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	var summary importSummary
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = applyImportRows(tx, payload.Rows, payload.scope(), job.CreatedByID, progress)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	streamImport(h.stream, summary)
+
+	return &jobs.Result{Summary: gin.H{
+		"filename": payload.Filename,
+		"message":  fmt.Sprintf("Import completed. Inserted: %d, Updated: %d", summary.Inserted, summary.Updated),
+		"summary":  summary,
+	}}, nil
+}
+
+// scaleProgress maps a renderer's 0-100 onto the part of the job left after loading rows.
+func scaleProgress(progress jobs.ProgressFunc) func(int) {
+	return func(percent int) {
+		progress(jobProgressLoadedFraction + percent*(100-jobProgressLoadedFraction)/100)
+	}
+}
+
+func (h *JobHandler) runItemsPDF(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) (*jobs.Result, error) {
+	var payload exportJobPayload
+	if err := decodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	query := payload.scope().items(applyInventoryFilters(h.db.WithContext(ctx).Preload("Warehouse"), payload.Filters))
+	var items []models.InventoryItem
+	if err := query.Order("name ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	progress(jobProgressLoadedFraction)
+
+	data, err := renderItemsPDF(items, scaleProgress(progress))
+	if err != nil {
+		return nil, err
+	}
+	return &jobs.Result{
+		FileName:    fmt.Sprintf("inventory-%s.pdf", time.Now().Format("20060102-150405")),
+		ContentType: "application/pdf",
+		Data:        data,
+		Summary:     gin.H{"rows": len(items)},
+	}, nil
+}
+
+func (h *JobHandler) runTransactionsPDF(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) (*jobs.Result, error) {
+	var payload exportJobPayload
+	if err := decodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	var transactions []models.InventoryTransaction
+	query := payload.scope().transactions(applyTransactionFilters(h.db.WithContext(ctx).Model(&models.InventoryTransaction{}), payload.Filters))
+	if err := query.
+		Preload("Item").
+		Preload("FromWarehouse").
+		Preload("ToWarehouse").
+		Preload("CreatedBy").
+		Order("created_at DESC").
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	progress(jobProgressLoadedFraction)
+
+	data, err := renderTransactionsPDF(transactions, scaleProgress(progress))
+	if err != nil {
+		return nil, err
+	}
+	return &jobs.Result{
+		FileName:    fmt.Sprintf("inventory-transactions-%s.pdf", time.Now().Format("20060102-150405")),
+		ContentType: "application/pdf",
+		Data:        data,
+		Summary:     gin.H{"rows": len(transactions)},
+	}, nil
+}
+
+func (h *JobHandler) runDatabaseBackup(ctx context.Context, job *models.Job, progress jobs.ProgressFunc) (*jobs.Result, error) {
+	output, err := dumpDatabase(ctx, h.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create backup: %w", err)
+	}
+	return &jobs.Result{
+		FileName:    backupFilename(),
+		ContentType: "application/sql",
+		Data:        output,
+		Summary:     gin.H{"size": len(output)},
+	}, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJobResultsNeedTheSessionAndTheJobPermission(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	admin := models.Role{Name: "admin"}
+	mustCreate(t, db, &admin)
+	grantPermissions(t, db, &admin, "backup.run", "inventory.view")
+	owner := models.User{Email: "owner@example.com", Password: "x", FullName: "Owner", RoleID: admin.ID, IsActive: true}
+	mustCreate(t, db, &owner)
+	backup := models.Job{Type: jobTypeDatabaseBackup, Status: jobs.StatusCompleted, Payload: "{}", Result: "null", CreatedByID: owner.ID}
+	mustCreate(t, db, &backup)
+
+	h := NewJobHandler(db, cfg, jobs.NewRunner(db, nil, 1, t.TempDir()), nil)
+	// Mirrors the /jobs group in routes.go.
+	register := func(router *gin.Engine, auth gin.HandlerFunc) {
+		group := router.Group("/jobs", auth, middleware.RequireUserSession())
+		group.GET("", h.ListJobs)
+		group.GET("/:id/download", h.DownloadJobResult)
+	}
+	download := fmt.Sprintf("/jobs/%d/download", backup.ID)
+
+	keyRouter := gin.New()
+	register(keyRouter, middleware.AuthMiddleware(cfg, db))
+	key := newAPIKey(t, db, owner, "inventory.view")
+	for _, path := range []string{"/jobs", download} {
+		if resp := serve(keyRouter, http.MethodGet, path, nil, "X-API-Key", key); resp.Code != http.StatusForbidden {
+			t.Fatalf("expected an API key to get 403 on %s, got %d: %s", path, resp.Code, resp.Body)
+		}
+	}
+
+	// The owner lost backup.run since enqueueing the dump.
+	viewer := models.Role{Name: "viewer"}
+	mustCreate(t, db, &viewer)
+	grantPermissions(t, db, &viewer, "inventory.view")
+	db.Model(&owner).Update("role_id", viewer.ID)
+	owner.RoleID = viewer.ID
+	userRouter := gin.New()
+	register(userRouter, asUser(owner))
+	if resp := serve(userRouter, http.MethodGet, download, nil); resp.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 downloading a backup without backup.run, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestListJobsIsPagedAndOwnOnly(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	owner := models.User{Email: "owner@example.com", Password: "x", FullName: "Owner", RoleID: role.ID, IsActive: true}
+	other := models.User{Email: "other@example.com", Password: "x", FullName: "Other", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &owner, &other)
+	for _, creator := range []models.User{owner, other, owner, owner} {
+		mustCreate(t, db, &models.Job{Type: jobTypeItemsPDF, Status: jobs.StatusCompleted, Payload: "{}", Result: "null", CreatedByID: creator.ID})
+	}
+
+	h := NewJobHandler(db, &config.Config{JWTSecret: "test-secret"}, jobs.NewRunner(db, nil, 1, t.TempDir()), nil)
+	router := gin.New()
+	router.GET("/jobs", asUser(owner), h.ListJobs)
+
+	type page struct {
+		Data []jobResponse `json:"data"`
+		Meta listMeta      `json:"meta"`
+	}
+	var first page
+	resp := serve(router, http.MethodGet, "/jobs?page_size=2", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	resp.decode(t, &first)
+	if len(first.Data) != 2 || first.Meta.Total != 3 || first.Meta.NextCursor == "" || first.Data[0].ID != 4 {
+		t.Fatalf("expected the newest 2 of 3 own jobs with a next cursor, got %+v and %+v", first.Data, first.Meta)
+	}
+
+	var rest page
+	serve(router, http.MethodGet, "/jobs?page_size=2&cursor="+first.Meta.NextCursor, nil).decode(t, &rest)
+	if len(rest.Data) != 1 || rest.Data[0].ID != 1 || rest.Meta.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v and %+v", rest.Data, rest.Meta)
+	}
+}
@@ -0,0 +1,654 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	locationTypeZone  = "zone"
+	locationTypeAisle = "aisle"
+	locationTypeBin   = "bin"
+)
+
+// allowedLocationParents lists which location types may contain a given type.
+var allowedLocationParents = map[string][]string{
+	locationTypeZone:  {},
+	locationTypeAisle: {locationTypeZone},
+	locationTypeBin:   {locationTypeZone, locationTypeAisle},
+}
+
+type LocationHandler struct {
+	db *gorm.DB
+}
+
+func NewLocationHandler(db *gorm.DB) *LocationHandler {
+	return &LocationHandler{db: db}
+}
+
+type locationRequest struct {
+	ParentID  *uint  `json:"parent_id"`
+	Type      string `json:"type"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	SortOrder *int   `json:"sort_order"`
+	IsActive  *bool  `json:"is_active"`
+}
+
+type pickListRequest struct {
+	Lines []struct {
+		ItemID   uint    `json:"item_id"`
+		Quantity float64 `json:"quantity"`
+	} `json:"lines"`
+}
+
+type pickListEntry struct {
+	ItemID       uint    `json:"item_id"`
+	ItemSN       string  `json:"item_sn"`
+	ItemName     string  `json:"item_name"`
+	Unit         string  `json:"unit"`
+	LocationID   *uint   `json:"location_id,omitempty"`
+	LocationPath string  `json:"location_path"`
+	Quantity     float64 `json:"quantity"`
+}
+
+func parseWarehouseParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// warehouseParam parses the warehouse ID and checks it against the caller's warehouse scope.
+func (h *LocationHandler) warehouseParam(c *gin.Context) (uint, bool) {
+	warehouseID, ok := parseWarehouseParam(c)
+	if !ok {
+		return 0, false
+	}
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return 0, false
+	}
+	if !scope.allows(warehouseID) {
+		respondWarehouseForbidden(c)
+		return 0, false
+	}
+	return warehouseID, true
+}
+
+func (h *LocationHandler) ensureWarehouse(c *gin.Context, warehouseID uint) bool {
+	var warehouse models.Warehouse
+	if err := h.db.Select("id").First(&warehouse, warehouseID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Warehouse not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch warehouse"})
+		return false
+	}
+	return true
+}
+
+func (h *LocationHandler) findLocation(c *gin.Context, warehouseID uint) (*models.WarehouseLocation, bool) {
+	locationID, err := strconv.ParseUint(c.Param("locationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid location ID"})
+		return nil, false
+	}
+
+	var location models.WarehouseLocation
+	if err := h.db.Where("warehouse_id = ?", warehouseID).First(&location, locationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch location"})
+		return nil, false
+	}
+	return &location, true
+}
+
+// ListLocations returns the zones, aisles and bins of a warehouse ordered by path.
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Where("warehouse_id = ?", warehouseID)
+	if locationType := strings.TrimSpace(c.Query("type")); locationType != "" {
+		query = query.Where("type = ?", locationType)
+	}
+	if parentID := strings.TrimSpace(c.Query("parent_id")); parentID != "" {
+		query = query.Where("parent_id = ?", parentID)
+	}
+
+	var locations []models.WarehouseLocation
+	if err := query.Order("path ASC").Find(&locations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch locations",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": locations})
+}
+
+// CreateLocation adds a zone, aisle or bin to a warehouse.
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok || !h.ensureWarehouse(c, warehouseID) {
+		return
+	}
+
+	var req locationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	location := models.WarehouseLocation{
+		WarehouseID: warehouseID,
+		ParentID:    req.ParentID,
+		Type:        strings.ToLower(strings.TrimSpace(req.Type)),
+		Code:        strings.TrimSpace(req.Code),
+		Name:        strings.TrimSpace(req.Name),
+		IsActive:    true,
+	}
+	if req.SortOrder != nil {
+		location.SortOrder = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		location.IsActive = *req.IsActive
+	}
+
+	if err := h.validateLocation(&location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&location).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create location",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    location,
+		"message": "Location created successfully",
+	})
+}
+
+// UpdateLocation renames or re-parents a location and rewrites descendant paths.
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok {
+		return
+	}
+	location, ok := h.findLocation(c, warehouseID)
+	if !ok {
+		return
+	}
+
+	var req locationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	oldPath := location.Path
+	if strings.TrimSpace(req.Code) != "" {
+		location.Code = strings.TrimSpace(req.Code)
+	}
+	if req.Name != "" {
+		location.Name = strings.TrimSpace(req.Name)
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			location.ParentID = nil
+		} else {
+			location.ParentID = req.ParentID
+		}
+	}
+	if req.SortOrder != nil {
+		location.SortOrder = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		location.IsActive = *req.IsActive
+	}
+
+	if err := h.validateLocation(location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(location).Error; err != nil {
+			return err
+		}
+		if oldPath == location.Path {
+			return nil
+		}
+		// Lengths are counted by Postgres in characters, so codes with non-ASCII
+		// letters or LIKE wildcards keep their descendants' paths intact.
+		prefix := oldPath + "/"
+		return tx.Model(&models.WarehouseLocation{}).
+			Where("warehouse_id = ? AND LEFT(path, char_length(?)) = ?", warehouseID, prefix, prefix).
+			Update("path", gorm.Expr("? || SUBSTRING(path FROM char_length(?) + 1)", location.Path, oldPath)).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update location",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    location,
+		"message": "Location updated successfully",
+	})
+}
+
+// DeleteLocation removes an empty location that has no children.
+func (h *LocationHandler) DeleteLocation(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok {
+		return
+	}
+	location, ok := h.findLocation(c, warehouseID)
+	if !ok {
+		return
+	}
+
+	var childCount int64
+	if err := h.db.Model(&models.WarehouseLocation{}).Where("parent_id = ?", location.ID).Count(&childCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check child locations"})
+		return
+	}
+	if childCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Location still has child locations"})
+		return
+	}
+
+	var stockCount int64
+	if err := h.db.Model(&models.InventoryBinStock{}).Where("location_id = ? AND quantity <> 0", location.ID).Count(&stockCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bin stock"})
+		return
+	}
+	if stockCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Location still holds stock"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("location_id = ?", location.ID).Delete(&models.InventoryBinStock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(location).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete location",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+}
+
+// GetLocationStock lists the items stored in a location and all of its descendants.
+func (h *LocationHandler) GetLocationStock(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok {
+		return
+	}
+	location, ok := h.findLocation(c, warehouseID)
+	if !ok {
+		return
+	}
+
+	var stock []models.InventoryBinStock
+	if err := h.db.
+		Joins("Location").
+		Preload("Item").
+		Where("\"Location\".warehouse_id = ?", warehouseID).
+		Where("(\"Location\".id = ? OR LEFT(\"Location\".path, char_length(?)) = ?)", location.ID, location.Path+"/", location.Path+"/").
+		Where("inventory_bin_stocks.quantity <> 0").
+		Order("\"Location\".path ASC").
+		Find(&stock).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch location stock",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stock})
+}
+
+// GetItemBins returns the bin breakdown of an inventory item, including stock not yet put away.
+func (h *LocationHandler) GetItemBins(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	item, ok := findScopedItem(h.db, c, uint(id))
+	if !ok {
+		return
+	}
+
+	var stock []models.InventoryBinStock
+	if err := h.db.
+		Joins("Location").
+		Where("inventory_bin_stocks.item_id = ? AND inventory_bin_stocks.quantity <> 0", item.ID).
+		Order("\"Location\".path ASC").
+		Find(&stock).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch item bins",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	binned := 0.0
+	for _, entry := range stock {
+		binned += entry.Quantity
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       stock,
+		"binned":     binned,
+		"unassigned": item.Quantity - binned,
+	})
+}
+
+// BuildPickList allocates requested quantities across bins and returns the lines sorted by bin path.
+func (h *LocationHandler) BuildPickList(c *gin.Context) {
+	warehouseID, ok := h.warehouseParam(c)
+	if !ok {
+		return
+	}
+
+	var req pickListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+	if len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pick lines provided"})
+		return
+	}
+
+	entries := make([]pickListEntry, 0, len(req.Lines))
+	var shortages []string
+
+	for _, line := range req.Lines {
+		if line.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Quantity for item %d must be greater than zero", line.ItemID)})
+			return
+		}
+
+		var item models.InventoryItem
+		if err := h.db.Where("warehouse_id = ?", warehouseID).First(&item, line.ItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Item %d not found in this warehouse", line.ItemID)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
+			return
+		}
+
+		var stock []models.InventoryBinStock
+		if err := h.db.
+			Joins("Location").
+			Where("inventory_bin_stocks.item_id = ? AND inventory_bin_stocks.quantity > 0", item.ID).
+			Order("\"Location\".path ASC").
+			Find(&stock).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bin stock"})
+			return
+		}
+
+		remaining := line.Quantity
+		binned := 0.0
+		for _, entry := range stock {
+			binned += entry.Quantity
+			if remaining <= 0 {
+				continue
+			}
+			take := entry.Quantity
+			if take > remaining {
+				take = remaining
+			}
+			locationID := entry.LocationID
+			entries = append(entries, pickListEntry{
+				ItemID:       item.ID,
+				ItemSN:       item.SN,
+				ItemName:     item.Name,
+				Unit:         item.Unit,
+				LocationID:   &locationID,
+				LocationPath: entry.Location.Path,
+				Quantity:     take,
+			})
+			remaining -= take
+		}
+
+		if unassigned := item.Quantity - binned; remaining > 0 && unassigned > 0 {
+			take := unassigned
+			if take > remaining {
+				take = remaining
+			}
+			entries = append(entries, pickListEntry{
+				ItemID:   item.ID,
+				ItemSN:   item.SN,
+				ItemName: item.Name,
+				Unit:     item.Unit,
+				Quantity: take,
+			})
+			remaining -= take
+		}
+
+		if remaining > 0 {
+			shortages = append(shortages, fmt.Sprintf("%s: short by %s %s", item.Name, formatFloat(remaining), item.Unit))
+		}
+	}
+
+	// Unassigned stock sorts last so pickers walk the bins first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if (entries[i].LocationID == nil) != (entries[j].LocationID == nil) {
+			return entries[j].LocationID == nil
+		}
+		return entries[i].LocationPath < entries[j].LocationPath
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      entries,
+		"shortages": shortages,
+	})
+}
+
+func (h *LocationHandler) validateLocation(location *models.WarehouseLocation) error {
+	parents, known := allowedLocationParents[location.Type]
+	if !known {
+		return errors.New("type must be one of zone, aisle or bin")
+	}
+	if location.Code == "" {
+		return errors.New("code is required")
+	}
+	if strings.Contains(location.Code, "/") {
+		return errors.New("code cannot contain '/'")
+	}
+
+	currentPath := location.Path
+	location.Path = location.Code
+	if location.ParentID == nil {
+		if location.Type == locationTypeAisle {
+			return errors.New("an aisle must belong to a zone")
+		}
+	} else {
+		if location.ID != 0 && *location.ParentID == location.ID {
+			return errors.New("a location cannot be its own parent")
+		}
+		var parent models.WarehouseLocation
+		if err := h.db.Where("warehouse_id = ?", location.WarehouseID).First(&parent, *location.ParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("parent location not found in this warehouse")
+			}
+			return err
+		}
+		allowed := false
+		for _, parentType := range parents {
+			if parent.Type == parentType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("a %s cannot be placed under a %s", location.Type, parent.Type)
+		}
+		if location.ID != 0 && strings.HasPrefix(parent.Path+"/", currentPath+"/") {
+			return errors.New("a location cannot be moved under its own descendant")
+		}
+		location.Path = parent.Path + "/" + location.Code
+	}
+
+	var duplicate int64
+	query := h.db.Model(&models.WarehouseLocation{}).
+		Where("warehouse_id = ? AND path = ?", location.WarehouseID, location.Path)
+	if location.ID != 0 {
+		query = query.Where("id <> ?", location.ID)
+	}
+	if err := query.Count(&duplicate).Error; err != nil {
+		return err
+	}
+	if duplicate > 0 {
+		return fmt.Errorf("location %s already exists", location.Path)
+	}
+	return nil
+}
+
+// loadBin fetches an active bin belonging to the given warehouse.
+func loadBin(tx *gorm.DB, locationID, warehouseID uint) (*models.WarehouseLocation, error) {
+	var location models.WarehouseLocation
+	if err := tx.First(&location, locationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newNotFoundError("Location %d not found", locationID)
+		}
+		return nil, err
+	}
+	if location.WarehouseID != warehouseID {
+		return nil, newValidationError("Location %s does not belong to the item's warehouse", location.Path)
+	}
+	if location.Type != locationTypeBin {
+		return nil, newValidationError("Location %s is not a bin", location.Path)
+	}
+	if !location.IsActive {
+		return nil, newValidationError("Bin %s is inactive", location.Path)
+	}
+	return &location, nil
+}
+
+// adjustBinStock adds delta to the item's quantity in a bin, refusing to go negative.
+func adjustBinStock(tx *gorm.DB, itemID, warehouseID, locationID uint, delta float64) error {
+	location, err := loadBin(tx, locationID, warehouseID)
+	if err != nil {
+		return err
+	}
+
+	var stock models.InventoryBinStock
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("item_id = ? AND location_id = ?", itemID, locationID).
+		First(&stock).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		stock = models.InventoryBinStock{ItemID: itemID, LocationID: locationID}
+	case err != nil:
+		return err
+	}
+
+	if stock.Quantity+delta < 0 {
+		return newValidationError("Insufficient stock in bin %s", location.Path)
+	}
+	stock.Quantity += delta
+	return tx.Save(&stock).Error
+}
+
+// unassignedBinQuantity returns the part of an item's quantity not stored in any bin.
+func unassignedBinQuantity(tx *gorm.DB, item *models.InventoryItem) (float64, error) {
+	var binned float64
+	if err := tx.Model(&models.InventoryBinStock{}).
+		Where("item_id = ?", item.ID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&binned).Error; err != nil {
+		return 0, err
+	}
+	return item.Quantity - binned, nil
+}
+
+// ensureUnassignedStock rejects removals that would eat into binned stock when no source bin is given.
+func ensureUnassignedStock(tx *gorm.DB, item *models.InventoryItem, quantity float64) error {
+	unassigned, err := unassignedBinQuantity(tx, item)
+	if err != nil {
+		return err
+	}
+	if unassigned < quantity {
+		return newValidationError("Only %s %s is outside bins; specify a source bin", formatFloat(unassigned), item.Unit)
+	}
+	return nil
+}
+
+// takeFromBinOrUnassigned removes quantity from a source bin, or checks that enough
+// unbinned stock exists when no bin is given.
+func takeFromBinOrUnassigned(tx *gorm.DB, item *models.InventoryItem, fromLocationID *uint, quantity float64) error {
+	if fromLocationID != nil {
+		return adjustBinStock(tx, item.ID, item.WarehouseID, *fromLocationID, -quantity)
+	}
+	return ensureUnassignedStock(tx, item, quantity)
+}
+
+// revertBinMovement undoes the bin side of a transaction on its source item.
+// Transfer destinations are handled by the caller because they live on a different item.
+func revertBinMovement(tx *gorm.DB, item *models.InventoryItem, transaction *models.InventoryTransaction) error {
+	if transaction.FromLocationID != nil {
+		quantity := transaction.Quantity
+		if transaction.Type == "adjustment" {
+			quantity = -transaction.Quantity
+		}
+		if err := adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.FromLocationID, quantity); err != nil {
+			return err
+		}
+	}
+	if transaction.ToLocationID != nil && transaction.Type != "transfer" {
+		if err := adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.ToLocationID, -transaction.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	loginReasonUnknownUser      = "unknown_user"
+	loginReasonInactive         = "inactive"
+	loginReasonInvalidPassword  = "invalid_password"
+	loginReasonInvalidTwoFactor = "invalid_two_factor"
+	loginReasonLocked           = "locked"
+	loginReasonIPBlocked        = "ip_blocked"
+
+	maxLoginLockout = 24 * time.Hour
+)
+
+// failedLoginReasons are the attempts that count against an IP address.
+var failedLoginReasons = []string{loginReasonUnknownUser, loginReasonInvalidPassword, loginReasonInvalidTwoFactor}
+
+// recordLoginAttempt writes the attempt log; failures to log never block a login.
+func (h *AuthHandler) recordLoginAttempt(c *gin.Context, email string, userID *uint, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		UserAgent: truncateString(c.Request.UserAgent(), 255),
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := h.db.Create(&attempt).Error; err != nil {
+		log.Printf("[login] failed to record login attempt: %v", err)
+	}
+}
+
+// ipLoginBlock returns how long the client IP must wait when it has too many
+// recent failures, across all accounts.
+func (h *AuthHandler) ipLoginBlock(c *gin.Context) (time.Duration, error) {
+	if h.config.LoginMaxFailedPerIP <= 0 {
+		return 0, nil
+	}
+	window := time.Duration(h.config.LoginIPWindowMinutes) * time.Minute
+	since := time.Now().Add(-window)
+
+	var stats struct {
+		Failures int64
+		Oldest   *time.Time
+	}
+	if err := h.db.Model(&models.LoginAttempt{}).
+		Select("COUNT(*) AS failures, MIN(created_at) AS oldest").
+		Where("ip_address = ? AND success = ? AND reason IN ? AND created_at > ?", c.ClientIP(), false, failedLoginReasons, since).
+		Scan(&stats).Error; err != nil {
+		return 0, err
+	}
+	if stats.Failures < int64(h.config.LoginMaxFailedPerIP) || stats.Oldest == nil {
+		return 0, nil
+	}
+	return time.Until(stats.Oldest.Add(window)), nil
+}
+
+// accountLocked reports the remaining backoff or lockout of the user, if any.
+func accountLocked(user *models.User) (time.Duration, bool) {
+	if user.LockedUntil == nil {
+		return 0, false
+	}
+	remaining := time.Until(*user.LockedUntil)
+	return remaining, remaining > 0
+}
+
+// loginBackoff is the wait after the given number of consecutive failures: 1s,
+// 2s, 4s, ... below the limit, then the lockout duration doubling per failure.
+func (h *AuthHandler) loginBackoff(failures int) time.Duration {
+	limit := h.config.LoginMaxFailedAttempts
+	var wait time.Duration
+	if limit <= 0 || failures < limit {
+		wait = time.Second * time.Duration(math.Pow(2, float64(failures-1)))
+	} else {
+		base := time.Duration(h.config.LoginLockoutMinutes) * time.Minute
+		wait = base * time.Duration(math.Pow(2, float64(failures-limit)))
+	}
+	if wait <= 0 || wait > maxLoginLockout {
+		wait = maxLoginLockout
+	}
+	return wait
+}
+
+// registerLoginFailure counts a failed password or second factor against the
+// account and emails the owner when it becomes locked.
+func (h *AuthHandler) registerLoginFailure(c *gin.Context, user *models.User, reason string) {
+	h.recordLoginAttempt(c, user.Email, &user.ID, false, reason)
+
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumn("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+		log.Printf("[login] failed to count failure for user %d: %v", user.ID, err)
+		return
+	}
+	var failures int
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).Pluck("failed_login_count", &failures).Error; err != nil {
+		log.Printf("[login] failed to read failures for user %d: %v", user.ID, err)
+		return
+	}
+
+	lockedUntil := time.Now().Add(h.loginBackoff(failures))
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("locked_until", lockedUntil).Error; err != nil {
+		log.Printf("[login] failed to lock user %d: %v", user.ID, err)
+		return
+	}
+	user.FailedLoginCount = failures
+	user.LockedUntil = &lockedUntil
+
+	if h.config.LoginMaxFailedAttempts > 0 && failures >= h.config.LoginMaxFailedAttempts {
+		lockout := notification.AccountLockedEmail(user.Email, user.FullName, c.ClientIP(), lockedUntil)
+		if _, err := h.notif.Outbox.QueueEmail(lockout, "user", &user.ID); err != nil {
+			log.Printf("[login] failed to queue lockout email for user %d: %v", user.ID, err)
+		}
+	}
+}
+
+// registerLoginSuccess clears the failure counter and logs the successful sign-in.
+func (h *AuthHandler) registerLoginSuccess(c *gin.Context, user *models.User) {
+	h.recordLoginAttempt(c, user.Email, &user.ID, true, "")
+	if user.FailedLoginCount == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"failed_login_count": 0, "locked_until": nil}).Error; err != nil {
+		log.Printf("[login] failed to reset failures for user %d: %v", user.ID, err)
+	}
+	user.FailedLoginCount = 0
+	user.LockedUntil = nil
+}
+
+// respondLocked writes the throttling response: 423 for a lockout, 429 for a
+// short backoff or a blocked IP.
+func (h *AuthHandler) respondLocked(c *gin.Context, wait time.Duration, lockedOut bool) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	if lockedOut {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":       "Account is temporarily locked after too many failed login attempts",
+			"retry_after": seconds,
+		})
+		return
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, try again later",
+		"retry_after": seconds,
+	})
+}
+
+// checkLoginAllowed rejects the attempt while the account is backing off or locked.
+func (h *AuthHandler) checkLoginAllowed(c *gin.Context, user *models.User) bool {
+	wait, locked := accountLocked(user)
+	if !locked {
+		return true
+	}
+	h.recordLoginAttempt(c, user.Email, &user.ID, false, loginReasonLocked)
+	lockedOut := h.config.LoginMaxFailedAttempts > 0 && user.FailedLoginCount >= h.config.LoginMaxFailedAttempts
+	h.respondLocked(c, wait, lockedOut)
+	return false
+}
+
+// unknownEmailBackoff mirrors the account backoff for an email without an
+// account, counting its failures of the last maxLoginLockout, so the throttling
+// responses do not tell which emails exist.
+func (h *AuthHandler) unknownEmailBackoff(email string) (time.Duration, int, error) {
+	query := h.db.Model(&models.LoginAttempt{}).
+		Where("email = ? AND reason = ? AND created_at > ?", strings.ToLower(strings.TrimSpace(email)), loginReasonUnknownUser, time.Now().Add(-maxLoginLockout))
+
+	var failures int64
+	if err := query.Count(&failures).Error; err != nil {
+		return 0, 0, err
+	}
+	if failures == 0 {
+		return 0, 0, nil
+	}
+	var latest models.LoginAttempt
+	if err := query.Order("created_at DESC").First(&latest).Error; err != nil {
+		return 0, 0, err
+	}
+	return time.Until(latest.CreatedAt.Add(h.loginBackoff(int(failures)))), int(failures), nil
+}
+
+// checkUnknownEmailAllowed rejects the attempt while the unknown email is
+// backing off, answering exactly as checkLoginAllowed does for an account.
+func (h *AuthHandler) checkUnknownEmailAllowed(c *gin.Context, email string) bool {
+	wait, failures, err := h.unknownEmailBackoff(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	h.recordLoginAttempt(c, email, nil, false, loginReasonLocked)
+	lockedOut := h.config.LoginMaxFailedAttempts > 0 && failures >= h.config.LoginMaxFailedAttempts
+	h.respondLocked(c, wait, lockedOut)
+	return false
+}
+
+// UnlockUser clears a user's failed-login counter and lockout.
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	result := h.db.Model(&models.User{}).Where("id = ?", c.Param("id")).
+		UpdateColumns(map[string]interface{}{"failed_login_count": 0, "locked_until": nil})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unlock user",
+			"message": result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+var loginAttemptListSort = listSort{
+	table:       "login_attempts",
+	fields:      map[string]string{"id": "login_attempts.id", "created_at": "login_attempts.created_at"},
+	defaultSort: "created_at",
+	defaultDesc: true,
+}
+
+// ListLoginAttempts returns the login-attempt log, newest first.
+func (h *UserHandler) ListLoginAttempts(c *gin.Context) {
+	params, err := parseListParams(c, loginAttemptListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.LoginAttempt{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		query = query.Where("email = ?", strings.ToLower(email))
+	}
+	if ip := strings.TrimSpace(c.Query("ip_address")); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if success := c.Query("success"); success != "" {
+		value, err := strconv.ParseBool(success)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid success, use true or false"})
+			return
+		}
+		query = query.Where("success = ?", value)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseISOTime(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, use RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at >= ?", *t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseISOTime(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, use RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at <= ?", *t)
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login attempts"})
+		return
+	}
+
+	var attempts []models.LoginAttempt
+	if err := params.apply(query, loginAttemptListSort).Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login attempts"})
+		return
+	}
+	keep, meta := params.finish(c, total, len(attempts), func(i int) uint { return attempts[i].ID })
+
+	c.JSON(http.StatusOK, gin.H{"data": attempts[:keep], "meta": meta})
+}
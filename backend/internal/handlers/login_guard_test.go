@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestLoginThrottlingDoesNotRevealAccounts checks that wrong passwords for an
+// existing account and attempts for an unknown email get the same responses,
+// backoff included.
+func TestLoginThrottlingDoesNotRevealAccounts(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{JWTSecret: "test-secret", LoginMaxFailedAttempts: 5, LoginLockoutMinutes: 15}
+	h := NewAuthHandler(db, cfg, notification.NewNotificationService(cfg, db))
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	hash, err := utils.HashPassword("correct-password")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	mustCreate(t, db, &models.User{Email: "known@example.com", Password: hash, FullName: "Known", RoleID: role.ID, IsActive: true})
+
+	attempt := func(email string) testResponse {
+		return serve(router, http.MethodPost, "/auth/login", gin.H{"email": email, "password": "wrong-password"})
+	}
+	for i, want := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		known := attempt("known@example.com")
+		unknown := attempt("nobody@example.com")
+		if known.Code != want || unknown.Code != want {
+			t.Fatalf("attempt %d: expected %d for both emails, got %d (known) and %d (unknown)", i+1, want, known.Code, unknown.Code)
+		}
+	}
+
+	var failures int64
+	db.Model(&models.LoginAttempt{}).Where("email = ? AND reason = ?", "nobody@example.com", loginReasonUnknownUser).Count(&failures)
+	if failures != 1 {
+		t.Fatalf("throttled attempts must not count as failures, got %d", failures)
+	}
+}
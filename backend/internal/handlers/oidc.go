@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/oidc"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcLoginTTL  = 10 * time.Minute // time allowed at the identity provider
+	oidcTicketTTL = time.Minute      // time for the frontend to exchange its ticket
+
+	authProviderOIDC = "oidc"
+)
+
+// ssoError is a sign-in refusal; its value is passed to the frontend as sso_error.
+type ssoError string
+
+func (e ssoError) Error() string { return string(e) }
+
+const (
+	ssoErrDenied          ssoError = "denied"
+	ssoErrFailed          ssoError = "failed"
+	ssoErrEmailRequired   ssoError = "email_required"
+	ssoErrEmailUnverified ssoError = "email_unverified"
+	ssoErrEmailConflict   ssoError = "email_conflict"
+	ssoErrNotProvisioned  ssoError = "not_provisioned"
+	ssoErrNoRole          ssoError = "no_role"
+	ssoErrInactive        ssoError = "inactive"
+)
+
+// GetOIDCConfig tells the login page whether to show the SSO button.
+func (h *AuthHandler) GetOIDCConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"enabled":       h.oidc != nil,
+			"provider_name": h.config.OIDCProviderName,
+		},
+	})
+}
+
+// StartOIDCLogin redirects the browser to the identity provider using the
+// authorization-code flow with PKCE.
+func (h *AuthHandler) StartOIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not enabled"})
+		return
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+
+	authURL, err := h.oidc.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("[oidc] failed to build authorization URL: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	now := time.Now()
+	login := models.OIDCLogin{
+		StateHash:    hashSessionToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		RedirectPath: sanitizeRedirectPath(c.Query("redirect")),
+		IPAddress:    c.ClientIP(),
+		ExpiresAt:    now.Add(oidcLoginTTL),
+	}
+	if err := h.db.Create(&login).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+	// Abandoned attempts are only useful for a short while.
+	h.db.Where("expires_at < ?", now.Add(-24*time.Hour)).Delete(&models.OIDCLogin{})
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the code exchange, provisions or updates the user and
+// sends the browser back to the frontend with a one-time ticket.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not enabled"})
+		return
+	}
+
+	now := time.Now()
+	state := c.Query("state")
+	var login models.OIDCLogin
+	result := h.db.Model(&models.OIDCLogin{}).
+		Where("state_hash = ? AND callback_at IS NULL AND expires_at > ?", hashSessionToken(state), now).
+		Update("callback_at", now)
+	if state == "" || result.Error != nil || result.RowsAffected == 0 ||
+		h.db.Where("state_hash = ?", hashSessionToken(state)).First(&login).Error != nil {
+		h.redirectSSOError(c, ssoErrFailed)
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		log.Printf("[oidc] provider returned error %q: %s", providerError, c.Query("error_description"))
+		h.redirectSSOError(c, ssoErrDenied)
+		return
+	}
+
+	claims, err := h.oidc.Exchange(c.Request.Context(), c.Query("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("[oidc] code exchange failed: %v", err)
+		h.redirectSSOError(c, ssoErrFailed)
+		return
+	}
+
+	user, err := h.provisionOIDCUser(claims)
+	if err != nil {
+		var refusal ssoError
+		if !errors.As(err, &refusal) {
+			log.Printf("[oidc] failed to provision %s: %v", claims.Email, err)
+			refusal = ssoErrFailed
+		}
+		var userID *uint
+		if user != nil {
+			userID = &user.ID
+		}
+		h.recordLoginAttempt(c, claims.Email, userID, false, "sso_"+string(refusal))
+		h.redirectSSOError(c, refusal)
+		return
+	}
+
+	ticket, err := newRefreshToken()
+	if err != nil {
+		h.redirectSSOError(c, ssoErrFailed)
+		return
+	}
+	ticketHash := hashSessionToken(ticket)
+	ticketExpiry := time.Now().Add(oidcTicketTTL)
+	if err := h.db.Model(&login).Updates(map[string]interface{}{
+		"user_id":       user.ID,
+		"ticket_hash":   ticketHash,
+		"ticket_expiry": ticketExpiry,
+	}).Error; err != nil {
+		h.redirectSSOError(c, ssoErrFailed)
+		return
+	}
+
+	query := url.Values{"ticket": {ticket}, "redirect": {login.RedirectPath}}
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/auth/callback?%s", strings.TrimRight(h.config.FrontendURL, "/"), query.Encode()))
+}
+
+// ExchangeOIDCTicket trades the one-time ticket from the callback for a session,
+// or for a 2FA challenge when the account uses two-factor authentication.
+func (h *AuthHandler) ExchangeOIDCTicket(c *gin.Context) {
+	var req struct {
+		Ticket string `json:"ticket" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	ticketHash := hashSessionToken(req.Ticket)
+	result := h.db.Model(&models.OIDCLogin{}).
+		Where("ticket_hash = ? AND exchanged_at IS NULL AND ticket_expiry > ?", ticketHash, now).
+		Update("exchanged_at", now)
+	var login models.OIDCLogin
+	if result.Error != nil || result.RowsAffected == 0 ||
+		h.db.Where("ticket_hash = ?", ticketHash).First(&login).Error != nil || login.UserID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in ticket is invalid or expired"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, *login.UserID).Error; err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is not active"})
+		return
+	}
+
+	challenge, err := h.loginChallenge(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	h.respondLogin(c, user.ID, nil)
+}
+
+func (h *AuthHandler) redirectSSOError(c *gin.Context, refusal ssoError) {
+	target := fmt.Sprintf("%s/login?sso_error=%s", strings.TrimRight(h.config.FrontendURL, "/"), url.QueryEscape(string(refusal)))
+	c.Redirect(http.StatusFound, target)
+}
+
+// sanitizeRedirectPath only allows local paths, so the callback cannot be used
+// as an open redirect.
+func sanitizeRedirectPath(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") || strings.Contains(value, "\\") {
+		return "/"
+	}
+	return value
+}
+
+// provisionOIDCUser finds the user by subject, links an existing account by
+// email, or creates one. Role and warehouses follow the configured group
+// mappings on every sign-in. On refusal the matched user, if any, is returned
+// with the error.
+func (h *AuthHandler) provisionOIDCUser(claims *oidc.Claims) (*models.User, error) {
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return nil, ssoErrEmailRequired
+	}
+	roleName := h.mappedRoleName(claims.Groups)
+
+	var user models.User
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("external_subject = ?", claims.Subject).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		created := false
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = tx.Where("LOWER(email) = ?", email).First(&user).Error
+			switch {
+			case err == nil:
+				if user.ExternalSubject != nil && *user.ExternalSubject != claims.Subject {
+					return ssoErrEmailConflict
+				}
+				// Linking trusts the provider's word that the address is the
+				// user's own, so it has to say so explicitly.
+				if claims.EmailVerified == nil || !*claims.EmailVerified {
+					return ssoErrEmailUnverified
+				}
+				subject := claims.Subject
+				user.ExternalSubject = &subject
+				user.AuthProvider = authProviderOIDC
+				if err := tx.Model(&user).Updates(map[string]interface{}{
+					"external_subject": subject,
+					"auth_provider":    authProviderOIDC,
+				}).Error; err != nil {
+					return err
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if !h.config.OIDCAutoProvision {
+					return ssoErrNotProvisioned
+				}
+				if err := h.createOIDCUser(tx, &user, claims, email, roleName); err != nil {
+					return err
+				}
+				created = true
+			default:
+				return err
+			}
+		}
+
+		if !user.IsActive {
+			return ssoErrInactive
+		}
+
+		if !created {
+			updates := make(map[string]interface{})
+			if name := strings.TrimSpace(claims.Name); name != "" && name != user.FullName {
+				updates["full_name"] = name
+			}
+			if roleName != "" {
+				var role models.Role
+				if err := tx.Where("name = ?", roleName).First(&role).Error; err == nil {
+					if role.ID != user.RoleID {
+						updates["role_id"] = role.ID
+					}
+				} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+			}
+			if len(updates) > 0 {
+				if err := tx.Model(&user).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(h.config.OIDCWarehouseMapping) > 0 {
+			warehouseIDs, err := h.mappedWarehouseIDs(tx, claims.Groups)
+			if err != nil {
+				return err
+			}
+			if err := replaceUserWarehouses(tx, user.ID, warehouseIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if user.ID != 0 {
+			return &user, err
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (h *AuthHandler) createOIDCUser(tx *gorm.DB, user *models.User, claims *oidc.Claims, email, roleName string) error {
+	if roleName == "" {
+		roleName = h.config.OIDCDefaultRole
+	}
+	if roleName == "" {
+		return ssoErrNoRole
+	}
+	var role models.Role
+	if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ssoErrNoRole
+		}
+		return err
+	}
+
+	// SSO users sign in at the identity provider; the local password is random.
+	randomPassword, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = email
+	}
+	subject := claims.Subject
+	*user = models.User{
+		Email:           email,
+		Password:        hashedPassword,
+		FullName:        name,
+		RoleID:          role.ID,
+		IsActive:        true,
+		AuthProvider:    authProviderOIDC,
+		ExternalSubject: &subject,
+	}
+	return tx.Create(user).Error
+}
+
+// mappedRoleName returns the role of the first configured mapping whose group
+// the user belongs to.
+func (h *AuthHandler) mappedRoleName(groups []string) string {
+	member := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		member[group] = struct{}{}
+	}
+	for _, mapping := range h.config.OIDCRoleMapping {
+		if _, ok := member[mapping.Group]; ok {
+			return mapping.Values[0]
+		}
+	}
+	return ""
+}
+
+// mappedWarehouseIDs resolves the warehouse codes of every matching mapping;
+// "*" grants all warehouses.
+func (h *AuthHandler) mappedWarehouseIDs(tx *gorm.DB, groups []string) ([]uint, error) {
+	member := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		member[group] = struct{}{}
+	}
+	var codes []string
+	for _, mapping := range h.config.OIDCWarehouseMapping {
+		if _, ok := member[mapping.Group]; !ok {
+			continue
+		}
+		for _, code := range mapping.Values {
+			if code == "*" {
+				var ids []uint
+				err := tx.Model(&models.Warehouse{}).Pluck("id", &ids).Error
+				return ids, err
+			}
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	var ids []uint
+	err := tx.Model(&models.Warehouse{}).Where("code IN ?", codes).Pluck("id", &ids).Error
+	return ids, err
+}
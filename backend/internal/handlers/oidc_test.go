@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/models"
+	"tatapps/internal/services/oidc/oidctest"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const oidcTestFrontend = "http://frontend.test"
+
+// newOIDCRouter wires the SSO routes to a running mock provider.
+func newOIDCRouter(t *testing.T, db *gorm.DB) *gin.Engine {
+	t.Helper()
+	mock, err := oidctest.New("", "tatapps", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	mock.Issuer = server.URL
+
+	h := NewAuthHandler(db, &config.Config{
+		FrontendURL:       oidcTestFrontend,
+		OIDCEnabled:       true,
+		OIDCIssuerURL:     server.URL,
+		OIDCClientID:      "tatapps",
+		OIDCRedirectURL:   "http://api.test/api/v1/auth/oidc/callback",
+		OIDCScopes:        []string{"openid", "email", "profile"},
+		OIDCGroupsClaim:   "groups",
+		OIDCAutoProvision: true,
+	}, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/auth/oidc/login", h.StartOIDCLogin)
+	router.GET("/api/v1/auth/oidc/callback", h.OIDCCallback)
+	return router
+}
+
+// oidcSignIn runs the browser side of the flow: start, approve at the mock
+// provider with the given parameters, and return where the callback redirects.
+func oidcSignIn(t *testing.T, router *gin.Engine, params url.Values) *url.URL {
+	t.Helper()
+	start := httptest.NewRecorder()
+	router.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("login returned %d: %s", start.Code, start.Body.String())
+	}
+
+	authURL, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	authURL.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	approved, err := resp.Location()
+	if err != nil {
+		t.Fatalf("provider did not redirect back: %v", err)
+	}
+
+	callback := httptest.NewRecorder()
+	router.ServeHTTP(callback, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+approved.RawQuery, nil))
+	if callback.Code != http.StatusFound {
+		t.Fatalf("callback returned %d: %s", callback.Code, callback.Body.String())
+	}
+	target, err := url.Parse(callback.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+func TestOIDCLinkRequiresVerifiedEmail(t *testing.T) {
+	db := newTestDB(t)
+	router := newOIDCRouter(t, db)
+
+	role := models.Role{Name: "employee"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "jane@example.com", Password: "x", FullName: "Jane", RoleID: role.ID, IsActive: true, AuthProvider: "local"}
+	mustCreate(t, db, &user)
+
+	for _, verified := range []string{oidctest.EmailNotAsserted, oidctest.EmailUnverified} {
+		target := oidcSignIn(t, router, url.Values{"login_hint": {"jane@example.com"}, "email_verified": {verified}})
+		if !strings.HasPrefix(target.String(), oidcTestFrontend+"/login") || target.Query().Get("sso_error") != string(ssoErrEmailUnverified) {
+			t.Fatalf("email_verified=%s: expected email_unverified refusal, got %s", verified, target)
+		}
+		var reloaded models.User
+		db.First(&reloaded, user.ID)
+		if reloaded.ExternalSubject != nil || reloaded.AuthProvider != "local" {
+			t.Fatalf("email_verified=%s: account was linked", verified)
+		}
+	}
+
+	target := oidcSignIn(t, router, url.Values{"login_hint": {"jane@example.com"}})
+	if !strings.HasPrefix(target.String(), oidcTestFrontend+"/auth/callback") || target.Query().Get("ticket") == "" {
+		t.Fatalf("expected a ticket for a verified email, got %s", target)
+	}
+	var linked models.User
+	db.First(&linked, user.ID)
+	if linked.ExternalSubject == nil || linked.AuthProvider != authProviderOIDC {
+		t.Fatal("verified email did not link the account")
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OutboxHandler lets admins inspect outgoing email and WhatsApp messages and
+// requeue the ones that failed.
+type OutboxHandler struct {
+	db     *gorm.DB
+	outbox *notification.OutboxService
+}
+
+func NewOutboxHandler(db *gorm.DB, outbox *notification.OutboxService) *OutboxHandler {
+	return &OutboxHandler{db: db, outbox: outbox}
+}
+
+var outboxListSort = listSort{
+	table:       "notifications",
+	fields:      map[string]string{"id": "notifications.id", "created_at": "notifications.created_at"},
+	defaultSort: "created_at",
+	defaultDesc: true,
+}
+
+func (h *OutboxHandler) outboxQuery() *gorm.DB {
+	return h.db.Model(&models.Notification{}).
+		Where("type IN ?", []string{notification.TypeEmail, notification.TypeWhatsApp})
+}
+
+// ListOutbox returns outgoing messages, newest first.
+func (h *OutboxHandler) ListOutbox(c *gin.Context) {
+	params, err := parseListParams(c, outboxListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.outboxQuery()
+	switch status := c.Query("status"); status {
+	case "":
+	case notification.StatusPending, notification.StatusSent, notification.StatusFailed:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use pending, sent or failed"})
+		return
+	}
+	switch channel := c.Query("channel"); channel {
+	case "":
+	case notification.TypeEmail, notification.TypeWhatsApp:
+		query = query.Where("type = ?", channel)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel, use email or whatsapp"})
+		return
+	}
+	if module := strings.TrimSpace(c.Query("module")); module != "" {
+		query = query.Where("module = ?", module)
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("(email ILIKE ? OR phone ILIKE ? OR subject ILIKE ?)", like, like, like)
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outgoing messages"})
+		return
+	}
+
+	var rows []models.Notification
+	if err := params.apply(query, outboxListSort).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch outgoing messages",
+			"message": err.Error(),
+		})
+		return
+	}
+	keep, meta := params.finish(c, total, len(rows), func(i int) uint { return rows[i].ID })
+	for i := range rows[:keep] {
+		notification.Redact(&rows[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rows[:keep], "meta": meta})
+}
+
+// RequeueMessage sends a failed message again with a fresh set of retries.
+func (h *OutboxHandler) RequeueMessage(c *gin.Context) {
+	var row models.Notification
+	if err := h.outboxQuery().First(&row, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message"})
+		return
+	}
+	if row.Status != notification.StatusFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only failed messages can be requeued"})
+		return
+	}
+
+	if _, err := h.outbox.Requeue(h.db.Model(&models.Notification{}).Where("id = ?", row.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to requeue message",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Message queued"})
+}
+
+// RequeueFailed requeues every failed message, optionally limited by ?channel.
+func (h *OutboxHandler) RequeueFailed(c *gin.Context) {
+	query := h.db.Model(&models.Notification{})
+	switch channel := c.Query("channel"); channel {
+	case "":
+	case notification.TypeEmail, notification.TypeWhatsApp:
+		query = query.Where("type = ?", channel)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel, use email or whatsapp"})
+		return
+	}
+
+	count, err := h.outbox.Requeue(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to requeue messages",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Failed messages queued",
+		"requeued": count,
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOutboxListRedactsSecrets(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{JWTSecret: "test-secret", PasswordResetTTL: 15, FrontendURL: "https://app.example.com"}
+	notif := notification.NewNotificationService(cfg, db)
+	user := models.User{Email: "staff@example.com", Phone: "08123456789", Password: "x", FullName: "Staff", IsActive: true}
+	mustCreate(t, db, &user)
+
+	router := gin.New()
+	router.POST("/auth/forgot-password", NewAuthHandler(db, cfg, notif).ForgotPassword)
+	router.GET("/outbox", NewOutboxHandler(db, notif.Outbox).ListOutbox)
+
+	for _, channel := range []string{"email", "whatsapp"} {
+		resp := serve(router, http.MethodPost, "/auth/forgot-password", gin.H{"email": user.Email, "channel": channel})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d: %s", channel, resp.Code, resp.Body)
+		}
+	}
+
+	var queued []models.Notification
+	db.Order("id").Find(&queued)
+	if len(queued) != 2 || queued[0].Type != notification.TypeEmail || queued[1].Type != notification.TypeWhatsApp {
+		t.Fatalf("expected the email and WhatsApp resets in the outbox, got %+v", queued)
+	}
+	if !strings.Contains(queued[0].Message, "/reset-password?token=") {
+		t.Fatalf("expected the stored email to keep the reset link for delivery")
+	}
+
+	var page struct {
+		Data []models.Notification `json:"data"`
+	}
+	resp := serve(router, http.MethodGet, "/outbox", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	resp.decode(t, &page)
+	if len(page.Data) != 2 {
+		t.Fatalf("expected 2 listed messages, got %d", len(page.Data))
+	}
+	for _, row := range page.Data {
+		if row.Message != notification.RedactedMessage {
+			t.Fatalf("expected the %s body to be redacted, got %q", row.Type, row.Message)
+		}
+	}
+
+	// Requeueing a failed reset keeps the code out of the audit log.
+	code := regexp.MustCompile(`\*(\d{6})\*`).FindStringSubmatch(queued[1].Message)[1]
+	db.Model(&queued[1]).Updates(map[string]interface{}{"status": notification.StatusFailed})
+	router.POST("/outbox/:id/requeue", middleware.AuditAction(db, AuditOutboxMsg, "requeue"), NewOutboxHandler(db, notif.Outbox).RequeueMessage)
+	if resp := serve(router, http.MethodPost, fmt.Sprintf("/outbox/%d/requeue", queued[1].ID), nil); resp.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", resp.Code, resp.Body)
+	}
+	var entry models.AuditLog
+	if err := db.Where("entity_type = ? AND action = ?", "notification", "requeue").First(&entry).Error; err != nil {
+		t.Fatalf("expected an audit entry for the requeue: %v", err)
+	}
+	if strings.Contains(string(entry.Before)+string(entry.After), code) || !strings.Contains(string(entry.After), notification.RedactedMessage) {
+		t.Fatalf("expected the audited message to be redacted, got before=%s after=%s", entry.Before, entry.After)
+	}
+}
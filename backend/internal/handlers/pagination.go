@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// listSort whitelists the fields a list endpoint can be ordered by.
+type listSort struct {
+	table       string            // table the rows come from; used for cursor lookups
+	fields      map[string]string // query name -> column
+	defaultSort string
+	defaultDesc bool
+}
+
+func (s listSort) names() string {
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// listParams is the parsed page, page_size, cursor, sort and order of a list request.
+// Every request is paged; without page_size it gets defaultPageSize rows.
+type listParams struct {
+	sortName string
+	column   string
+	desc     bool
+
+	page      int
+	pageSize  int
+	cursor    uint
+	hasCursor bool
+}
+
+// listMeta describes the returned page; it is sent in the body and as X-* headers.
+type listMeta struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+	Order      string `json:"order"`
+}
+
+func parseListParams(c *gin.Context, spec listSort) (listParams, error) {
+	params := listParams{sortName: spec.defaultSort, desc: spec.defaultDesc}
+
+	if name := strings.TrimSpace(c.Query("sort")); name != "" {
+		params.sortName = strings.ToLower(name)
+	}
+	column, ok := spec.fields[params.sortName]
+	if !ok {
+		return params, fmt.Errorf("Invalid sort field, use one of: %s", spec.names())
+	}
+	params.column = column
+
+	switch strings.ToLower(strings.TrimSpace(c.Query("order"))) {
+	case "":
+	case "asc":
+		params.desc = false
+	case "desc":
+		params.desc = true
+	default:
+		return params, fmt.Errorf("Invalid order, use asc or desc")
+	}
+
+	params.page = 1
+	params.pageSize = defaultPageSize
+	if value := strings.TrimSpace(c.Query("page_size")); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return params, fmt.Errorf("Invalid page_size")
+		}
+		if size > maxPageSize {
+			size = maxPageSize
+		}
+		params.pageSize = size
+	}
+	if value := strings.TrimSpace(c.Query("page")); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return params, fmt.Errorf("Invalid page")
+		}
+		params.page = page
+	}
+	if value := strings.TrimSpace(c.Query("cursor")); value != "" {
+		id, err := decodeListCursor(value)
+		if err != nil {
+			return params, fmt.Errorf("Invalid cursor")
+		}
+		params.cursor = id
+		params.hasCursor = true
+	}
+	return params, nil
+}
+
+func encodeListCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeListCursor(value string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return uint(id), nil
+}
+
+// countList counts the rows matched by query; call it before adding preloads.
+func countList(query *gorm.DB) (int64, error) {
+	var total int64
+	err := query.Session(&gorm.Session{}).Count(&total).Error
+	return total, err
+}
+
+// apply adds ordering and the offset or keyset predicate of the page.
+// Cursor pages fetch one extra row so finish can tell whether another page exists.
+func (p listParams) apply(query *gorm.DB, spec listSort) *gorm.DB {
+	idColumn := spec.table + ".id"
+	direction := "ASC"
+	comparator := ">"
+	if p.desc {
+		direction = "DESC"
+		comparator = "<"
+	}
+
+	query = query.Session(&gorm.Session{})
+	if p.column == idColumn {
+		query = query.Order(fmt.Sprintf("%s %s", idColumn, direction))
+	} else {
+		query = query.Order(fmt.Sprintf("%s %s, %s %s", p.column, direction, idColumn, direction))
+	}
+
+	if p.hasCursor {
+		if p.column == idColumn {
+			query = query.Where(fmt.Sprintf("%s %s ?", idColumn, comparator), p.cursor)
+		} else {
+			// Compare against the cursor row's sort value, breaking ties on ID.
+			anchor := fmt.Sprintf("(SELECT %s FROM %s WHERE %s = ?)", p.column, spec.table, idColumn)
+			query = query.Where(
+				fmt.Sprintf("(%s %s %s OR (%s = %s AND %s %s ?))", p.column, comparator, anchor, p.column, anchor, idColumn, comparator),
+				p.cursor, p.cursor, p.cursor,
+			)
+		}
+		return query.Limit(p.pageSize + 1)
+	}
+
+	return query.Offset((p.page - 1) * p.pageSize).Limit(p.pageSize)
+}
+
+// finish builds the page metadata, sets the X-* headers and returns how many of
+// the fetched rows belong to the page. lastID returns the ID of row i.
+func (p listParams) finish(c *gin.Context, total int64, fetched int, lastID func(int) uint) (int, listMeta) {
+	meta := listMeta{Total: total, Sort: p.sortName, Order: "asc"}
+	if p.desc {
+		meta.Order = "desc"
+	}
+	keep := fetched
+
+	meta.PageSize = p.pageSize
+	if p.hasCursor {
+		if fetched > p.pageSize {
+			keep = p.pageSize
+			meta.NextCursor = encodeListCursor(lastID(keep - 1))
+		}
+	} else {
+		meta.Page = p.page
+		meta.TotalPages = int(math.Ceil(float64(total) / float64(p.pageSize)))
+		if int64(p.page*p.pageSize) < total && fetched > 0 {
+			meta.NextCursor = encodeListCursor(lastID(fetched - 1))
+		}
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if meta.Page > 0 {
+		c.Header("X-Page", strconv.Itoa(meta.Page))
+		c.Header("X-Total-Pages", strconv.Itoa(meta.TotalPages))
+	}
+	if meta.PageSize > 0 {
+		c.Header("X-Page-Size", strconv.Itoa(meta.PageSize))
+	}
+	if meta.NextCursor != "" {
+		c.Header("X-Next-Cursor", meta.NextCursor)
+	}
+	return keep, meta
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListsAreAlwaysPaged(t *testing.T) {
+	db := newTestDB(t)
+	user := models.User{Email: "buyer@example.com", Password: "x", FullName: "Buyer", IsActive: true}
+	mustCreate(t, db, &user)
+	for i := 1; i <= defaultPageSize+5; i++ {
+		mustCreate(t, db, &models.PurchaseOrder{
+			PONumber: fmt.Sprintf("PO-%03d", i), PODate: time.Now(), SupplierName: "Supplier", RequestedByID: user.ID,
+		})
+	}
+
+	router := gin.New()
+	router.GET("/purchase-orders", NewPOHandler(db, nil, nil).GetAll)
+
+	type page struct {
+		Data []models.PurchaseOrder `json:"data"`
+		Meta listMeta               `json:"meta"`
+	}
+
+	var first page
+	resp := serve(router, http.MethodGet, "/purchase-orders", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	resp.decode(t, &first)
+	if len(first.Data) != defaultPageSize || first.Meta.Total != int64(defaultPageSize+5) || first.Meta.NextCursor == "" {
+		t.Fatalf("expected the default page of %d with a next cursor, got %d rows and %+v", defaultPageSize, len(first.Data), first.Meta)
+	}
+
+	var rest page
+	serve(router, http.MethodGet, "/purchase-orders?cursor="+first.Meta.NextCursor, nil).decode(t, &rest)
+	if len(rest.Data) != 5 || rest.Meta.NextCursor != "" || rest.Data[0].PONumber != fmt.Sprintf("PO-%03d", defaultPageSize+1) {
+		t.Fatalf("unexpected second page: %d rows, %+v", len(rest.Data), rest.Meta)
+	}
+
+	var capped page
+	serve(router, http.MethodGet, "/purchase-orders?page_size=100000", nil).decode(t, &capped)
+	if capped.Meta.PageSize != maxPageSize {
+		t.Fatalf("expected page_size capped at %d, got %d", maxPageSize, capped.Meta.PageSize)
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	resetChannelEmail    = "email"
+	resetChannelWhatsApp = "whatsapp"
+
+	// maxResetRequestsPerHour caps reset messages per account, on top of the per-IP route limit.
+	maxResetRequestsPerHour = 3
+	// maxResetCodeAttempts invalidates a WhatsApp code after this many wrong guesses.
+	maxResetCodeAttempts = 5
+)
+
+var errResetUnavailable = errors.New("reset token or code is invalid or expired")
+
+type forgotPasswordRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	Channel string `json:"channel"` // email (default) or whatsapp
+}
+
+// resetPasswordRequest accepts either the emailed token or the email plus WhatsApp code.
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	Email       string `json:"email"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func newResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// resetCodeHash binds a code to its user so equal codes of different users differ.
+func resetCodeHash(userID uint, code string) string {
+	return hashSessionToken(fmt.Sprintf("%d:%s", userID, code))
+}
+
+// ForgotPassword sends a reset link or code. The response is the same whether or
+// not the account exists, and delivery happens in the background.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	channel := strings.ToLower(strings.TrimSpace(req.Channel))
+	if channel == "" {
+		channel = resetChannelEmail
+	}
+	if channel != resetChannelEmail && channel != resetChannelWhatsApp {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel, use email or whatsapp"})
+		return
+	}
+
+	if err := h.issuePasswordReset(c, strings.TrimSpace(req.Email), channel); err != nil {
+		log.Printf("[password-reset] failed to issue reset: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If the account exists, password reset instructions have been sent",
+	})
+}
+
+func (h *AuthHandler) issuePasswordReset(c *gin.Context, email, channel string) error {
+	var user models.User
+	if err := h.db.Where("LOWER(email) = LOWER(?) AND is_active = ?", email, true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if channel == resetChannelWhatsApp && strings.TrimSpace(user.Phone) == "" {
+		return nil
+	}
+
+	var recent int64
+	if err := h.db.Model(&models.PasswordReset{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent >= maxResetRequestsPerHour {
+		return nil
+	}
+
+	var secret, hash string
+	if channel == resetChannelEmail {
+		token, err := newRefreshToken()
+		if err != nil {
+			return err
+		}
+		secret, hash = token, hashSessionToken(token)
+	} else {
+		code, err := newResetCode()
+		if err != nil {
+			return err
+		}
+		secret, hash = code, resetCodeHash(user.ID, code)
+	}
+
+	now := time.Now()
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		Channel:   channel,
+		TokenHash: hash,
+		RequestIP: c.ClientIP(),
+		ExpiresAt: now.Add(time.Duration(h.config.PasswordResetTTL) * time.Minute),
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Only the newest reset of an account stays usable.
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if channel == resetChannelEmail {
+		link := fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(h.config.FrontendURL, "/"), url.QueryEscape(secret))
+		_, err = h.notif.Outbox.QueueEmail(notification.PasswordResetEmail(user.Email, user.FullName, link, reset.ExpiresAt), "user", &user.ID)
+	} else {
+		_, err = h.notif.Outbox.QueueWhatsApp(notification.PasswordResetCodeMessage(user.Phone, secret, h.config.PasswordResetTTL), "user", &user.ID)
+	}
+	return err
+}
+
+// ResetPassword sets a new password from a reset token or code and signs out every session.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Token) == "" && (strings.TrimSpace(req.Email) == "" || strings.TrimSpace(req.Code) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide a reset token, or an email and code"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var userID uint
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		reset, err := h.findPasswordReset(tx, req)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetUnavailable
+		}
+		// A completed reset proves control of the account, so it also lifts a lockout.
+		if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Updates(map[string]interface{}{
+			"password":           hashedPassword,
+			"failed_login_count": 0,
+			"locked_until":       nil,
+		}).Error; err != nil {
+			return err
+		}
+		userID = reset.UserID
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errResetUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reset token or code is invalid or expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reset password",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := revokeUserSessions(h.db, userID, sessionRevokedPasswordChanged, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
+
+func (h *AuthHandler) findPasswordReset(tx *gorm.DB, req resetPasswordRequest) (*models.PasswordReset, error) {
+	now := time.Now()
+	var reset models.PasswordReset
+
+	if token := strings.TrimSpace(req.Token); token != "" {
+		if err := tx.Where("token_hash = ? AND channel = ? AND used_at IS NULL AND expires_at > ?", hashSessionToken(token), resetChannelEmail, now).
+			First(&reset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errResetUnavailable
+			}
+			return nil, err
+		}
+		return &reset, nil
+	}
+
+	var user models.User
+	if err := tx.Select("id").Where("LOWER(email) = LOWER(?) AND is_active = ?", strings.TrimSpace(req.Email), true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errResetUnavailable
+		}
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND channel = ? AND used_at IS NULL AND expires_at > ?", user.ID, resetChannelWhatsApp, now).
+		Order("created_at DESC").
+		First(&reset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errResetUnavailable
+		}
+		return nil, err
+	}
+
+	if reset.TokenHash != resetCodeHash(user.ID, strings.TrimSpace(req.Code)) {
+		updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1")}
+		if reset.Attempts+1 >= maxResetCodeAttempts {
+			updates["expires_at"] = now
+		}
+		// Record the failed attempt outside the rolled-back transaction.
+		if err := h.db.Model(&models.PasswordReset{}).Where("id = ?", reset.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		return nil, errResetUnavailable
+	}
+	return &reset, nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"testing"
+
+	"tatapps/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func rolePermissionNames(db *gorm.DB, roleID uint) []string {
+	var names []string
+	db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleID).
+		Pluck("permissions.name", &names)
+	return names
+}
+
+func TestSyncPermissionsMigratesEveryUpgradePath(t *testing.T) {
+	paths := map[string][]string{
+		// Straight from the release where routes checked role names.
+		"baseline": {"inventory.view"},
+		// Through a build that already had role.manage and notification.send.
+		"intermediate": {"inventory.view", "role.manage", notificationSendPermission},
+	}
+	for name, existing := range paths {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t)
+			admin, manager, clerk := models.Role{Name: "admin"}, models.Role{Name: "manager"}, models.Role{Name: "clerk"}
+			mustCreate(t, db, &admin, &manager, &clerk)
+			grantPermissions(t, db, &clerk, existing...)
+
+			if err := SyncPermissions(db); err != nil {
+				t.Fatalf("sync permissions: %v", err)
+			}
+
+			for _, role := range []models.Role{admin, manager, clerk} {
+				granted := rolePermissionNames(db, role.ID)
+				for _, key := range openRouteGrants {
+					if !containsString(granted, key) {
+						t.Fatalf("expected %s to keep %s, got %v", role.Name, key, granted)
+					}
+				}
+			}
+			if granted := rolePermissionNames(db, manager.ID); !containsString(granted, "po.approve") {
+				t.Fatalf("expected manager to keep po.approve, got %v", granted)
+			}
+			if granted := rolePermissionNames(db, admin.ID); len(granted) != len(permissionDefinitions) {
+				t.Fatalf("expected admin to hold all %d permissions, got %d", len(permissionDefinitions), len(granted))
+			}
+
+			// Later starts do not grant again, so roles created or trimmed afterwards keep their access.
+			auditor := models.Role{Name: "auditor"}
+			mustCreate(t, db, &auditor)
+			if err := db.Model(&clerk).Association("Permissions").Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if err := SyncPermissions(db); err != nil {
+				t.Fatalf("second sync: %v", err)
+			}
+			if granted := append(rolePermissionNames(db, auditor.ID), rolePermissionNames(db, clerk.ID)...); len(granted) != 0 {
+				t.Fatalf("expected no grants after the migrations ran, got %v", granted)
+			}
+		})
+	}
+}
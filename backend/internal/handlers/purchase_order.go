@@ -3,7 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/services/webhook"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,28 +14,66 @@ import (
 )
 
 type POHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	webhooks *webhook.Dispatcher
+	inbox    *notification.InboxService
 }
 
-func NewPOHandler(db *gorm.DB) *POHandler {
-	return &POHandler{db: db}
+func NewPOHandler(db *gorm.DB, webhooks *webhook.Dispatcher, inbox *notification.InboxService) *POHandler {
+	return &POHandler{db: db, webhooks: webhooks, inbox: inbox}
 }
 
+var poListSort = listSort{
+	table: "purchase_orders",
+	fields: map[string]string{
+		"id":            "purchase_orders.id",
+		"po_number":     "purchase_orders.po_number",
+		"po_date":       "purchase_orders.po_date",
+		"supplier_name": "purchase_orders.supplier_name",
+		"status":        "purchase_orders.status",
+		"priority":      "purchase_orders.priority",
+		"total_amount":  "purchase_orders.total_amount",
+		"created_at":    "purchase_orders.created_at",
+	},
+	defaultSort: "id",
+}
+
+// GetAll lists purchase orders a page at a time.
 func (h *POHandler) GetAll(c *gin.Context) {
-	var pos []models.PurchaseOrder
-	query := h.db.Preload("RequestedBy").Preload("ApprovedBy").Preload("Project").Preload("Warehouse").Preload("Items")
+	params, err := parseListParams(c, poListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.PurchaseOrder{})
 
 	// Filter by status if provided
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status = ?", status)
 	}
+	if supplier := strings.TrimSpace(c.Query("supplier")); supplier != "" {
+		query = query.Where("supplier_name ILIKE ?", "%"+supplier+"%")
+	}
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
 
-	if err := query.Find(&pos).Error; err != nil {
+	total, err := countList(query)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, pos)
+	var pos []models.PurchaseOrder
+	query = query.Preload("RequestedBy").Preload("ApprovedBy").Preload("Project").Preload("Warehouse").Preload("Items")
+	if err := params.apply(query, poListSort).Find(&pos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	keep, meta := params.finish(c, total, len(pos), func(i int) uint { return pos[i].ID })
+
+	c.JSON(http.StatusOK, gin.H{"data": pos[:keep], "meta": meta})
 }
 
 func (h *POHandler) GetByID(c *gin.Context) {
@@ -59,6 +100,11 @@ func (h *POHandler) Create(c *gin.Context) {
 	po.RequestedByID = userID
 	po.Status = "draft"
 
+	if err := normalizePOItems(h.db, po.Items); err != nil {
+		respondError(c, err, "Failed to save purchase order")
+		return
+	}
+
 	// Calculate totals
 	var subtotal float64
 	for i := range po.Items {
@@ -75,6 +121,8 @@ func (h *POHandler) Create(c *gin.Context) {
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventPOCreated, webhookPOData(po, ""))
+
 	c.JSON(http.StatusCreated, po)
 }
 
@@ -86,17 +134,28 @@ func (h *POHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Purchase order not found"})
 		return
 	}
+	previousStatus := po.Status
 
 	if err := c.ShouldBindJSON(&po); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := normalizePOItems(h.db, po.Items); err != nil {
+		respondError(c, err, "Failed to save purchase order")
+		return
+	}
+
 	if err := h.db.Save(&po).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventPOUpdated, webhookPOData(po, previousStatus))
+	if po.Status == "pending" && previousStatus != "pending" {
+		notifyPOSubmitted(h.inbox, po, c.GetUint("user_id"))
+	}
+
 	c.JSON(http.StatusOK, po)
 }
 
@@ -125,6 +184,9 @@ func (h *POHandler) Approve(c *gin.Context) {
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventPOApproved, webhookPOData(po, "pending"))
+	notifyPODecision(h.inbox, po)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Purchase order approved successfully",
 		"po":      po,
@@ -148,6 +210,7 @@ func (h *POHandler) Reject(c *gin.Context) {
 		return
 	}
 
+	previousStatus := po.Status
 	po.Status = "rejected"
 	po.RejectionReason = req.Reason
 
@@ -156,6 +219,9 @@ func (h *POHandler) Reject(c *gin.Context) {
 		return
 	}
 
+	h.webhooks.Publish(webhook.EventPORejected, webhookPOData(po, previousStatus))
+	notifyPODecision(h.inbox, po)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Purchase order rejected",
 		"po":      po,
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"sort"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stream events are published after commit and reach clients whose warehouse
+// scope covers one of the affected warehouses.
+
+// stockEventWarehouses lists the item's warehouse and both transfer ends.
+func stockEventWarehouses(transaction models.InventoryTransaction, item models.InventoryItem) []uint {
+	ids := map[uint]struct{}{}
+	for _, id := range []*uint{&item.WarehouseID, transaction.FromWarehouseID, transaction.ToWarehouseID} {
+		if id != nil && *id != 0 {
+			ids[*id] = struct{}{}
+		}
+	}
+	return sortedIDs(ids)
+}
+
+func sortedIDs(set map[uint]struct{}) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func streamLowStock(hub *realtime.Hub, item models.InventoryItem) {
+	hub.Publish(realtime.EventStockLow, gin.H{"item": webhookItemData(item)},
+		realtime.Target{WarehouseIDs: []uint{item.WarehouseID}})
+}
+
+// streamImport announces a committed import and the items it took to their
+// minimum stock.
+func streamImport(hub *realtime.Hub, summary importSummary) {
+	if len(summary.warehouses) == 0 {
+		return
+	}
+	hub.Publish(realtime.EventStockImport, gin.H{
+		"inserted":      summary.Inserted,
+		"updated":       summary.Updated,
+		"warehouse_ids": sortedIDs(summary.warehouses),
+	}, realtime.Target{WarehouseIDs: sortedIDs(summary.warehouses)})
+	for _, item := range summary.lowStock {
+		streamLowStock(hub, item)
+	}
+}
+
+// streamItemsDeleted sends one event per warehouse with the deleted item IDs.
+func streamItemsDeleted(hub *realtime.Hub, items []models.InventoryItem) {
+	byWarehouse := map[uint][]uint{}
+	for _, item := range items {
+		byWarehouse[item.WarehouseID] = append(byWarehouse[item.WarehouseID], item.ID)
+	}
+	for warehouseID, itemIDs := range byWarehouse {
+		hub.Publish(realtime.EventItemDeleted, gin.H{
+			"warehouse_id": warehouseID,
+			"item_ids":     itemIDs,
+		}, realtime.Target{WarehouseIDs: []uint{warehouseID}})
+	}
+}
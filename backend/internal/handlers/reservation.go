@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	reservationStatusActive    = "active"
+	reservationStatusFulfilled = "fulfilled"
+	reservationStatusCancelled = "cancelled"
+)
+
+type ReservationHandler struct {
+	db *gorm.DB
+}
+
+func NewReservationHandler(db *gorm.DB) *ReservationHandler {
+	return &ReservationHandler{db: db}
+}
+
+type createReservationRequest struct {
+	ItemID    uint       `json:"item_id" binding:"required"`
+	ProjectID *uint      `json:"project_id"`
+	Reference string     `json:"reference"`
+	Quantity  float64    `json:"quantity" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Notes     string     `json:"notes"`
+}
+
+type updateReservationRequest struct {
+	Quantity  *float64   `json:"quantity"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Notes     *string    `json:"notes"`
+}
+
+// activeReservationScope limits a query to reservations that still hold stock.
+func activeReservationScope(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", reservationStatusActive).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+// reservedQuantities sums the outstanding reserved quantity per item.
+func reservedQuantities(db *gorm.DB, itemIDs []uint) (map[uint]float64, error) {
+	result := make(map[uint]float64, len(itemIDs))
+	if len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	type row struct {
+		ItemID   uint
+		Reserved float64
+	}
+	var rows []row
+	if err := db.Model(&models.StockReservation{}).
+		Scopes(activeReservationScope).
+		Where("item_id IN ?", itemIDs).
+		Select("item_id, COALESCE(SUM(quantity - fulfilled_quantity), 0) AS reserved").
+		Group("item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.ItemID] = r.Reserved
+	}
+	return result, nil
+}
+
+// attachReservedQuantities fills the computed reserved/available fields of the given items.
+func attachReservedQuantities(db *gorm.DB, items []models.InventoryItem) error {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	reserved, err := reservedQuantities(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].ReservedQuantity = reserved[items[i].ID]
+		items[i].AvailableQuantity = items[i].Quantity - items[i].ReservedQuantity
+	}
+	return nil
+}
+
+// attachReservedQuantity is the single-item variant of attachReservedQuantities.
+func attachReservedQuantity(db *gorm.DB, item *models.InventoryItem) error {
+	reserved, err := reservedQuantities(db, []uint{item.ID})
+	if err != nil {
+		return err
+	}
+	item.ReservedQuantity = reserved[item.ID]
+	item.AvailableQuantity = item.Quantity - item.ReservedQuantity
+	return nil
+}
+
+// consumeReservation books an outbound quantity against a reservation inside tx.
+func consumeReservation(tx *gorm.DB, reservationID, itemID uint, quantity float64) error {
+	var reservation models.StockReservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, reservationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newNotFoundError("Reservation %d not found", reservationID)
+		}
+		return err
+	}
+	if reservation.ItemID != itemID {
+		return newValidationError("Reservation %d belongs to a different item", reservationID)
+	}
+	if reservation.Status != reservationStatusActive {
+		return newValidationError("Reservation %d is %s", reservationID, reservation.Status)
+	}
+	if reservation.ExpiresAt != nil && reservation.ExpiresAt.Before(time.Now()) {
+		return newValidationError("Reservation %d has expired", reservationID)
+	}
+	if reservation.Remaining() < quantity {
+		return newValidationError("Reservation %d only has %s remaining", reservationID, formatFloat(reservation.Remaining()))
+	}
+
+	reservation.FulfilledQuantity += quantity
+	if reservation.Remaining() <= 0 {
+		reservation.Status = reservationStatusFulfilled
+	}
+	return tx.Save(&reservation).Error
+}
+
+// releaseReservation reverses consumeReservation when an outbound transaction is deleted.
+func releaseReservation(tx *gorm.DB, reservationID uint, quantity float64) error {
+	var reservation models.StockReservation
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, reservationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	reservation.FulfilledQuantity -= quantity
+	if reservation.FulfilledQuantity < 0 {
+		reservation.FulfilledQuantity = 0
+	}
+	if reservation.Status == reservationStatusFulfilled && reservation.Remaining() > 0 {
+		reservation.Status = reservationStatusActive
+	}
+	return tx.Unscoped().Save(&reservation).Error
+}
+
+// ensureUnreservedStock rejects a reduction of the item's stock by quantity that
+// would eat into stock held by reservations. Every path that lowers on-hand stock
+// without consuming a reservation calls it.
+func ensureUnreservedStock(tx *gorm.DB, item *models.InventoryItem, quantity float64) error {
+	reserved, err := reservedQuantities(tx, []uint{item.ID})
+	if err != nil {
+		return err
+	}
+	available := item.Quantity - reserved[item.ID]
+	if available < quantity {
+		return newValidationError("Insufficient available stock: %s %s reserved, %s available; reference the reservation to issue reserved stock",
+			formatFloat(reserved[item.ID]), item.Unit, formatFloat(available))
+	}
+	return nil
+}
+
+// ListReservations returns reservations filtered by item, project, status or reference.
+func (h *ReservationHandler) ListReservations(c *gin.Context) {
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	query := scope.reservations(h.db.Preload("Item").Preload("Item.Warehouse").Preload("Project").Preload("CreatedBy"))
+
+	if itemID := strings.TrimSpace(c.Query("item_id")); itemID != "" {
+		query = query.Where("item_id = ?", itemID)
+	}
+	if projectID := strings.TrimSpace(c.Query("project_id")); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if reference := strings.TrimSpace(c.Query("reference")); reference != "" {
+		query = query.Where("reference ILIKE ?", "%"+reference+"%")
+	}
+
+	var reservations []models.StockReservation
+	if err := query.Order("created_at DESC").Find(&reservations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch reservations",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": reservations})
+}
+
+// CreateReservation reserves available stock for a project or document.
+func (h *ReservationHandler) CreateReservation(c *gin.Context) {
+	var req createReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	req.Reference = strings.TrimSpace(req.Reference)
+	if req.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than zero"})
+		return
+	}
+	if req.ProjectID == nil && req.Reference == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A project or document reference is required"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	if req.ProjectID != nil {
+		var project models.Project
+		if err := h.db.Select("id").First(&project, *req.ProjectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project"})
+			return
+		}
+	}
+
+	reservation := models.StockReservation{
+		ItemID:      req.ItemID,
+		ProjectID:   req.ProjectID,
+		Reference:   req.Reference,
+		Quantity:    req.Quantity,
+		Status:      reservationStatusActive,
+		ExpiresAt:   req.ExpiresAt,
+		Notes:       strings.TrimSpace(req.Notes),
+		CreatedByID: userID,
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var item models.InventoryItem
+		if err := scope.items(tx.Clauses(clause.Locking{Strength: "UPDATE"})).First(&item, req.ItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Item not found")
+			}
+			return err
+		}
+		if err := ensureUnreservedStock(tx, &item, req.Quantity); err != nil {
+			return err
+		}
+		return tx.Create(&reservation).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create reservation")
+		return
+	}
+
+	h.db.Preload("Item").Preload("Project").Preload("CreatedBy").First(&reservation, reservation.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    reservation,
+		"message": "Reservation created successfully",
+	})
+}
+
+// UpdateReservation changes the reserved quantity, expiry or notes of an active reservation.
+func (h *ReservationHandler) UpdateReservation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	var req updateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var reservation models.StockReservation
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := scope.reservations(tx.Clauses(clause.Locking{Strength: "UPDATE"})).First(&reservation, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Reservation not found")
+			}
+			return err
+		}
+		if reservation.Status != reservationStatusActive {
+			return newValidationError("Only active reservations can be changed")
+		}
+
+		if req.Quantity != nil {
+			if *req.Quantity < reservation.FulfilledQuantity || *req.Quantity <= 0 {
+				return newValidationError("Quantity must be positive and not below the fulfilled quantity (%s)", formatFloat(reservation.FulfilledQuantity))
+			}
+			if increase := *req.Quantity - reservation.Quantity; increase > 0 {
+				var item models.InventoryItem
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, reservation.ItemID).Error; err != nil {
+					return err
+				}
+				if err := ensureUnreservedStock(tx, &item, increase); err != nil {
+					return err
+				}
+			}
+			reservation.Quantity = *req.Quantity
+			if reservation.Remaining() <= 0 {
+				reservation.Status = reservationStatusFulfilled
+			}
+		}
+		if req.ExpiresAt != nil {
+			reservation.ExpiresAt = req.ExpiresAt
+		}
+		if req.Notes != nil {
+			reservation.Notes = strings.TrimSpace(*req.Notes)
+		}
+		return tx.Save(&reservation).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update reservation")
+		return
+	}
+
+	h.db.Preload("Item").Preload("Project").Preload("CreatedBy").First(&reservation, reservation.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    reservation,
+		"message": "Reservation updated successfully",
+	})
+}
+
+// CancelReservation releases whatever an active reservation still holds.
+func (h *ReservationHandler) CancelReservation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation ID"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var reservation models.StockReservation
+	if err := scope.reservations(h.db).First(&reservation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reservation"})
+		return
+	}
+
+	if reservation.Status != reservationStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reservation is already %s", reservation.Status)})
+		return
+	}
+
+	reservation.Status = reservationStatusCancelled
+	if err := h.db.Save(&reservation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to cancel reservation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    reservation,
+		"message": "Reservation cancelled",
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestReservedStockIsProtected checks that every path lowering on-hand stock
+// leaves reserved stock alone unless it consumes the reservation.
+func TestReservedStockIsProtected(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	main := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	backup := models.Warehouse{Code: "BACKUP", Name: "Backup", IsActive: true}
+	mustCreate(t, db, &main, &backup)
+
+	// 10 on hand, 8 reserved: only 2 are available.
+	item := models.InventoryItem{WarehouseID: main.ID, SN: "BULK", Name: "Bulk item", Unit: "pcs", Quantity: 10, IsActive: true}
+	serialItem := models.InventoryItem{WarehouseID: main.ID, SN: "TRACKED", Name: "Tracked item", Unit: "pcs", Quantity: 1, IsActive: true, IsSerialized: true}
+	mustCreate(t, db, &item, &serialItem)
+	serial := models.SerialNumber{ItemID: serialItem.ID, Serial: "TRACKED-1", Status: serialStatusInStock, WarehouseID: &main.ID}
+	mustCreate(t, db, &serial)
+	incoming := models.InventoryTransaction{ItemID: item.ID, Type: "in", Quantity: 5, CreatedByID: user.ID}
+	mustCreate(t, db, &incoming)
+	mustCreate(t, db,
+		&models.StockReservation{ItemID: item.ID, Reference: "SO-1", Quantity: 8, Status: reservationStatusActive, CreatedByID: user.ID},
+		&models.StockReservation{ItemID: serialItem.ID, Reference: "SO-2", Quantity: 1, Status: reservationStatusActive, CreatedByID: user.ID},
+	)
+
+	inventory := NewInventoryHandler(db, nil, nil, nil)
+	serials := NewSerialHandler(db)
+	router := gin.New()
+	api := router.Group("", asUser(user))
+	api.PUT("/inventory/items/:id", inventory.UpdateItem)
+	api.POST("/inventory/items/:id/transactions", inventory.RecordTransaction)
+	api.DELETE("/inventory/transactions/:id", inventory.DeleteTransaction)
+	api.DELETE("/inventory/items/:id", inventory.DeleteItem)
+	api.DELETE("/inventory/items", inventory.DeleteItemsBatch)
+	api.POST("/inventory/serials/:id/move", serials.MoveSerial)
+
+	itemTransactions := fmt.Sprintf("/inventory/items/%d/transactions", item.ID)
+	cases := []struct {
+		name, method, path string
+		body               gin.H
+	}{
+		{"out", http.MethodPost, itemTransactions, gin.H{"type": "out", "quantity": 3}},
+		{"transfer", http.MethodPost, itemTransactions, gin.H{"type": "transfer", "quantity": 3, "to_warehouse_id": backup.ID}},
+		{"negative adjustment", http.MethodPost, itemTransactions, gin.H{"type": "adjustment", "quantity": -3}},
+		{"quantity edit", http.MethodPut, fmt.Sprintf("/inventory/items/%d", item.ID), gin.H{"quantity": 7}},
+		{"deleting stock in", http.MethodDelete, fmt.Sprintf("/inventory/transactions/%d", incoming.ID), nil},
+		{"serial repair", http.MethodPost, fmt.Sprintf("/inventory/serials/%d/move", serial.ID), gin.H{"action": "repair"}},
+		{"serial scrap", http.MethodPost, fmt.Sprintf("/inventory/serials/%d/move", serial.ID), gin.H{"action": "scrap"}},
+		{"serial transfer", http.MethodPost, fmt.Sprintf("/inventory/serials/%d/move", serial.ID), gin.H{"action": "transfer", "to_warehouse_id": backup.ID}},
+		{"item delete", http.MethodDelete, fmt.Sprintf("/inventory/items/%d", item.ID), nil},
+		{"batch delete", http.MethodDelete, "/inventory/items", gin.H{"ids": []uint{serialItem.ID}}},
+	}
+	for _, tc := range cases {
+		resp := serve(router, tc.method, tc.path, tc.body)
+		if resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, resp.Code, resp.Body)
+		}
+	}
+
+	var reloaded models.InventoryItem
+	if err := db.First(&reloaded, item.ID).Error; err != nil {
+		t.Fatalf("reserved item was deleted: %v", err)
+	}
+	if reloaded.Quantity != 10 {
+		t.Fatalf("reserved stock was reduced to %v", reloaded.Quantity)
+	}
+	var reloadedSerialItem models.InventoryItem
+	if err := db.First(&reloadedSerialItem, serialItem.ID).Error; err != nil {
+		t.Fatalf("reserved serialised item was deleted: %v", err)
+	}
+	if reloadedSerialItem.Quantity != 1 {
+		t.Fatalf("reserved serialised stock was reduced to %v", reloadedSerialItem.Quantity)
+	}
+
+	// The unreserved remainder can still be taken.
+	if resp := serve(router, http.MethodPost, itemTransactions, gin.H{"type": "adjustment", "quantity": -2}); resp.Code != http.StatusCreated {
+		t.Fatalf("adjusting unreserved stock failed with %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestReservationErrorsMapByType(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	warehouse := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	mustCreate(t, db, &warehouse)
+	item := models.InventoryItem{WarehouseID: warehouse.ID, SN: "BULK", Name: "Bulk item", Unit: "pcs", Quantity: 10, IsActive: true}
+	mustCreate(t, db, &item)
+	reservation := models.StockReservation{ItemID: item.ID, Reference: "SO-1", Quantity: 4, Status: reservationStatusActive, CreatedByID: user.ID}
+	mustCreate(t, db, &reservation)
+
+	reservations := NewReservationHandler(db)
+	router := gin.New()
+	api := router.Group("", asUser(user))
+	api.POST("/inventory/reservations", reservations.CreateReservation)
+	api.PUT("/inventory/reservations/:id", reservations.UpdateReservation)
+	api.POST("/inventory/items/:id/transactions", NewInventoryHandler(db, nil, nil, nil).RecordTransaction)
+
+	cases := []struct {
+		name, method, path string
+		body               gin.H
+		want               int
+	}{
+		{"unknown reservation", http.MethodPut, "/inventory/reservations/999", gin.H{"quantity": 1}, http.StatusNotFound},
+		{"unknown item", http.MethodPost, "/inventory/reservations", gin.H{"item_id": 999, "quantity": 1, "reference": "SO-2"}, http.StatusNotFound},
+		{"quantity below fulfilled", http.MethodPut, fmt.Sprintf("/inventory/reservations/%d", reservation.ID), gin.H{"quantity": -1}, http.StatusBadRequest},
+		{"issuing against an unknown reservation", http.MethodPost, fmt.Sprintf("/inventory/items/%d/transactions", item.ID), gin.H{"type": "out", "quantity": 1, "reservation_id": 999}, http.StatusNotFound},
+		{"issuing more than reserved", http.MethodPost, fmt.Sprintf("/inventory/items/%d/transactions", item.ID), gin.H{"type": "out", "quantity": 5, "reservation_id": reservation.ID}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if resp := serve(router, tc.method, tc.path, tc.body); resp.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, resp.Code, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const warehouseScopeKey = "warehouse_scope"
+
+var errNoUserContext = errors.New("unable to resolve user context")
+
+// warehouseScope limits a request to the warehouses assigned to the user.
+// An unrestricted scope leaves queries untouched.
+type warehouseScope struct {
+	restricted bool
+	ids        []uint
+	set        map[uint]struct{}
+}
+
+// resolveWarehouseScope loads the scope of the current user once per request.
+func resolveWarehouseScope(db *gorm.DB, c *gin.Context) (warehouseScope, error) {
+	if cached, ok := c.Get(warehouseScopeKey); ok {
+		if scope, ok := cached.(warehouseScope); ok {
+			return scope, nil
+		}
+	}
+
+	scope := warehouseScope{}
+	if ids, scoped, ok := middleware.APIKeyWarehouseScope(c); ok {
+		if scoped {
+			scope = newWarehouseScope(ids)
+		}
+		c.Set(warehouseScopeKey, scope)
+		return scope, nil
+	}
+
+	roleValue, _ := c.Get("role_id")
+	roleID, ok := toUint(roleValue)
+	if !ok || roleID == 0 {
+		return scope, errNoUserContext
+	}
+	scoped, err := roleWarehouseScoped(db, roleID)
+	if err != nil {
+		return scope, err
+	}
+	if scoped {
+		value, _ := c.Get("user_id")
+		userID, ok := toUint(value)
+		if !ok || userID == 0 {
+			return scope, errNoUserContext
+		}
+		ids, err := userWarehouseIDs(db, userID)
+		if err != nil {
+			return scope, err
+		}
+		scope = newWarehouseScope(ids)
+	}
+
+	c.Set(warehouseScopeKey, scope)
+	return scope, nil
+}
+
+func newWarehouseScope(ids []uint) warehouseScope {
+	return warehouseScope{restricted: true, ids: ids, set: buildUintSet(ids)}
+}
+
+// requireWarehouseScope resolves the scope or writes the error response.
+func requireWarehouseScope(db *gorm.DB, c *gin.Context) (warehouseScope, bool) {
+	scope, err := resolveWarehouseScope(db, c)
+	if err != nil {
+		respondWarehouseScopeError(c, err)
+		return scope, false
+	}
+	return scope, true
+}
+
+func respondWarehouseScopeError(c *gin.Context, err error) {
+	if errors.Is(err, errNoUserContext) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unable to resolve user context"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve warehouse permissions"})
+}
+
+func respondWarehouseForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this warehouse"})
+}
+
+// allows reports whether every given warehouse is inside the scope.
+func (s warehouseScope) allows(ids ...uint) bool {
+	if !s.restricted {
+		return true
+	}
+	for _, id := range ids {
+		if _, ok := s.set[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsOptional is allows for an optional warehouse reference; nil is always allowed.
+func (s warehouseScope) allowsOptional(id *uint) bool {
+	return id == nil || s.allows(*id)
+}
+
+// sqlIDs never returns an empty list, so "IN ?" matches nothing instead of failing.
+func (s warehouseScope) sqlIDs() []uint {
+	if len(s.ids) == 0 {
+		return []uint{0}
+	}
+	return s.ids
+}
+
+// restrict adds "column IN (assigned warehouses)" to query.
+func (s warehouseScope) restrict(query *gorm.DB, column string) *gorm.DB {
+	if !s.restricted {
+		return query
+	}
+	return query.Where(column+" IN ?", s.sqlIDs())
+}
+
+func (s warehouseScope) items(query *gorm.DB) *gorm.DB {
+	return s.restrict(query, "inventory_items.warehouse_id")
+}
+
+func (s warehouseScope) warehouses(query *gorm.DB) *gorm.DB {
+	return s.restrict(query, "warehouses.id")
+}
+
+// transactions keeps transactions whose item, source or destination warehouse is in scope.
+func (s warehouseScope) transactions(query *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return query
+	}
+	ids := s.sqlIDs()
+	return query.Where(
+		"(inventory_transactions.item_id IN (SELECT id FROM inventory_items WHERE warehouse_id IN ?) OR inventory_transactions.from_warehouse_id IN ? OR inventory_transactions.to_warehouse_id IN ?)",
+		ids, ids, ids,
+	)
+}
+
+// serials keeps serial numbers whose item belongs to a warehouse in scope.
+func (s warehouseScope) serials(query *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return query
+	}
+	return query.Where("serial_numbers.item_id IN (SELECT id FROM inventory_items WHERE warehouse_id IN ?)", s.sqlIDs())
+}
+
+// reservations keeps stock reservations whose item belongs to a warehouse in scope.
+func (s warehouseScope) reservations(query *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return query
+	}
+	return query.Where("stock_reservations.item_id IN (SELECT id FROM inventory_items WHERE warehouse_id IN ?)", s.sqlIDs())
+}
+
+// findScopedItem loads an inventory item the caller may access. Items in other
+// warehouses are reported as not found. ok is false once a response was written.
+func findScopedItem(db *gorm.DB, c *gin.Context, id uint) (*models.InventoryItem, bool) {
+	scope, ok := requireWarehouseScope(db, c)
+	if !ok {
+		return nil, false
+	}
+	var item models.InventoryItem
+	if err := scope.items(db).First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch item",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return &item, true
+}
+
+func roleWarehouseScoped(db *gorm.DB, roleID uint) (bool, error) {
+	scoped, exists, err := middleware.RoleWarehouseScoped(db, roleID)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, errNoUserContext
+	}
+	return scoped, nil
+}
+
+func userWarehouseIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&models.UserWarehouse{}).
+		Where("user_id = ?", userID).
+		Pluck("warehouse_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopeFixture signs in an employee assigned to the "own" warehouse; every
+// "other" row lives in a warehouse the employee is not assigned to.
+type scopeFixture struct {
+	db     *gorm.DB
+	router *gin.Engine
+
+	own, other         models.Warehouse
+	ownItem, otherItem models.InventoryItem
+	otherSerialItem    models.InventoryItem
+	ownTxn, otherTxn   models.InventoryTransaction
+	otherSerial        models.SerialNumber
+	otherReservation   models.StockReservation
+	ownBin, otherBin   models.WarehouseLocation
+	otherBinStock      models.InventoryBinStock
+}
+
+func newScopeFixture(t *testing.T) *scopeFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := &scopeFixture{db: db}
+
+	role := models.Role{Name: "employee", WarehouseScoped: true}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "employee@example.com", Password: "x", FullName: "Employee", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+
+	f.own = models.Warehouse{Code: "OWN", Name: "Own Warehouse", IsActive: true}
+	f.other = models.Warehouse{Code: "OTHER", Name: "Other Warehouse", IsActive: true}
+	mustCreate(t, db, &f.own, &f.other)
+	mustCreate(t, db, &models.UserWarehouse{UserID: user.ID, WarehouseID: f.own.ID})
+
+	f.ownItem = models.InventoryItem{WarehouseID: f.own.ID, SN: "OWN-ITEM", Name: "Own item", Unit: "pcs", Quantity: 50, IsActive: true}
+	f.otherItem = models.InventoryItem{WarehouseID: f.other.ID, SN: "OTHER-ITEM", Name: "Other item", Unit: "pcs", Quantity: 50, IsActive: true}
+	f.otherSerialItem = models.InventoryItem{WarehouseID: f.other.ID, SN: "OTHER-SERIAL-ITEM", Name: "Other serialised item", Unit: "pcs", Quantity: 1, IsActive: true, IsSerialized: true}
+	mustCreate(t, db, &f.ownItem, &f.otherItem, &f.otherSerialItem)
+
+	f.ownTxn = models.InventoryTransaction{ItemID: f.ownItem.ID, Type: "in", Quantity: 50, Reference: "OWN-TXN", CreatedByID: user.ID}
+	f.otherTxn = models.InventoryTransaction{ItemID: f.otherItem.ID, Type: "in", Quantity: 50, Reference: "OTHER-TXN", CreatedByID: user.ID}
+	mustCreate(t, db, &f.ownTxn, &f.otherTxn)
+
+	f.otherSerial = models.SerialNumber{ItemID: f.otherSerialItem.ID, Serial: "OTHER-SERIAL", Status: serialStatusInStock, WarehouseID: &f.other.ID}
+	mustCreate(t, db, &f.otherSerial)
+
+	f.otherReservation = models.StockReservation{ItemID: f.otherItem.ID, Reference: "OTHER-RES", Quantity: 5, Status: reservationStatusActive, CreatedByID: user.ID}
+	mustCreate(t, db, &f.otherReservation)
+
+	f.ownBin = models.WarehouseLocation{WarehouseID: f.own.ID, Type: "bin", Code: "OWN-BIN", Path: "OWN-BIN", IsActive: true}
+	f.otherBin = models.WarehouseLocation{WarehouseID: f.other.ID, Type: "bin", Code: "OTHER-BIN", Path: "OTHER-BIN", IsActive: true}
+	mustCreate(t, db, &f.ownBin, &f.otherBin)
+	f.otherBinStock = models.InventoryBinStock{ItemID: f.otherItem.ID, LocationID: f.otherBin.ID, Quantity: 10}
+	mustCreate(t, db, &f.otherBinStock)
+
+	inventory := NewInventoryHandler(db, nil, nil, nil)
+	serials := NewSerialHandler(db)
+	reservations := NewReservationHandler(db)
+	locations := NewLocationHandler(db)
+	warehouses := NewWarehouseHandler(db)
+
+	router := gin.New()
+	api := router.Group("/api/v1", asUser(user))
+	api.GET("/warehouses", warehouses.GetAll)
+	api.GET("/warehouses/:id", warehouses.GetByID)
+	api.PUT("/warehouses/:id", warehouses.Update)
+	api.DELETE("/warehouses/:id", warehouses.Delete)
+	api.GET("/warehouses/:id/locations", locations.ListLocations)
+	api.POST("/warehouses/:id/locations", locations.CreateLocation)
+	api.PUT("/warehouses/:id/locations/:locationId", locations.UpdateLocation)
+	api.DELETE("/warehouses/:id/locations/:locationId", locations.DeleteLocation)
+	api.GET("/warehouses/:id/locations/:locationId/stock", locations.GetLocationStock)
+	api.POST("/warehouses/:id/pick-list", locations.BuildPickList)
+	api.GET("/inventory", inventory.GetAllItems)
+	api.GET("/inventory/transactions", inventory.GetAllTransactions)
+	api.DELETE("/inventory/transactions/:id", inventory.DeleteTransaction)
+	api.GET("/inventory/serials", serials.ListSerials)
+	api.GET("/inventory/serials/:id", serials.GetSerial)
+	api.POST("/inventory/serials/:id/move", serials.MoveSerial)
+	api.GET("/inventory/reservations", reservations.ListReservations)
+	api.POST("/inventory/reservations", reservations.CreateReservation)
+	api.PUT("/inventory/reservations/:id", reservations.UpdateReservation)
+	api.POST("/inventory/reservations/:id/cancel", reservations.CancelReservation)
+	api.POST("/inventory/import/csv", inventory.ImportItemsFromCSV)
+	api.POST("/inventory/import/preview", inventory.PreviewImport)
+	api.GET("/inventory/export/csv", inventory.ExportItemsToCSV)
+	api.GET("/inventory/transactions/export/csv", inventory.ExportTransactionsToCSV)
+	api.POST("/inventory/items", inventory.CreateItem)
+	api.DELETE("/inventory/items", inventory.DeleteItemsBatch)
+	api.GET("/inventory/items/:id", inventory.GetItemByID)
+	api.PUT("/inventory/items/:id", inventory.UpdateItem)
+	api.DELETE("/inventory/items/:id", inventory.DeleteItem)
+	api.GET("/inventory/items/:id/transactions", inventory.GetItemTransactions)
+	api.POST("/inventory/items/:id/transactions", inventory.RecordTransaction)
+	api.GET("/inventory/items/:id/bins", locations.GetItemBins)
+	api.GET("/inventory/items/:id/serials", serials.ListItemSerials)
+	api.POST("/inventory/items/:id/serials", serials.RegisterSerials)
+	f.router = router
+	return f
+}
+
+func (f *scopeFixture) do(method, path string, body interface{}, headers ...string) testResponse {
+	return serve(f.router, method, "/api/v1"+path, body, headers...)
+}
+
+// expectDenied fails unless a handler refused the request as forbidden or not
+// found; an unmatched route does not count.
+func expectDenied(t *testing.T, resp testResponse) {
+	t.Helper()
+	if resp.Code != http.StatusForbidden && resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 403 or 404, got %d: %s", resp.Code, resp.Body)
+	}
+	if !strings.HasPrefix(resp.Body, "{") {
+		t.Fatalf("request did not reach a handler: %s", resp.Body)
+	}
+}
+
+// expectListed fails unless the request succeeded, contains every wanted marker
+// and none of the hidden ones.
+func expectListed(t *testing.T, resp testResponse, wanted []string, hidden ...string) {
+	t.Helper()
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	for _, marker := range wanted {
+		if !strings.Contains(resp.Body, marker) {
+			t.Fatalf("expected %q in response: %s", marker, resp.Body)
+		}
+	}
+	for _, marker := range hidden {
+		if strings.Contains(resp.Body, marker) {
+			t.Fatalf("response leaks %q: %s", marker, resp.Body)
+		}
+	}
+}
+
+func (f *scopeFixture) reloadItem(t *testing.T, id uint) models.InventoryItem {
+	t.Helper()
+	var item models.InventoryItem
+	if err := f.db.Unscoped().First(&item, id).Error; err != nil {
+		t.Fatalf("reload item %d: %v", id, err)
+	}
+	return item
+}
+
+func TestWarehouseScopeItems(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/inventory", nil), []string{"OWN-ITEM"}, "OTHER-ITEM")
+	expectListed(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/items/%d", f.ownItem.ID), nil), []string{"OWN-ITEM"})
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/items/%d", f.otherItem.ID), nil))
+
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/inventory/items/%d", f.otherItem.ID), gin.H{"name": "Renamed"}))
+	if item := f.reloadItem(t, f.otherItem.ID); item.Name != "Other item" {
+		t.Fatalf("out-of-scope item was renamed to %q", item.Name)
+	}
+
+	// Moving an own item into the other warehouse is refused as well.
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/inventory/items/%d", f.ownItem.ID), gin.H{"warehouse_id": f.other.ID}))
+	if item := f.reloadItem(t, f.ownItem.ID); item.WarehouseID != f.own.ID {
+		t.Fatalf("item was moved to warehouse %d", item.WarehouseID)
+	}
+
+	expectDenied(t, f.do(http.MethodPost, "/inventory/items", gin.H{
+		"warehouse_id": f.other.ID, "sn": "NEW-ITEM", "name": "New item", "unit": "pcs",
+	}))
+	var created int64
+	f.db.Model(&models.InventoryItem{}).Where("sku = ?", "NEW-ITEM").Count(&created)
+	if created != 0 {
+		t.Fatal("item was created in an out-of-scope warehouse")
+	}
+
+	expectDenied(t, f.do(http.MethodDelete, fmt.Sprintf("/inventory/items/%d", f.otherItem.ID), nil))
+	expectDenied(t, f.do(http.MethodDelete, "/inventory/items", gin.H{"ids": []uint{f.ownItem.ID, f.otherItem.ID}}))
+	for _, id := range []uint{f.ownItem.ID, f.otherItem.ID} {
+		if item := f.reloadItem(t, id); item.DeletedAt.Valid {
+			t.Fatalf("item %d was deleted", id)
+		}
+	}
+}
+
+func TestWarehouseScopeTransactions(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/inventory/transactions", nil), []string{"OWN-TXN"}, "OTHER-TXN")
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/items/%d/transactions", f.otherItem.ID), nil))
+
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/inventory/items/%d/transactions", f.otherItem.ID), gin.H{
+		"type": "out", "quantity": 5,
+	}))
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/inventory/items/%d/transactions", f.ownItem.ID), gin.H{
+		"type": "transfer", "quantity": 5, "to_warehouse_id": f.other.ID,
+	}))
+	if item := f.reloadItem(t, f.otherItem.ID); item.Quantity != 50 {
+		t.Fatalf("out-of-scope stock changed to %v", item.Quantity)
+	}
+	if item := f.reloadItem(t, f.ownItem.ID); item.Quantity != 50 {
+		t.Fatalf("transfer to an out-of-scope warehouse changed stock to %v", item.Quantity)
+	}
+
+	// The same employee can still move stock in the assigned warehouse.
+	if resp := f.do(http.MethodPost, fmt.Sprintf("/inventory/items/%d/transactions", f.ownItem.ID), gin.H{
+		"type": "out", "quantity": 5,
+	}); resp.Code != http.StatusCreated {
+		t.Fatalf("in-scope transaction failed with %d: %s", resp.Code, resp.Body)
+	}
+
+	expectDenied(t, f.do(http.MethodDelete, fmt.Sprintf("/inventory/transactions/%d", f.otherTxn.ID), nil))
+	var remaining int64
+	f.db.Model(&models.InventoryTransaction{}).Where("id = ?", f.otherTxn.ID).Count(&remaining)
+	if remaining != 1 {
+		t.Fatal("out-of-scope transaction was deleted")
+	}
+}
+
+func TestWarehouseScopeSerials(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/inventory/serials", nil), nil, "OTHER-SERIAL")
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/serials/%d", f.otherSerial.ID), nil))
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/items/%d/serials", f.otherSerialItem.ID), nil))
+
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/inventory/items/%d/serials", f.otherSerialItem.ID), gin.H{
+		"serials": []string{"NEW-SERIAL"},
+	}))
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/inventory/serials/%d/move", f.otherSerial.ID), gin.H{
+		"action": "scrap",
+	}))
+
+	var serial models.SerialNumber
+	if err := f.db.First(&serial, f.otherSerial.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if serial.Status != serialStatusInStock {
+		t.Fatalf("out-of-scope serial moved to %s", serial.Status)
+	}
+	var registered int64
+	f.db.Model(&models.SerialNumber{}).Where("serial = ?", "NEW-SERIAL").Count(&registered)
+	if registered != 0 {
+		t.Fatal("serial was registered on an out-of-scope item")
+	}
+}
+
+func TestWarehouseScopeReservations(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/inventory/reservations", nil), nil, "OTHER-RES")
+	expectDenied(t, f.do(http.MethodPost, "/inventory/reservations", gin.H{
+		"item_id": f.otherItem.ID, "quantity": 1, "reference": "NEW-RES",
+	}))
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/inventory/reservations/%d", f.otherReservation.ID), gin.H{"quantity": 1}))
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/inventory/reservations/%d/cancel", f.otherReservation.ID), nil))
+
+	var reservation models.StockReservation
+	if err := f.db.First(&reservation, f.otherReservation.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reservation.Status != reservationStatusActive || reservation.Quantity != 5 {
+		t.Fatalf("out-of-scope reservation changed: status %s, quantity %v", reservation.Status, reservation.Quantity)
+	}
+	var created int64
+	f.db.Model(&models.StockReservation{}).Where("reference = ?", "NEW-RES").Count(&created)
+	if created != 0 {
+		t.Fatal("reservation was created on an out-of-scope item")
+	}
+}
+
+func TestWarehouseScopeLocations(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, fmt.Sprintf("/warehouses/%d/locations", f.own.ID), nil), []string{"OWN-BIN"}, "OTHER-BIN")
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/warehouses/%d/locations", f.other.ID), nil))
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/warehouses/%d/locations", f.other.ID), gin.H{"type": "bin", "code": "NEW-BIN"}))
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/warehouses/%d/locations/%d", f.other.ID, f.otherBin.ID), gin.H{"code": "RENAMED"}))
+	expectDenied(t, f.do(http.MethodDelete, fmt.Sprintf("/warehouses/%d/locations/%d", f.other.ID, f.otherBin.ID), nil))
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/warehouses/%d/locations/%d/stock", f.other.ID, f.otherBin.ID), nil))
+	expectDenied(t, f.do(http.MethodPost, fmt.Sprintf("/warehouses/%d/pick-list", f.other.ID), gin.H{
+		"lines": []gin.H{{"item_id": f.otherItem.ID, "quantity": 1}},
+	}))
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/items/%d/bins", f.otherItem.ID), nil))
+
+	// A location in the other warehouse cannot be reached through an own warehouse ID either.
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/warehouses/%d/locations/%d", f.own.ID, f.otherBin.ID), gin.H{"code": "RENAMED"}))
+
+	var bin models.WarehouseLocation
+	if err := f.db.First(&bin, f.otherBin.ID).Error; err != nil {
+		t.Fatalf("out-of-scope location was deleted: %v", err)
+	}
+	if bin.Code != "OTHER-BIN" {
+		t.Fatalf("out-of-scope location renamed to %q", bin.Code)
+	}
+}
+
+func TestWarehouseScopeWarehouses(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/warehouses", nil), []string{"Own Warehouse"}, "Other Warehouse")
+	expectDenied(t, f.do(http.MethodGet, fmt.Sprintf("/warehouses/%d", f.other.ID), nil))
+	expectDenied(t, f.do(http.MethodPut, fmt.Sprintf("/warehouses/%d", f.other.ID), gin.H{"code": "OTHER", "name": "Renamed"}))
+	expectDenied(t, f.do(http.MethodDelete, fmt.Sprintf("/warehouses/%d", f.other.ID), nil))
+
+	var warehouse models.Warehouse
+	if err := f.db.First(&warehouse, f.other.ID).Error; err != nil {
+		t.Fatalf("out-of-scope warehouse was deleted: %v", err)
+	}
+	if warehouse.Name != "Other Warehouse" {
+		t.Fatalf("out-of-scope warehouse renamed to %q", warehouse.Name)
+	}
+}
+
+func scopeImportUpload(t *testing.T, rows string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "items.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("sn,name,warehouse_code,quantity\n" + rows))
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+func TestWarehouseScopeImport(t *testing.T) {
+	f := newScopeFixture(t)
+
+	body, contentType := scopeImportUpload(t, "OWN-NEW,Own new,OWN,3\nOTHER-NEW,Other new,OTHER,3\nOTHER-ITEM,Renamed,OTHER,99\n")
+	resp := f.do(http.MethodPost, "/inventory/import/preview", body, "Content-Type", contentType)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("preview failed with %d: %s", resp.Code, resp.Body)
+	}
+	var preview struct {
+		Counts map[string]int `json:"counts"`
+	}
+	resp.decode(t, &preview)
+	if preview.Counts[importActionInsert] != 1 || preview.Counts[importActionError] != 2 {
+		t.Fatalf("expected 1 insert and 2 errors in preview, got %v", preview.Counts)
+	}
+
+	body, contentType = scopeImportUpload(t, "OWN-NEW,Own new,OWN,3\nOTHER-NEW,Other new,OTHER,3\nOTHER-ITEM,Renamed,OTHER,99\n")
+	resp = f.do(http.MethodPost, "/inventory/import/csv", body, "Content-Type", contentType)
+	if resp.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207 with row errors, got %d: %s", resp.Code, resp.Body)
+	}
+	var result struct {
+		Summary importSummary `json:"summary"`
+	}
+	resp.decode(t, &result)
+	if result.Summary.Inserted != 1 || result.Summary.Updated != 0 || len(result.Summary.Errors) != 2 {
+		t.Fatalf("unexpected import summary %+v", result.Summary)
+	}
+
+	var created int64
+	f.db.Model(&models.InventoryItem{}).Where("sku = ?", "OTHER-NEW").Count(&created)
+	if created != 0 {
+		t.Fatal("import created an item in an out-of-scope warehouse")
+	}
+	if item := f.reloadItem(t, f.otherItem.ID); item.Name != "Other item" || item.Quantity != 50 {
+		t.Fatalf("import changed an out-of-scope item: %q, %v", item.Name, item.Quantity)
+	}
+}
+
+func TestWarehouseScopeExport(t *testing.T) {
+	f := newScopeFixture(t)
+
+	expectListed(t, f.do(http.MethodGet, "/inventory/export/csv", nil), []string{"OWN-ITEM"}, "OTHER-ITEM", "OTHER-SERIAL-ITEM")
+	expectListed(t, f.do(http.MethodGet, "/inventory/transactions/export/csv", nil), []string{"OWN-TXN"}, "OTHER-TXN")
+
+	// Filtering on the other warehouse yields nothing rather than its rows.
+	expectListed(t, f.do(http.MethodGet, fmt.Sprintf("/inventory/export/csv?warehouse_id=%d", f.other.ID), nil), nil, "OTHER-ITEM")
+}
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	serialStatusInStock  = "in_stock"
+	serialStatusIssued   = "issued"
+	serialStatusInRepair = "in_repair"
+	serialStatusScrapped = "scrapped"
+)
+
+type SerialHandler struct {
+	db *gorm.DB
+}
+
+func NewSerialHandler(db *gorm.DB) *SerialHandler {
+	return &SerialHandler{db: db}
+}
+
+type registerSerialsRequest struct {
+	Serials      []string `json:"serials" binding:"required"`
+	OnHand       bool     `json:"on_hand"` // serials for stock already counted in Quantity
+	ToLocationID *uint    `json:"to_location_id"`
+	Reference    string   `json:"reference"`
+	Notes        string   `json:"notes"`
+}
+
+type serialMoveRequest struct {
+	Action         string `json:"action" binding:"required"` // issue, return, repair, scrap, transfer
+	CustodianID    *uint  `json:"custodian_id"`
+	ToWarehouseID  *uint  `json:"to_warehouse_id"`
+	FromLocationID *uint  `json:"from_location_id"`
+	ToLocationID   *uint  `json:"to_location_id"`
+	ReservationID  *uint  `json:"reservation_id"`
+	Reference      string `json:"reference"`
+	Notes          string `json:"notes"`
+}
+
+// serialTransitions lists the statuses each action may start from.
+var serialTransitions = map[string][]string{
+	"issue":    {serialStatusInStock},
+	"return":   {serialStatusIssued, serialStatusInRepair},
+	"repair":   {serialStatusInStock, serialStatusIssued},
+	"scrap":    {serialStatusInStock, serialStatusIssued, serialStatusInRepair},
+	"transfer": {serialStatusInStock},
+}
+
+// recordSerialStock books a stock change caused by a serial movement so that item
+// quantities and transaction history stay consistent with serial statuses. The
+// caller fills Type, Quantity, locations and references on transaction. Stock
+// leaving the item may not eat into reservations unless it is issued against one.
+func recordSerialStock(tx *gorm.DB, item *models.InventoryItem, transaction *models.InventoryTransaction) error {
+	transaction.ItemID = item.ID
+	transaction.EntryUnit = item.Unit
+	transaction.EntryQuantity = transaction.Quantity
+
+	if transaction.Type == "in" || (transaction.Type == "adjustment" && transaction.Quantity > 0) {
+		if transaction.ToLocationID != nil {
+			if err := adjustBinStock(tx, item.ID, item.WarehouseID, *transaction.ToLocationID, transaction.Quantity); err != nil {
+				return err
+			}
+		}
+		if transaction.ToWarehouseID == nil {
+			transaction.ToWarehouseID = &item.WarehouseID
+		}
+		item.Quantity += transaction.Quantity
+	} else {
+		amount := transaction.Quantity
+		if transaction.Type == "adjustment" {
+			amount = -transaction.Quantity
+		}
+		if item.Quantity < amount {
+			return newValidationError("Insufficient stock for %s", item.Name)
+		}
+		if transaction.ReservationID == nil {
+			if err := ensureUnreservedStock(tx, item, amount); err != nil {
+				return err
+			}
+		}
+		if err := takeFromBinOrUnassigned(tx, item, transaction.FromLocationID, amount); err != nil {
+			return err
+		}
+		transaction.FromWarehouseID = &item.WarehouseID
+		item.Quantity -= amount
+	}
+
+	item.IsActive = item.Quantity > 0
+	if err := tx.Save(item).Error; err != nil {
+		return err
+	}
+	return tx.Create(transaction).Error
+}
+
+// serialDestinationItem finds or creates the item row for the same SN in another warehouse.
+func serialDestinationItem(tx *gorm.DB, item *models.InventoryItem, warehouseID uint) (*models.InventoryItem, error) {
+	var destItem models.InventoryItem
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sku = ? AND warehouse_id = ?", item.SN, warehouseID).
+		First(&destItem).Error
+	switch {
+	case err == nil:
+		if !destItem.IsSerialized {
+			destItem.IsSerialized = true
+			if err := tx.Save(&destItem).Error; err != nil {
+				return nil, err
+			}
+		}
+		return &destItem, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		destItem = models.InventoryItem{
+			WarehouseID:  warehouseID,
+			SN:           item.SN,
+			Name:         item.Name,
+			Description:  item.Description,
+			Category:     item.Category,
+			Unit:         item.Unit,
+			MinStock:     item.MinStock,
+			MaxStock:     item.MaxStock,
+			UnitPrice:    item.UnitPrice,
+			IsSerialized: true,
+		}
+		if err := tx.Create(&destItem).Error; err != nil {
+			return nil, err
+		}
+		// IsActive defaults to true in the database; an empty row is inactive.
+		if err := tx.Model(&destItem).Update("is_active", false).Error; err != nil {
+			return nil, err
+		}
+		return &destItem, nil
+	default:
+		return nil, err
+	}
+}
+
+func serialQuery(db *gorm.DB) *gorm.DB {
+	return db.Preload("Item").Preload("Item.Warehouse").Preload("Warehouse").Preload("Custodian")
+}
+
+// ListSerials returns serials filtered by item, status, warehouse, custodian or serial text.
+func (h *SerialHandler) ListSerials(c *gin.Context) {
+	query := serialQuery(h.db).Model(&models.SerialNumber{})
+
+	if itemID := strings.TrimSpace(c.Query("item_id")); itemID != "" {
+		query = query.Where("serial_numbers.item_id = ?", itemID)
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		query = query.Where("serial_numbers.status = ?", status)
+	}
+	if warehouseID := strings.TrimSpace(c.Query("warehouse_id")); warehouseID != "" {
+		query = query.Where("serial_numbers.warehouse_id = ?", warehouseID)
+	}
+	if custodianID := strings.TrimSpace(c.Query("custodian_id")); custodianID != "" {
+		query = query.Where("serial_numbers.custodian_id = ?", custodianID)
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		query = query.Where("serial_numbers.serial ILIKE ?", "%"+search+"%")
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	query = scope.serials(query)
+
+	var serials []models.SerialNumber
+	if err := query.Order("serial_numbers.serial ASC").Find(&serials).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch serial numbers",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": serials})
+}
+
+// ListItemSerials returns the serials of one item.
+func (h *SerialHandler) ListItemSerials(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	if _, ok := findScopedItem(h.db, c, uint(itemID)); !ok {
+		return
+	}
+
+	var serials []models.SerialNumber
+	query := serialQuery(h.db).Where("item_id = ?", itemID)
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("serial ASC").Find(&serials).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch serial numbers",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": serials})
+}
+
+// GetSerial returns a serial with its full movement history.
+func (h *SerialHandler) GetSerial(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid serial ID"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var serial models.SerialNumber
+	if err := scope.serials(serialQuery(h.db)).
+		Preload("Movements", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		Preload("Movements.FromWarehouse").
+		Preload("Movements.ToWarehouse").
+		Preload("Movements.FromCustodian").
+		Preload("Movements.ToCustodian").
+		Preload("Movements.CreatedBy").
+		First(&serial, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Serial number not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch serial number",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": serial})
+}
+
+// RegisterSerials adds serial numbers to a serialised item. New serials add stock
+// unless on_hand is set, in which case they label units already counted.
+func (h *SerialHandler) RegisterSerials(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req registerSerialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if _, ok := findScopedItem(h.db, c, uint(itemID)); !ok {
+		return
+	}
+
+	values := make([]string, 0, len(req.Serials))
+	seen := make(map[string]struct{}, len(req.Serials))
+	for _, raw := range req.Serials {
+		value := strings.TrimSpace(raw)
+		if value == "" {
+			continue
+		}
+		if _, dup := seen[strings.ToLower(value)]; dup {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate serial " + value})
+			return
+		}
+		seen[strings.ToLower(value)] = struct{}{}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one serial is required"})
+		return
+	}
+
+	var created []models.SerialNumber
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var item models.InventoryItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, itemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Item not found")
+			}
+			return err
+		}
+		if !item.IsSerialized {
+			return newValidationError("Item %s is not serialised", item.Name)
+		}
+
+		var existing []string
+		if err := tx.Model(&models.SerialNumber{}).
+			Joins("JOIN inventory_items ON inventory_items.id = serial_numbers.item_id").
+			Where("inventory_items.sku = ? AND LOWER(serial_numbers.serial) IN ?", item.SN, lowerAll(values)).
+			Pluck("serial_numbers.serial", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return newValidationError("Serial already registered: %s", strings.Join(existing, ", "))
+		}
+
+		var transactionID *uint
+		if req.OnHand {
+			var inStock int64
+			if err := tx.Model(&models.SerialNumber{}).
+				Where("item_id = ? AND status = ?", item.ID, serialStatusInStock).
+				Count(&inStock).Error; err != nil {
+				return err
+			}
+			if float64(inStock)+float64(len(values)) > item.Quantity {
+				return newValidationError("Only %s unlabelled units are on hand", formatFloat(item.Quantity-float64(inStock)))
+			}
+		} else {
+			transaction := models.InventoryTransaction{
+				Type:         "in",
+				Quantity:     float64(len(values)),
+				ToLocationID: req.ToLocationID,
+				Reference:    strings.TrimSpace(req.Reference),
+				Notes:        strings.TrimSpace(req.Notes),
+				CreatedByID:  userID,
+			}
+			if err := recordSerialStock(tx, &item, &transaction); err != nil {
+				return err
+			}
+			transactionID = &transaction.ID
+		}
+
+		warehouseID := item.WarehouseID
+		for _, value := range values {
+			serial := models.SerialNumber{
+				ItemID:      item.ID,
+				Serial:      value,
+				Status:      serialStatusInStock,
+				WarehouseID: &warehouseID,
+				Notes:       strings.TrimSpace(req.Notes),
+			}
+			if err := tx.Create(&serial).Error; err != nil {
+				return err
+			}
+			movement := models.SerialMovement{
+				SerialID:      serial.ID,
+				Action:        "register",
+				ToStatus:      serialStatusInStock,
+				ToWarehouseID: &warehouseID,
+				TransactionID: transactionID,
+				Reference:     strings.TrimSpace(req.Reference),
+				Notes:         strings.TrimSpace(req.Notes),
+				CreatedByID:   userID,
+			}
+			if err := tx.Create(&movement).Error; err != nil {
+				return err
+			}
+			created = append(created, serial)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to register serial numbers")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    created,
+		"message": "Serial numbers registered successfully",
+	})
+}
+
+// MoveSerial changes a serial's status, warehouse or custodian and books the
+// matching stock movement on the item.
+func (h *SerialHandler) MoveSerial(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid serial ID"})
+		return
+	}
+
+	var req serialMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Action = strings.ToLower(strings.TrimSpace(req.Action))
+	allowedFrom, known := serialTransitions[req.Action]
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be one of issue, return, repair, scrap, transfer"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var serial models.SerialNumber
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&serial, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Serial number not found")
+			}
+			return err
+		}
+		if !containsString(allowedFrom, serial.Status) {
+			return newValidationError("Cannot %s a serial that is %s", req.Action, serial.Status)
+		}
+
+		var item models.InventoryItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, serial.ItemID).Error; err != nil {
+			return err
+		}
+
+		if !scope.allows(item.WarehouseID) || !scope.allowsOptional(req.ToWarehouseID) {
+			return errSerialForbidden
+		}
+
+		movement := models.SerialMovement{
+			SerialID:        serial.ID,
+			Action:          req.Action,
+			FromStatus:      serial.Status,
+			FromWarehouseID: serial.WarehouseID,
+			FromCustodianID: serial.CustodianID,
+			Reference:       strings.TrimSpace(req.Reference),
+			Notes:           strings.TrimSpace(req.Notes),
+			CreatedByID:     userID,
+		}
+		reference := movement.Reference
+		if reference == "" {
+			reference = "SN " + serial.Serial
+		}
+		notes := strings.TrimSpace(strings.Join([]string{req.Action + " " + serial.Serial, movement.Notes}, " - "))
+		wasInStock := serial.Status == serialStatusInStock
+
+		stockTx := &models.InventoryTransaction{
+			Quantity:       1,
+			FromLocationID: req.FromLocationID,
+			Reference:      reference,
+			Notes:          notes,
+			CreatedByID:    userID,
+		}
+		switch req.Action {
+		case "issue":
+			if req.CustodianID == nil {
+				return newValidationError("Custodian is required to issue a serial")
+			}
+			if err := tx.Select("id").First(&models.Employee{}, *req.CustodianID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return newNotFoundError("Custodian employee not found")
+				}
+				return err
+			}
+			if req.ReservationID != nil {
+				if err := consumeReservation(tx, *req.ReservationID, item.ID, 1); err != nil {
+					return err
+				}
+			}
+			stockTx.Type = "out"
+			stockTx.ReservationID = req.ReservationID
+			serial.Status = serialStatusIssued
+			serial.WarehouseID = nil
+			serial.CustodianID = req.CustodianID
+
+		case "return":
+			stockTx.Type = "in"
+			stockTx.FromLocationID = nil
+			stockTx.ToLocationID = req.ToLocationID
+			warehouseID := item.WarehouseID
+			serial.Status = serialStatusInStock
+			serial.WarehouseID = &warehouseID
+			serial.CustodianID = nil
+
+		case "repair":
+			stockTx.Type = "out"
+			serial.Status = serialStatusInRepair
+			serial.WarehouseID = nil
+			serial.CustodianID = nil
+
+		case "scrap":
+			stockTx.Type = "adjustment"
+			stockTx.Quantity = -1
+			serial.Status = serialStatusScrapped
+			serial.WarehouseID = nil
+			serial.CustodianID = nil
+
+		case "transfer":
+			if req.ToWarehouseID == nil || *req.ToWarehouseID == item.WarehouseID {
+				return newValidationError("A different destination warehouse is required")
+			}
+			destItem, err := serialDestinationItem(tx, &item, *req.ToWarehouseID)
+			if err != nil {
+				return err
+			}
+			if req.ToLocationID != nil {
+				if err := adjustBinStock(tx, destItem.ID, destItem.WarehouseID, *req.ToLocationID, 1); err != nil {
+					return err
+				}
+			}
+			destItem.Quantity++
+			destItem.IsActive = true
+			if err := tx.Save(destItem).Error; err != nil {
+				return err
+			}
+			stockTx.Type = "transfer"
+			stockTx.ToWarehouseID = req.ToWarehouseID
+			stockTx.ToLocationID = req.ToLocationID
+			serial.ItemID = destItem.ID
+			serial.WarehouseID = req.ToWarehouseID
+		}
+
+		// Only movements into or out of stock change the item quantity.
+		if wasInStock || serial.Status == serialStatusInStock {
+			if err := recordSerialStock(tx, &item, stockTx); err != nil {
+				return err
+			}
+			movement.TransactionID = &stockTx.ID
+		}
+		movement.ToStatus = serial.Status
+		movement.ToWarehouseID = serial.WarehouseID
+		movement.ToCustodianID = serial.CustodianID
+
+		if err := tx.Save(&serial).Error; err != nil {
+			return err
+		}
+		return tx.Create(&movement).Error
+	})
+	if err != nil {
+		if errors.Is(err, errSerialForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to move serials in this warehouse"})
+			return
+		}
+		respondError(c, err, "Failed to move serial number")
+		return
+	}
+
+	serialQuery(h.db).First(&serial, serial.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    serial,
+		"message": "Serial number updated successfully",
+	})
+}
+
+var errSerialForbidden = errors.New("serial warehouse forbidden")
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// serialMovementTransaction reports whether a stock transaction was produced by a serial movement.
+func serialMovementTransaction(db *gorm.DB, transactionID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&models.SerialMovement{}).Where("transaction_id = ?", transactionID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionRevokedLogout          = "logout"
+	sessionRevokedLogoutAll       = "logout_all"
+	sessionRevokedPasswordChanged = "password_changed"
+	sessionRevokedDeactivated     = "deactivated"
+	sessionRevokedDeleted         = "deleted"
+	sessionRevokedRoleChanged     = "role_changed"
+	sessionRevokedReuse           = "reuse"
+)
+
+var errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// sessionTokens is the access/refresh pair returned by login and refresh.
+type sessionTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func accessTokenTTL(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.JWTAccessTTL) * time.Minute
+}
+
+func signAccessToken(cfg *config.Config, user *models.User, sessionID uint) (string, error) {
+	return utils.GenerateToken(user.ID, user.Email, user.RoleID, user.Role.Name, sessionID, cfg.JWTSecret, accessTokenTTL(cfg))
+}
+
+// startSession creates a session for user and returns its first token pair.
+// user.Role must be loaded.
+func startSession(db *gorm.DB, cfg *config.Config, c *gin.Context, user *models.User) (sessionTokens, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	now := time.Now()
+	session := models.UserSession{
+		UserID:     user.ID,
+		TokenHash:  hashSessionToken(refreshToken),
+		UserAgent:  truncateString(c.Request.UserAgent(), 255),
+		IPAddress:  c.ClientIP(),
+		ExpiresAt:  now.Add(time.Duration(cfg.RefreshTokenTTL) * time.Hour),
+		LastUsedAt: now,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return sessionTokens{}, err
+	}
+
+	token, err := signAccessToken(cfg, user, session.ID)
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	return sessionTokens{Token: token, RefreshToken: refreshToken, ExpiresIn: int(accessTokenTTL(cfg).Seconds())}, nil
+}
+
+// rotateSession exchanges a refresh token for a new pair. Presenting an already
+// rotated token revokes the session, since it means the token was copied.
+func rotateSession(db *gorm.DB, cfg *config.Config, refreshToken string) (sessionTokens, *models.User, error) {
+	hash := hashSessionToken(refreshToken)
+
+	var session models.UserSession
+	if err := db.Where("token_hash = ?", hash).First(&session).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return sessionTokens{}, nil, err
+		}
+		var reused models.UserSession
+		if err := db.Where("previous_token_hash = ? AND revoked_at IS NULL", hash).First(&reused).Error; err == nil {
+			if err := revokeSessions(db, sessionRevokedReuse, "id = ?", reused.ID); err != nil {
+				return sessionTokens{}, nil, err
+			}
+		}
+		return sessionTokens{}, nil, errInvalidRefreshToken
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return sessionTokens{}, nil, errInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return sessionTokens{}, nil, errInvalidRefreshToken
+		}
+		return sessionTokens{}, nil, err
+	}
+	if !user.IsActive {
+		return sessionTokens{}, nil, errInvalidRefreshToken
+	}
+
+	next, err := newRefreshToken()
+	if err != nil {
+		return sessionTokens{}, nil, err
+	}
+	// The hash condition makes concurrent refreshes with the same token fail.
+	result := db.Model(&models.UserSession{}).
+		Where("id = ? AND token_hash = ?", session.ID, hash).
+		Updates(map[string]interface{}{
+			"token_hash":          hashSessionToken(next),
+			"previous_token_hash": hash,
+			"last_used_at":        time.Now(),
+		})
+	if result.Error != nil {
+		return sessionTokens{}, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return sessionTokens{}, nil, errInvalidRefreshToken
+	}
+
+	token, err := signAccessToken(cfg, &user, session.ID)
+	if err != nil {
+		return sessionTokens{}, nil, err
+	}
+	return sessionTokens{Token: token, RefreshToken: next, ExpiresIn: int(accessTokenTTL(cfg).Seconds())}, &user, nil
+}
+
+// revokeSessions revokes the active sessions matching the condition.
+func revokeSessions(db *gorm.DB, reason string, query interface{}, args ...interface{}) error {
+	var ids []uint
+	if err := db.Model(&models.UserSession{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if err := db.Model(&models.UserSession{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason}).Error; err != nil {
+		return err
+	}
+	middleware.InvalidateSessionCache(ids...)
+	return nil
+}
+
+// revokeUserSessions revokes every active session of a user except keepSessionID.
+func revokeUserSessions(db *gorm.DB, userID uint, reason string, keepSessionID uint) error {
+	if keepSessionID != 0 {
+		return revokeSessions(db, reason, "user_id = ? AND id <> ?", userID, keepSessionID)
+	}
+	return revokeSessions(db, reason, "user_id = ?", userID)
+}
+
+func truncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
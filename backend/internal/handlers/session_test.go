@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TestRoleChangeRevokesSessions checks that moving a user to another role signs
+// them out, while an edit that keeps the role leaves their sessions alone.
+func TestRoleChangeRevokesSessions(t *testing.T) {
+	db := newTestDB(t)
+	staff := models.Role{Name: "staff"}
+	manager := models.Role{Name: "manager"}
+	mustCreate(t, db, &staff, &manager)
+	admin := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: manager.ID, IsActive: true}
+	user := models.User{Email: "user@example.com", Password: "x", FullName: "User", RoleID: staff.ID, IsActive: true}
+	mustCreate(t, db, &admin, &user)
+	session := models.UserSession{UserID: user.ID, TokenHash: hashSessionToken("refresh"), ExpiresAt: time.Now().Add(time.Hour), LastUsedAt: time.Now()}
+	mustCreate(t, db, &session)
+
+	router := gin.New()
+	router.PUT("/settings/users/:id", asUser(admin), NewUserHandler(db, nil, &config.Config{}).UpdateUser)
+	update := func(roleID uint) {
+		t.Helper()
+		resp := serve(router, http.MethodPut, fmt.Sprintf("/settings/users/%d", user.ID), gin.H{
+			"full_name": "User", "email": "user@example.com", "role_id": roleID,
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update user: expected 200, got %d: %s", resp.Code, resp.Body)
+		}
+	}
+
+	update(staff.ID)
+	var kept models.UserSession
+	db.First(&kept, session.ID)
+	if kept.RevokedAt != nil {
+		t.Fatal("session was revoked although the role did not change")
+	}
+
+	update(manager.ID)
+	var revoked models.UserSession
+	db.First(&revoked, session.ID)
+	if revoked.RevokedAt == nil || revoked.RevokeReason != sessionRevokedRoleChanged {
+		t.Fatalf("expected the session to be revoked for the role change, got %+v", revoked)
+	}
+}
+
+// sessionFixture runs the auth routes behind the real AuthMiddleware for one
+// user with a known password.
+type sessionFixture struct {
+	db     *gorm.DB
+	router *gin.Engine
+	user   models.User
+}
+
+func newSessionFixture(t *testing.T) *sessionFixture {
+	t.Helper()
+	db := newTestDB(t)
+	cfg := &config.Config{JWTSecret: "test-secret", JWTAccessTTL: 15, RefreshTokenTTL: 24}
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	hash, err := utils.HashPassword("old-password")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{Email: "user@example.com", Password: hash, FullName: "User", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+
+	h := NewAuthHandler(db, cfg, notification.NewNotificationService(cfg, db))
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+	router.POST("/auth/refresh", h.RefreshToken)
+	router.POST("/auth/password/reset", h.ResetPassword)
+	protected := router.Group("", middleware.AuthMiddleware(cfg, db))
+	userOnly := middleware.RequireUserSession()
+	protected.GET("/auth/sessions", userOnly, h.ListSessions)
+	protected.POST("/auth/logout", userOnly, h.Logout)
+	protected.GET("/inventory", middleware.RequirePermission(db, "inventory.view"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	})
+	protected.POST("/inventory", middleware.RequirePermission(db, "inventory.create"), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	return &sessionFixture{db: db, router: router, user: user}
+}
+
+func (f *sessionFixture) login(t *testing.T, password string) sessionTokens {
+	t.Helper()
+	resp := serve(f.router, http.MethodPost, "/auth/login", gin.H{"email": f.user.Email, "password": password})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var tokens sessionTokens
+	resp.decode(t, &tokens)
+	// Session IDs repeat across test databases; drop what earlier tests cached.
+	var ids []uint
+	f.db.Model(&models.UserSession{}).Pluck("id", &ids)
+	middleware.InvalidateSessionCache(ids...)
+	return tokens
+}
+
+func (f *sessionFixture) refresh(refreshToken string) testResponse {
+	return serve(f.router, http.MethodPost, "/auth/refresh", gin.H{"refresh_token": refreshToken})
+}
+
+func (f *sessionFixture) sessions(token string) testResponse {
+	return serve(f.router, http.MethodGet, "/auth/sessions", nil, "Authorization", "Bearer "+token)
+}
+
+func TestRefreshRotatesTheToken(t *testing.T) {
+	f := newSessionFixture(t)
+	first := f.login(t, "old-password")
+
+	resp := f.refresh(first.RefreshToken)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var second sessionTokens
+	resp.decode(t, &second)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh did not rotate the refresh token")
+	}
+	if resp := f.sessions(second.Token); resp.Code != http.StatusOK {
+		t.Fatalf("new access token: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.refresh(second.RefreshToken); resp.Code != http.StatusOK {
+		t.Fatalf("refreshing the rotated token: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+
+	var count int64
+	f.db.Model(&models.UserSession{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("rotation must keep one session, got %d", count)
+	}
+}
+
+// TestReusedRefreshTokenRevokesTheSession checks that presenting a rotated
+// refresh token again revokes the session for both the thief and the owner.
+func TestReusedRefreshTokenRevokesTheSession(t *testing.T) {
+	f := newSessionFixture(t)
+	first := f.login(t, "old-password")
+	other := f.login(t, "old-password")
+
+	resp := f.refresh(first.RefreshToken)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var second sessionTokens
+	resp.decode(t, &second)
+
+	if resp := f.refresh(first.RefreshToken); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.refresh(second.RefreshToken); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reuse: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.sessions(second.Token); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("access token after reuse: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+
+	var revoked models.UserSession
+	f.db.Where("previous_token_hash = ?", hashSessionToken(first.RefreshToken)).First(&revoked)
+	if revoked.RevokedAt == nil || revoked.RevokeReason != sessionRevokedReuse {
+		t.Fatalf("expected the session to be revoked for reuse, got %+v", revoked)
+	}
+	// Other sessions of the user are untouched.
+	if resp := f.sessions(other.Token); resp.Code != http.StatusOK {
+		t.Fatalf("other session: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestLogoutRevokesTheSession(t *testing.T) {
+	f := newSessionFixture(t)
+	tokens := f.login(t, "old-password")
+
+	if resp := serve(f.router, http.MethodPost, "/auth/logout", nil, "Authorization", "Bearer "+tokens.Token); resp.Code != http.StatusOK {
+		t.Fatalf("logout: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.sessions(tokens.Token); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("access token after logout: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.refresh(tokens.RefreshToken); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after logout: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+// TestPasswordResetRevokesSessions checks that a reset token is single-use,
+// signs out every session and leaves only the new password working.
+func TestPasswordResetRevokesSessions(t *testing.T) {
+	f := newSessionFixture(t)
+	tokens := f.login(t, "old-password")
+	mustCreate(t, f.db, &models.PasswordReset{
+		UserID:    f.user.ID,
+		Channel:   resetChannelEmail,
+		TokenHash: hashSessionToken("reset-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	reset := gin.H{"token": "reset-token", "new_password": "new-password"}
+	if resp := serve(f.router, http.MethodPost, "/auth/password/reset", reset); resp.Code != http.StatusOK {
+		t.Fatalf("reset: expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := serve(f.router, http.MethodPost, "/auth/password/reset", reset); resp.Code != http.StatusBadRequest {
+		t.Fatalf("reusing the reset token: expected 400, got %d: %s", resp.Code, resp.Body)
+	}
+
+	if resp := f.sessions(tokens.Token); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("access token after reset: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := f.refresh(tokens.RefreshToken); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reset: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+	f.login(t, "new-password")
+	if resp := serve(f.router, http.MethodPost, "/auth/login", gin.H{"email": f.user.Email, "password": "old-password"}); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("old password: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+// TestAPIKeyAuthentication checks both ways of presenting a key, its
+// permissions, the user-session-only routes and revoked or expired keys.
+func TestAPIKeyAuthentication(t *testing.T) {
+	f := newSessionFixture(t)
+	key := newAPIKey(t, f.db, f.user, "inventory.view")
+
+	for _, header := range [][]string{{"X-API-Key", key}, {"Authorization", "Bearer " + key}} {
+		resp := serve(f.router, http.MethodGet, "/inventory", nil, header...)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", header[0], resp.Code, resp.Body)
+		}
+		var body struct {
+			UserID uint `json:"user_id"`
+		}
+		resp.decode(t, &body)
+		if body.UserID != f.user.ID {
+			t.Fatalf("%s: request ran as user %d, want the key creator %d", header[0], body.UserID, f.user.ID)
+		}
+	}
+
+	if resp := serve(f.router, http.MethodPost, "/inventory", nil, "X-API-Key", key); resp.Code != http.StatusForbidden {
+		t.Fatalf("permission the key lacks: expected 403, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := serve(f.router, http.MethodGet, "/auth/sessions", nil, "X-API-Key", key); resp.Code != http.StatusForbidden {
+		t.Fatalf("user-session route: expected 403, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := serve(f.router, http.MethodGet, "/inventory", nil, "X-API-Key", key+"x"); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+
+	var stored models.APIKey
+	f.db.Where("key_hash = ?", middleware.HashAPIKey(key)).First(&stored)
+	past := time.Now().Add(-time.Minute)
+	f.db.Model(&stored).Update("expires_at", past)
+	middleware.InvalidateAPIKeyCache(stored.ID)
+	if resp := serve(f.router, http.MethodGet, "/inventory", nil, "X-API-Key", key); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expired key: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+
+	f.db.Model(&stored).Updates(map[string]interface{}{"expires_at": nil, "revoked_at": past})
+	middleware.InvalidateAPIKeyCache(stored.ID)
+	if resp := serve(f.router, http.MethodGet, "/inventory", nil, "X-API-Key", key); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key: expected 401, got %d: %s", resp.Code, resp.Body)
+	}
+}
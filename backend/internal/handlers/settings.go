@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"tatapps/internal/config"
+	"tatapps/internal/middleware"
 	"tatapps/internal/models"
 	"tatapps/internal/services/notification"
 
@@ -271,23 +273,22 @@ func (h *SettingsHandler) UpdateSiteSettings(c *gin.Context) {
 	})
 }
 
-func (h *SettingsHandler) BackupDatabase(c *gin.Context) {
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("tatapps_backup_%s.sql", timestamp)
-
+// dumpDatabase runs pg_dump against the configured database and returns the
+// SQL script. Cancelling ctx kills pg_dump.
+func dumpDatabase(ctx context.Context, cfg *config.Config) ([]byte, error) {
 	args := []string{
 		"--clean",
 		"--if-exists",
 		"--no-owner",
 		"--no-privileges",
-		"--host", h.cfg.DBHost,
-		"--port", h.cfg.DBPort,
-		"--username", h.cfg.DBUser,
-		"--dbname", h.cfg.DBName,
+		"--host", cfg.DBHost,
+		"--port", cfg.DBPort,
+		"--username", cfg.DBUser,
+		"--dbname", cfg.DBName,
 	}
 
-	cmd := exec.Command("pg_dump", args...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", h.cfg.DBPassword))
+	cmd := exec.CommandContext(ctx, "pg_dump", args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", cfg.DBPassword))
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -298,7 +299,21 @@ func (h *SettingsHandler) BackupDatabase(c *gin.Context) {
 		if message == "" {
 			message = err.Error()
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create backup: %s", message)})
+		return nil, errors.New(message)
+	}
+	return output, nil
+}
+
+func backupFilename() string {
+	return fmt.Sprintf("tatapps_backup_%s.sql", time.Now().Format("20060102_150405"))
+}
+
+func (h *SettingsHandler) BackupDatabase(c *gin.Context) {
+	filename := backupFilename()
+
+	output, err := dumpDatabase(c.Request.Context(), h.cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create backup: %s", err.Error())})
 		return
 	}
 
@@ -350,7 +365,7 @@ func (h *SettingsHandler) RestoreDatabase(c *gin.Context) {
 		"--single-transaction",
 	}
 
-	cmd := exec.Command("psql", args...)
+	cmd := exec.CommandContext(c.Request.Context(), "psql", args...)
 	cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", h.cfg.DBPassword))
 	cmd.Stdin = restoreFile
 
@@ -367,6 +382,9 @@ func (h *SettingsHandler) RestoreDatabase(c *gin.Context) {
 		return
 	}
 
+	// Roles and permissions may differ in the restored data.
+	middleware.InvalidatePermissionCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Database restored successfully"})
 }
 
@@ -631,37 +649,47 @@ func (h *SettingsHandler) SendTestNotification(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	message := "This is a test notification from TatApps inventory system. If you receive this, your notification settings are working correctly."
 
-	var errors []string
-	whatsAppSent := false
+	var (
+		errors         []string
+		queued         []models.Notification
+		whatsAppQueued bool
+		emailQueued    bool
+	)
 
-	// Send WhatsApp notification
+	// Queue WhatsApp notification
 	if req.WhatsAppEnabled {
 		recipients := notification.SplitWhatsAppRecipients(req.WhatsAppNumber)
 		if len(recipients) == 0 {
 			errors = append(errors, "WhatsApp: no valid phone numbers provided")
 		} else {
 			for _, phone := range recipients {
-				if err := h.notif.WhatsApp.SendMessage(notification.WhatsAppMessage{
+				row, err := h.notif.Outbox.QueueWhatsApp(notification.WhatsAppMessage{
 					Phone:   phone,
 					Message: message,
-				}); err != nil {
+				}, "settings", &userID)
+				if err != nil {
 					errors = append(errors, fmt.Sprintf("WhatsApp (%s): %s", phone, err.Error()))
-				} else {
-					whatsAppSent = true
+					continue
 				}
+				queued = append(queued, *row)
+				whatsAppQueued = true
 			}
 		}
 	}
 
-	// Send Email notification
+	// Queue Email notification
 	if req.EmailEnabled && req.EmailAddress != "" {
-		if err := h.notif.Email.SendEmail(notification.EmailData{
+		row, err := h.notif.Outbox.QueueEmail(notification.EmailData{
 			To:      req.EmailAddress,
 			Subject: "Test Notification - TatApps",
 			Body:    message,
 			IsHTML:  false,
-		}); err != nil {
+		}, "settings", &userID)
+		if err != nil {
 			errors = append(errors, "Email: "+err.Error())
+		} else {
+			queued = append(queued, *row)
+			emailQueued = true
 		}
 	}
 
@@ -671,20 +699,23 @@ func (h *SettingsHandler) SendTestNotification(c *gin.Context) {
 		Type:         "test",
 		Title:        "Test Notification",
 		Message:      message,
-		WhatsAppSent: whatsAppSent,
-		EmailSent:    req.EmailEnabled && len(errors) == 0,
+		WhatsAppSent: whatsAppQueued,
+		EmailSent:    emailQueued,
 	})
 
 	if len(errors) > 0 {
 		c.JSON(http.StatusPartialContent, gin.H{
-			"message": "Some notifications failed to send",
+			"message": "Some notifications failed to queue",
 			"errors":  errors,
+			"data":    queued,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Test notification sent successfully",
+	// Delivery happens in the background; the outbox rows report the outcome.
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Test notification queued",
+		"data":    queued,
 	})
 }
 
@@ -719,7 +750,7 @@ type CheckLowStockRequest struct {
 	SendEmail    bool `json:"send_email"`
 }
 
-// CheckLowStock manually checks for low stock items and sends notifications
+// CheckLowStock manually checks for low stock items and queues notifications
 func (h *SettingsHandler) CheckLowStock(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
@@ -789,26 +820,26 @@ func (h *SettingsHandler) CheckLowStock(c *gin.Context) {
 	whatsappSent := false
 	emailSent := false
 
-	// Send WhatsApp to user's phone (from profile) - only if selected
+	// Queue WhatsApp to user's phone (from profile) - only if selected
 	if req.SendWhatsApp && user.Phone != "" {
-		if err := h.notif.WhatsApp.SendMessage(notification.WhatsAppMessage{
+		if _, err := h.notif.Outbox.QueueWhatsApp(notification.WhatsAppMessage{
 			Phone:   user.Phone,
 			Message: message,
-		}); err != nil {
+		}, "warehouse", &user.ID); err != nil {
 			errors = append(errors, "WhatsApp: "+err.Error())
 		} else {
 			whatsappSent = true
 		}
 	}
 
-	// Send Email to user's email (from profile) - only if selected
+	// Queue Email to user's email (from profile) - only if selected
 	if req.SendEmail && user.Email != "" {
-		if err := h.notif.Email.SendEmail(notification.EmailData{
+		if _, err := h.notif.Outbox.QueueEmail(notification.EmailData{
 			To:      user.Email,
 			Subject: fmt.Sprintf("Low Stock Alert - %d Items", lowStockCount),
 			Body:    message,
 			IsHTML:  false,
-		}); err != nil {
+		}, "warehouse", &user.ID); err != nil {
 			errors = append(errors, "Email: "+err.Error())
 		} else {
 			emailSent = true
@@ -827,7 +858,7 @@ func (h *SettingsHandler) CheckLowStock(c *gin.Context) {
 
 	if len(errors) > 0 {
 		c.JSON(http.StatusPartialContent, gin.H{
-			"message":         "Some notifications failed to send",
+			"message":         "Some notifications failed to queue",
 			"errors":          errors,
 			"low_stock_count": lowStockCount,
 			"items_sent":      whatsappSent || emailSent,
@@ -836,7 +867,7 @@ func (h *SettingsHandler) CheckLowStock(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":         "Low stock notifications sent successfully",
+		"message":         "Low stock notifications queued",
 		"low_stock_count": len(lowStock),
 		"whatsapp_sent":   whatsappSent,
 		"email_sent":      emailSent,
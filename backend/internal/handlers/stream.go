@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/middleware"
+	"tatapps/internal/services/realtime"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// streamHeartbeat keeps idle connections open through proxies.
+const streamHeartbeat = 25 * time.Second
+
+type StreamHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+	hub *realtime.Hub
+}
+
+func NewStreamHandler(db *gorm.DB, cfg *config.Config, hub *realtime.Hub) *StreamHandler {
+	return &StreamHandler{db: db, cfg: cfg, hub: hub}
+}
+
+// Stream sends live events as Server-Sent Events. Stock events need
+// inventory.view and follow the caller's warehouse scope; notification events
+// only reach their own user. ?events= limits the event types.
+//
+// The stream ends when the access token would have expired, so revoked sessions
+// and changed warehouse assignments take effect; clients reconnect with a
+// current token.
+func (h *StreamHandler) Stream(c *gin.Context) {
+	types := make(map[string]struct{})
+	if raw := strings.TrimSpace(c.Query("events")); raw != "" {
+		known := make(map[string]struct{}, len(realtime.Events))
+		for _, event := range realtime.Events {
+			known[event] = struct{}{}
+		}
+		for _, event := range strings.Split(raw, ",") {
+			event = strings.TrimSpace(event)
+			if event == "" {
+				continue
+			}
+			if _, ok := known[event]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event: " + event})
+				return
+			}
+			types[event] = struct{}{}
+		}
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	stock, err := middleware.HasPermission(c, h.db, "inventory.view")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+
+	filter := realtime.Filter{
+		Stock:      stock,
+		Restricted: scope.restricted,
+		Warehouses: scope.set,
+		Types:      types,
+	}
+	// API keys act as their creator but have no inbox of their own.
+	if c.GetUint(middleware.APIKeyIDContextKey) == 0 {
+		filter.UserID = c.GetUint("user_id")
+	}
+
+	sub := h.hub.Subscribe(filter)
+	defer h.hub.Unsubscribe(sub)
+
+	lifetime := time.Duration(h.cfg.JWTAccessTTL) * time.Minute
+	if lifetime <= 0 {
+		lifetime = 15 * time.Minute
+	}
+	expired := time.NewTimer(lifetime)
+	defer expired.Stop()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case message, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Type, message.Data)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		case <-expired.C:
+			fmt.Fprint(c.Writer, "event: reconnect\ndata: {}\n\n")
+			c.Writer.Flush()
+			return
+		}
+		c.Writer.Flush()
+	}
+}
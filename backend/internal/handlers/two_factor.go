@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"tatapps/internal/middleware"
+	"tatapps/internal/models"
+	"tatapps/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	preAuthTokenTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// twoFactorChallenge is returned by Login instead of tokens while the second step is pending.
+type twoFactorChallenge struct {
+	TwoFactorRequired      bool   `json:"two_factor_required"`
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
+	PreAuthToken           string `json:"pre_auth_token"`
+	ExpiresIn              int    `json:"expires_in"`
+}
+
+type twoFactorVerifyRequest struct {
+	PreAuthToken string `json:"pre_auth_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type twoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type twoFactorDisableRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// twoFactorRequired reports whether policy forces 2FA on the user's role.
+func (h *AuthHandler) twoFactorRequired(user *models.User) (bool, error) {
+	if !h.config.TwoFactorEnforced || len(h.config.TwoFactorSensitivePermissions) == 0 {
+		return false, nil
+	}
+	return middleware.RoleHasAnyPermission(h.db, user.RoleID, h.config.TwoFactorSensitivePermissions...)
+}
+
+// loginChallenge returns the challenge Login must send instead of tokens, or nil
+// when the password alone is enough.
+func (h *AuthHandler) loginChallenge(user *models.User) (*twoFactorChallenge, error) {
+	challenge := &twoFactorChallenge{ExpiresIn: int(preAuthTokenTTL.Seconds())}
+	if user.TwoFactorEnabled {
+		challenge.TwoFactorRequired = true
+	} else {
+		required, err := h.twoFactorRequired(user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		challenge.TwoFactorSetupRequired = true
+	}
+
+	token, err := utils.GeneratePreAuthToken(user.ID, h.config.JWTSecret, preAuthTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	challenge.PreAuthToken = token
+	return challenge, nil
+}
+
+// preAuthUser loads the active user behind a pre-auth token, writing a 401 when it is invalid.
+func (h *AuthHandler) preAuthUser(c *gin.Context, token string) (*models.User, bool) {
+	userID, err := utils.ValidatePreAuthToken(token, h.config.JWTSecret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired pre-auth token"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired pre-auth token"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// verifySecondFactor accepts a TOTP code newer than the last one used, or an unused recovery code.
+func verifySecondFactor(db *gorm.DB, user *models.User, code, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(recoveryCode) != "" {
+		result := db.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(recoveryCode)).
+			Update("used_at", time.Now())
+		return result.RowsAffected > 0, result.Error
+	}
+	return verifyTOTPCode(db, user, code)
+}
+
+func verifyTOTPCode(db *gorm.DB, user *models.User, code string) (bool, error) {
+	if user.TwoFactorSecret == "" {
+		return false, nil
+	}
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now())
+	if !ok || step <= user.TwoFactorLastStep {
+		return false, nil
+	}
+	result := db.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	user.TwoFactorLastStep = step
+	return result.RowsAffected > 0, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return hashSessionToken(normalized)
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and returns a new set in plain text.
+func replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for i := 0; i < recoveryCodeCount; i++ {
+			buf := make([]byte, 5)
+			if _, err := rand.Read(buf); err != nil {
+				return err
+			}
+			raw := hex.EncodeToString(buf)
+			code := raw[:5] + "-" + raw[5:]
+			if err := tx.Create(&models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}).Error; err != nil {
+				return err
+			}
+			codes = append(codes, code)
+		}
+		return nil
+	})
+	return codes, err
+}
+
+// startTwoFactorSetup stores a new pending secret and returns it with its provisioning URI.
+func (h *AuthHandler) startTwoFactorSetup(c *gin.Context, user *models.User) {
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"two_factor_secret": secret, "two_factor_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"secret":      secret,
+			"otpauth_uri": utils.TOTPProvisioningURI(h.config.AppName, user.Email, secret),
+		},
+	})
+}
+
+// confirmTwoFactorSetup enables 2FA once the first code checks out and returns recovery codes.
+func (h *AuthHandler) confirmTwoFactorSetup(c *gin.Context, user *models.User, code string) ([]string, bool) {
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return nil, false
+	}
+	if user.TwoFactorSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return nil, false
+	}
+	ok, err := verifyTOTPCode(h.db, user, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return nil, false
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return nil, false
+	}
+
+	now := time.Now()
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"two_factor_enabled": true, "two_factor_enabled_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return nil, false
+	}
+	codes, err := replaceRecoveryCodes(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return nil, false
+	}
+	return codes, true
+}
+
+func (h *AuthHandler) respondLogin(c *gin.Context, userID uint, extra gin.H) {
+	var user models.User
+	if err := h.db.Preload("Role").Preload("Role.Menus").Preload("Role.Permissions").
+		Preload("Warehouses").Preload("Warehouses.Warehouse").
+		First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
+	h.registerLoginSuccess(c, &user)
+	tokens, err := startSession(h.db, h.config, c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	user.Password = ""
+
+	if len(extra) == 0 {
+		c.JSON(http.StatusOK, LoginResponse{sessionTokens: tokens, User: user})
+		return
+	}
+	body := gin.H{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	}
+	for key, value := range extra {
+		body[key] = value
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// VerifyTwoFactor completes a two-step login with a TOTP or recovery code.
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req twoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.preAuthUser(c, req.PreAuthToken)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+
+	valid, err := verifySecondFactor(h.db, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		h.registerLoginFailure(c, user, loginReasonInvalidTwoFactor)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	h.respondLogin(c, user.ID, nil)
+}
+
+// EnrollTwoFactor starts the setup that policy requires before a login can finish.
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	var req struct {
+		PreAuthToken string `json:"pre_auth_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.preAuthUser(c, req.PreAuthToken)
+	if !ok {
+		return
+	}
+	h.startTwoFactorSetup(c, user)
+}
+
+// ConfirmTwoFactorEnrollment enables 2FA during login and signs the user in.
+func (h *AuthHandler) ConfirmTwoFactorEnrollment(c *gin.Context) {
+	var req twoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.preAuthUser(c, req.PreAuthToken)
+	if !ok {
+		return
+	}
+	codes, ok := h.confirmTwoFactorSetup(c, user, req.Code)
+	if !ok {
+		return
+	}
+	h.respondLogin(c, user.ID, gin.H{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// GetTwoFactorStatus reports whether 2FA is enabled or required for the current user.
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	required, err := h.twoFactorRequired(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor policy"})
+		return
+	}
+	var remaining int64
+	if err := h.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"enabled":                  user.TwoFactorEnabled,
+			"enabled_at":               user.TwoFactorEnabledAt,
+			"required":                 required,
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// SetupTwoFactor returns a new secret and provisioning URI for the current user.
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	h.startTwoFactorSetup(c, user)
+}
+
+// EnableTwoFactor confirms setup with a code and returns the recovery codes once.
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	codes, ok := h.confirmTwoFactorSetup(c, user, req.Code)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+		"data":    gin.H{"recovery_codes": codes},
+	})
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a second factor.
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req twoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	required, err := h.twoFactorRequired(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor policy"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	valid, err := verifySecondFactor(h.db, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	if err := clearTwoFactor(h.db, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after a TOTP check.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	valid, err := verifyTOTPCode(h.db, user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+func clearTwoFactor(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled":    false,
+			"two_factor_secret":     "",
+			"two_factor_last_step":  0,
+			"two_factor_enabled_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ResetUserTwoFactor removes a user's 2FA, e.g. after a lost device, and signs them out.
+func (h *UserHandler) ResetUserTwoFactor(c *gin.Context) {
+	var user models.User
+	if err := h.db.First(&user, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	if err := clearTwoFactor(h.db, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+	if err := revokeUserSessions(h.db, user.ID, sessionRevokedLogoutAll, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke user sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/utils"
+)
+
+// currentTOTP computes the code an authenticator app shows for secret right now.
+func currentTOTP(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// TestTOTPCodeCannotBeReplayed checks that a code is accepted once, also when
+// the second attempt loads a fresh copy of the user.
+func TestTOTPCodeCannotBeReplayed(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generate secret: %v", err)
+	}
+	user := models.User{Email: "user@example.com", Password: "x", FullName: "User", RoleID: role.ID, IsActive: true, TwoFactorEnabled: true, TwoFactorSecret: secret}
+	mustCreate(t, db, &user)
+	code := currentTOTP(t, secret)
+
+	if ok, err := verifyTOTPCode(db, &user, code); err != nil || !ok {
+		t.Fatalf("first use: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := verifyTOTPCode(db, &user, code); ok {
+		t.Fatal("the same code was accepted twice")
+	}
+	var reloaded models.User
+	db.First(&reloaded, user.ID)
+	if ok, _ := verifyTOTPCode(db, &reloaded, code); ok {
+		t.Fatal("the code was accepted again for a reloaded user")
+	}
+
+	// A stale copy still holding the old step is stopped by the database check.
+	stale := user
+	stale.TwoFactorLastStep = 0
+	if ok, _ := verifyTOTPCode(db, &stale, code); ok {
+		t.Fatal("the code was accepted again for a stale copy of the user")
+	}
+}
+
+// TestRecoveryCodeIsSingleUse checks that each recovery code signs in once,
+// in any formatting, and that the other codes stay usable.
+func TestRecoveryCodeIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "staff"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "user@example.com", Password: "x", FullName: "User", RoleID: role.ID, IsActive: true, TwoFactorEnabled: true}
+	mustCreate(t, db, &user)
+	codes, err := replaceRecoveryCodes(db, user.ID)
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("replace recovery codes: %v (%d codes)", err, len(codes))
+	}
+
+	if ok, err := verifySecondFactor(db, &user, "", codes[0]); err != nil || !ok {
+		t.Fatalf("first use: ok=%v err=%v", ok, err)
+	}
+	for _, reuse := range []string{codes[0], " " + codes[0][:5] + codes[0][6:] + " "} {
+		if ok, _ := verifySecondFactor(db, &user, "", reuse); ok {
+			t.Fatalf("recovery code %q was accepted twice", reuse)
+		}
+	}
+	if ok, err := verifySecondFactor(db, &user, "", codes[1]); err != nil || !ok {
+		t.Fatalf("another recovery code: ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UnitHandler struct {
+	db *gorm.DB
+}
+
+func NewUnitHandler(db *gorm.DB) *UnitHandler {
+	return &UnitHandler{db: db}
+}
+
+type unitRequest struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+type itemConversionInput struct {
+	UnitCode string  `json:"unit_code" binding:"required"`
+	Factor   float64 `json:"factor" binding:"required"`
+}
+
+func normalizeUnitCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// unitFactor returns how many base units one unitCode holds for the item.
+// An empty code or the item's own unit yields 1; other units must be defined
+// for the item and active in the catalogue.
+func unitFactor(db *gorm.DB, item *models.InventoryItem, unitCode string) (float64, error) {
+	code := normalizeUnitCode(unitCode)
+	if code == "" || code == normalizeUnitCode(item.Unit) {
+		return 1, nil
+	}
+
+	var conversion models.ItemUnitConversion
+	if err := db.Where("item_id = ? AND unit_code = ?", item.ID, code).First(&conversion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, newValidationError("Unit %q is not defined for item %s", unitCode, item.Name)
+		}
+		return 0, err
+	}
+	var active int64
+	if err := db.Model(&models.UnitOfMeasure{}).Where("code = ? AND is_active = ?", code, true).Count(&active).Error; err != nil {
+		return 0, err
+	}
+	if active == 0 {
+		return 0, newValidationError("Unit %q is inactive", unitCode)
+	}
+	return conversion.Factor, nil
+}
+
+// copyUnitConversions gives a new item the unit conversions of the item it was
+// created from, e.g. the destination item of a transfer.
+func copyUnitConversions(tx *gorm.DB, fromItemID, toItemID uint) error {
+	var conversions []models.ItemUnitConversion
+	if err := tx.Where("item_id = ?", fromItemID).Find(&conversions).Error; err != nil {
+		return err
+	}
+	for _, conversion := range conversions {
+		copied := models.ItemUnitConversion{ItemID: toItemID, UnitCode: conversion.UnitCode, Factor: conversion.Factor}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeTransactionUnit converts an entered quantity into the item's base unit
+// and keeps the original entry on the transaction.
+func normalizeTransactionUnit(db *gorm.DB, item *models.InventoryItem, transaction *models.InventoryTransaction) error {
+	factor, err := unitFactor(db, item, transaction.EntryUnit)
+	if err != nil {
+		return err
+	}
+	transaction.EntryQuantity = transaction.Quantity
+	if normalizeUnitCode(transaction.EntryUnit) == "" {
+		transaction.EntryUnit = item.Unit
+	} else {
+		transaction.EntryUnit = normalizeUnitCode(transaction.EntryUnit)
+	}
+	transaction.Quantity = transaction.Quantity * factor
+	return nil
+}
+
+// normalizePOItems fills BaseUnit/BaseQuantity on PO lines. Lines linked to an
+// inventory item are converted with the item's conversions; others keep their unit.
+func normalizePOItems(db *gorm.DB, lines []models.POItem) error {
+	for i := range lines {
+		line := &lines[i]
+		line.Unit = strings.TrimSpace(line.Unit)
+		if line.ItemID == nil {
+			line.BaseUnit = line.Unit
+			line.BaseQuantity = line.Quantity
+			continue
+		}
+
+		var item models.InventoryItem
+		if err := db.First(&item, *line.ItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newNotFoundError("Inventory item %d not found", *line.ItemID)
+			}
+			return err
+		}
+		factor, err := unitFactor(db, &item, line.Unit)
+		if err != nil {
+			return err
+		}
+		if line.Unit == "" {
+			line.Unit = item.Unit
+		}
+		line.BaseUnit = item.Unit
+		line.BaseQuantity = line.Quantity * factor
+	}
+	return nil
+}
+
+// formatConversions renders an item's conversions as "box=24; pack=6".
+func formatConversions(conversions []models.ItemUnitConversion) string {
+	parts := make([]string, 0, len(conversions))
+	for _, conversion := range conversions {
+		parts = append(parts, conversion.UnitCode+"="+formatFloat(conversion.Factor))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ListUnits returns the unit-of-measure catalogue.
+func (h *UnitHandler) ListUnits(c *gin.Context) {
+	query := h.db.Order("code ASC")
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var units []models.UnitOfMeasure
+	if err := query.Find(&units).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch units",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": units})
+}
+
+// CreateUnit adds a unit to the catalogue.
+func (h *UnitHandler) CreateUnit(c *gin.Context) {
+	var req unitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	unit := models.UnitOfMeasure{
+		Code:     normalizeUnitCode(req.Code),
+		Name:     strings.TrimSpace(req.Name),
+		IsActive: true,
+	}
+	if req.Description != nil {
+		unit.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.IsActive != nil {
+		unit.IsActive = *req.IsActive
+	}
+	if unit.Code == "" || unit.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code and name are required"})
+		return
+	}
+
+	var existing int64
+	h.db.Unscoped().Model(&models.UnitOfMeasure{}).Where("code = ?", unit.Code).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit code already exists"})
+		return
+	}
+
+	if err := h.db.Create(&unit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create unit",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    unit,
+		"message": "Unit created successfully",
+	})
+}
+
+// UpdateUnit changes a unit's name, description or status. The code is immutable
+// because item conversions reference it.
+func (h *UnitHandler) UpdateUnit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	var unit models.UnitOfMeasure
+	if err := h.db.First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unit"})
+		return
+	}
+
+	var req unitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if code := normalizeUnitCode(req.Code); code != "" && code != unit.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit code cannot be changed"})
+		return
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		unit.Name = name
+	}
+	if req.Description != nil {
+		unit.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.IsActive != nil {
+		unit.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(&unit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update unit",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    unit,
+		"message": "Unit updated successfully",
+	})
+}
+
+// DeleteUnit removes a unit that no item conversion uses.
+func (h *UnitHandler) DeleteUnit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	var unit models.UnitOfMeasure
+	if err := h.db.First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unit"})
+		return
+	}
+
+	var usage int64
+	h.db.Model(&models.ItemUnitConversion{}).Where("unit_code = ?", unit.Code).Count(&usage)
+	if usage > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit is used by item conversions"})
+		return
+	}
+
+	if err := h.db.Delete(&unit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete unit",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unit deleted successfully"})
+}
+
+// GetItemUnits returns an item's base unit and its conversions.
+func (h *UnitHandler) GetItemUnits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	var item models.InventoryItem
+	if err := scope.items(h.db.Preload("Conversions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("factor ASC")
+	})).First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"item_id":     item.ID,
+			"base_unit":   item.Unit,
+			"conversions": item.Conversions,
+		},
+	})
+}
+
+// SetItemUnits replaces an item's conversions.
+func (h *UnitHandler) SetItemUnits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req struct {
+		Conversions []itemConversionInput `json:"conversions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	item, ok := findScopedItem(h.db, c, uint(id))
+	if !ok {
+		return
+	}
+
+	baseUnit := normalizeUnitCode(item.Unit)
+	conversions := make([]models.ItemUnitConversion, 0, len(req.Conversions))
+	seen := make(map[string]struct{}, len(req.Conversions))
+	for _, input := range req.Conversions {
+		code := normalizeUnitCode(input.UnitCode)
+		if code == baseUnit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The base unit cannot have a conversion"})
+			return
+		}
+		if input.Factor <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Conversion factor must be greater than zero"})
+			return
+		}
+		if _, dup := seen[code]; dup {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate unit " + code})
+			return
+		}
+		seen[code] = struct{}{}
+
+		var unitCount int64
+		h.db.Model(&models.UnitOfMeasure{}).Where("code = ?", code).Count(&unitCount)
+		if unitCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unit " + code + " is not in the catalogue"})
+			return
+		}
+
+		conversions = append(conversions, models.ItemUnitConversion{
+			ItemID:   item.ID,
+			UnitCode: code,
+			Factor:   input.Factor,
+		})
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", item.ID).Delete(&models.ItemUnitConversion{}).Error; err != nil {
+			return err
+		}
+		if len(conversions) == 0 {
+			return nil
+		}
+		return tx.Create(&conversions).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save conversions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"item_id":     item.ID,
+			"base_unit":   item.Unit,
+			"conversions": conversions,
+		},
+		"message": "Unit conversions updated successfully",
+	})
+}
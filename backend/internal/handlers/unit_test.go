@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateUnitKeepsOmittedFields(t *testing.T) {
+	db := newTestDB(t)
+	unit := models.UnitOfMeasure{Code: "box", Name: "Box", Description: "Carton of 24", IsActive: true}
+	mustCreate(t, db, &unit)
+
+	router := gin.New()
+	router.PUT("/units/:id", NewUnitHandler(db).UpdateUnit)
+	path := fmt.Sprintf("/units/%d", unit.ID)
+
+	resp := serve(router, http.MethodPut, path, gin.H{"name": "Carton"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	var saved models.UnitOfMeasure
+	db.First(&saved, unit.ID)
+	if saved.Name != "Carton" || saved.Description != "Carton of 24" {
+		t.Fatalf("expected only the name to change, got %+v", saved)
+	}
+
+	serve(router, http.MethodPut, path, gin.H{"description": ""})
+	var cleared models.UnitOfMeasure
+	db.First(&cleared, unit.ID)
+	if cleared.Description != "" || cleared.Name != "Carton" {
+		t.Fatalf("expected an explicit empty description to clear it, got %+v", cleared)
+	}
+}
+
+// TestTransferCarriesUnitConversions checks that a transfer creating the
+// destination item copies the source conversions, and that a deactivated
+// catalogue unit can no longer be used for entries.
+func TestTransferCarriesUnitConversions(t *testing.T) {
+	db := newTestDB(t)
+	role := models.Role{Name: "admin"}
+	mustCreate(t, db, &role)
+	user := models.User{Email: "admin@example.com", Password: "x", FullName: "Admin", RoleID: role.ID, IsActive: true}
+	mustCreate(t, db, &user)
+	main := models.Warehouse{Code: "MAIN", Name: "Main", IsActive: true}
+	backup := models.Warehouse{Code: "BACKUP", Name: "Backup", IsActive: true}
+	mustCreate(t, db, &main, &backup)
+	box := models.UnitOfMeasure{Code: "box", Name: "Box", IsActive: true}
+	mustCreate(t, db, &box)
+	item := models.InventoryItem{WarehouseID: main.ID, SN: "BOXED", Name: "Boxed item", Unit: "pcs", Quantity: 48, IsActive: true}
+	mustCreate(t, db, &item)
+	mustCreate(t, db, &models.ItemUnitConversion{ItemID: item.ID, UnitCode: "box", Factor: 24})
+
+	router := gin.New()
+	router.POST("/inventory/items/:id/transactions", asUser(user), NewInventoryHandler(db, nil, nil, nil).RecordTransaction)
+	path := fmt.Sprintf("/inventory/items/%d/transactions", item.ID)
+
+	resp := serve(router, http.MethodPost, path, gin.H{"type": "transfer", "quantity": 1, "entry_unit": "box", "to_warehouse_id": backup.ID})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("transfer: expected 201, got %d: %s", resp.Code, resp.Body)
+	}
+	var dest models.InventoryItem
+	if err := db.Where("sku = ? AND warehouse_id = ?", "BOXED", backup.ID).First(&dest).Error; err != nil {
+		t.Fatalf("destination item: %v", err)
+	}
+	var conversion models.ItemUnitConversion
+	if err := db.Where("item_id = ? AND unit_code = ?", dest.ID, "box").First(&conversion).Error; err != nil || conversion.Factor != 24 {
+		t.Fatalf("expected the destination item to convert box=24, got %+v (%v)", conversion, err)
+	}
+	if dest.Quantity != 24 {
+		t.Fatalf("expected 24 pcs at the destination, got %v", dest.Quantity)
+	}
+
+	db.Model(&box).Update("is_active", false)
+	if resp := serve(router, http.MethodPost, path, gin.H{"type": "out", "quantity": 1, "entry_unit": "box"}); resp.Code != http.StatusBadRequest {
+		t.Fatalf("inactive unit: expected 400, got %d: %s", resp.Code, resp.Body)
+	}
+}
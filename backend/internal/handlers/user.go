@@ -3,17 +3,21 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"tatapps/internal/config"
+	"tatapps/internal/middleware"
 	"tatapps/internal/models"
 	"tatapps/internal/services/notification"
 	"tatapps/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserHandler struct {
@@ -31,11 +35,12 @@ func NewUserHandler(db *gorm.DB, notif *notification.NotificationService, cfg *c
 }
 
 type RoleRequest struct {
-	Name           string   `json:"name" binding:"required"`
-	Description    string   `json:"description"`
-	Color          string   `json:"color"`
-	MenuKeys       []string `json:"menu_keys"`
-	PermissionKeys []string `json:"permission_keys"`
+	Name            string   `json:"name" binding:"required"`
+	Description     string   `json:"description"`
+	Color           string   `json:"color"`
+	WarehouseScoped *bool    `json:"warehouse_scoped"`
+	MenuKeys        []string `json:"menu_keys"`
+	PermissionKeys  []string `json:"permission_keys"`
 }
 
 type MenuDefinition struct {
@@ -132,34 +137,234 @@ var permissionDefinitions = []PermissionDefinition{
 	{Name: "po.update", Description: "Update purchase order", Module: "po", Action: "update"},
 	{Name: "po.approve", Description: "Approve purchase order", Module: "po", Action: "approve"},
 	{Name: "po.delete", Description: "Delete purchase order", Module: "po", Action: "delete"},
+
+	// Settings & administration
+	{Name: "settings.manage", Description: "Manage site settings", Module: "settings", Action: "manage"},
+	{Name: "backup.run", Description: "Back up and restore the database", Module: "backup", Action: "run"},
+	{Name: "role.manage", Description: "Manage roles and their permissions", Module: "role", Action: "manage"},
+	{Name: "audit.view", Description: "View and export the audit log", Module: "audit", Action: "view"},
+	{Name: "api_key.manage", Description: "Manage API keys for integrations", Module: "api_key", Action: "manage"},
+	{Name: "webhook.manage", Description: "Manage outbound webhooks", Module: "webhook", Action: "manage"},
+	{Name: "notification.manage", Description: "View and requeue outgoing email and WhatsApp messages", Module: "notification", Action: "manage"},
+	{Name: "notification.send", Description: "Send test notifications and low stock summaries", Module: "notification", Action: "send"},
+}
+
+const notificationSendPermission = "notification.send"
+
+// openRouteGrants are the permissions that warehouse, purchase order and
+// notification routes began to require. Every role could use those routes before.
+var openRouteGrants = []string{"warehouse.view", "po.view", "po.create", "po.update", notificationSendPermission}
+
+// permissionMigration grants permissions to the roles that exist when it runs.
+type permissionMigration struct {
+	version uint
+	name    string
+	// admin grants every permission definition to the admin role.
+	admin bool
+	// roles maps a role name to the permissions it is granted.
+	roles map[string][]string
+	// allRoles is granted to every role.
+	allRoles []string
+}
+
+// permissionMigrations keep the access roles had before a route started to
+// require a permission. They run in order, once per database, and are recorded
+// in permission_migrations. Append new ones; never change applied versions.
+var permissionMigrations = []permissionMigration{
+	{
+		// Role-name checks were replaced by permission keys.
+		version: 1,
+		name:    "role_names_to_permissions",
+		admin:   true,
+		roles:   map[string][]string{"manager": {"warehouse.create", "warehouse.update", "po.approve"}},
+	},
+	{
+		// Warehouse, purchase order and notification routes were open to every role.
+		version:  2,
+		name:     "gate_open_routes",
+		allRoles: openRouteGrants,
+	},
+}
+
+// SyncPermissions makes sure every permission definition exists in the database,
+// restores soft-deleted ones and applies the pending permission migrations.
+// Permissions created here are granted to the admin role.
+func SyncPermissions(db *gorm.DB) error {
+	var created []models.Permission
+
+	for _, def := range permissionDefinitions {
+		var perm models.Permission
+		err := db.Unscoped().Where("name = ?", def.Name).First(&perm).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			perm = models.Permission{
+				Name:        def.Name,
+				Description: def.Description,
+				Module:      def.Module,
+				Action:      def.Action,
+			}
+			if err := db.Create(&perm).Error; err != nil {
+				return err
+			}
+			created = append(created, perm)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := make(map[string]interface{})
+		if perm.Description != def.Description {
+			updates["description"] = def.Description
+		}
+		if perm.Module != def.Module {
+			updates["module"] = def.Module
+		}
+		if perm.Action != def.Action {
+			updates["action"] = def.Action
+		}
+		if perm.DeletedAt.Valid {
+			updates["deleted_at"] = nil
+		}
+		if len(updates) > 0 {
+			if err := db.Unscoped().Model(&perm).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(created) > 0 {
+		var admin models.Role
+		err := db.Where("name = ?", "admin").First(&admin).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if err := db.Model(&admin).Association("Permissions").Append(&created); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := applyPermissionMigrations(db); err != nil {
+		return err
+	}
+	middleware.InvalidatePermissionCache()
+	return nil
+}
+
+func applyPermissionMigrations(db *gorm.DB) error {
+	var applied []uint
+	if err := db.Model(&models.PermissionMigration{}).Pluck("version", &applied).Error; err != nil {
+		return err
+	}
+	done := make(map[uint]struct{}, len(applied))
+	for _, version := range applied {
+		done[version] = struct{}{}
+	}
+
+	for _, migration := range permissionMigrations {
+		if _, ok := done[migration.version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Another instance may be starting at the same time; the first to
+			// record the version applies it.
+			record := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PermissionMigration{
+				Version:   migration.version,
+				Name:      migration.name,
+				AppliedAt: time.Now(),
+			})
+			if record.Error != nil || record.RowsAffected == 0 {
+				return record.Error
+			}
+			return migration.apply(tx)
+		})
+		if err != nil {
+			return fmt.Errorf("permission migration %d (%s): %w", migration.version, migration.name, err)
+		}
+	}
+	return nil
+}
+
+func (m permissionMigration) apply(db *gorm.DB) error {
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err != nil {
+		return err
+	}
+	for i := range roles {
+		keys := append([]string{}, m.allRoles...)
+		keys = append(keys, m.roles[roles[i].Name]...)
+		if m.admin && roles[i].Name == "admin" {
+			for _, def := range permissionDefinitions {
+				keys = append(keys, def.Name)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		var permissions []models.Permission
+		if err := db.Where("name IN ?", keys).Find(&permissions).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&roles[i]).Association("Permissions").Append(&permissions); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetAll returns all users with their roles
 func (h *UserHandler) GetAll(c *gin.Context) {
-	var users []models.User
-
-	// Get query parameters for filtering
-	roleID := c.Query("role_id")
+	params, err := parseListParams(c, userListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	query := h.db.Preload("Role").Preload("Role.Menus").Preload("Role.Permissions").
-		Preload("Warehouses").Preload("Warehouses.Warehouse")
+	query := h.db.Model(&models.User{})
 
 	// Filter by role if provided
-	if roleID != "" {
+	if roleID := c.Query("role_id"); roleID != "" {
 		query = query.Where("role_id = ?", roleID)
 	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("(full_name ILIKE ? OR email ILIKE ?)", like, like)
+	}
 
-	if err := query.Find(&users).Error; err != nil {
+	total, err := countList(query)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
+	var users []models.User
+	query = query.Preload("Role").Preload("Role.Menus").Preload("Role.Permissions").
+		Preload("Warehouses").Preload("Warehouses.Warehouse")
+	if err := params.apply(query, userListSort).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	keep, meta := params.finish(c, total, len(users), func(i int) uint { return users[i].ID })
+	users = users[:keep]
+
 	// Remove password from response
 	for i := range users {
 		users[i].Password = ""
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": users})
+	c.JSON(http.StatusOK, gin.H{"data": users, "meta": meta})
+}
+
+var userListSort = listSort{
+	table: "users",
+	fields: map[string]string{
+		"id":         "users.id",
+		"full_name":  "users.full_name",
+		"email":      "users.email",
+		"created_at": "users.created_at",
+	},
+	defaultSort: "id",
 }
 
 // GetByID returns a single user by ID
@@ -276,12 +481,14 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 			appURL,
 		)
 
-		if err := h.notif.WhatsApp.SendMessage(notification.WhatsAppMessage{
-			Phone:   req.Phone,
-			Message: welcomeMessage,
-		}); err != nil {
+		// The message holds the temporary password, so the outbox never lists it.
+		if _, err := h.notif.Outbox.QueueWhatsApp(notification.WhatsAppMessage{
+			Phone:     req.Phone,
+			Message:   welcomeMessage,
+			Sensitive: true,
+		}, "user", &user.ID); err != nil {
 			// Log error but don't fail the user creation
-			fmt.Printf("Failed to send welcome message: %v\n", err)
+			log.Printf("[user] failed to queue welcome message for user %d: %v", user.ID, err)
 		}
 	}
 
@@ -336,6 +543,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	user.FullName = strings.TrimSpace(req.FullName)
 	user.Email = strings.TrimSpace(req.Email)
 	user.Phone = strings.TrimSpace(req.Phone)
+	roleChanged := user.RoleID != req.RoleID
 	user.RoleID = req.RoleID
 
 	if req.Password != nil && strings.TrimSpace(*req.Password) != "" {
@@ -357,6 +565,21 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// Sessions carry the role the user signed in with; a new password or role
+	// makes them sign in again.
+	revokeReason := ""
+	if req.Password != nil && strings.TrimSpace(*req.Password) != "" {
+		revokeReason = sessionRevokedPasswordChanged
+	} else if roleChanged {
+		revokeReason = sessionRevokedRoleChanged
+	}
+	if revokeReason != "" {
+		if err := revokeUserSessions(h.db, user.ID, revokeReason, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke user sessions"})
+			return
+		}
+	}
+
 	if err := h.syncUserWarehouses(user.ID, req.WarehouseIDs); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update warehouse access"})
 		return
@@ -404,6 +627,15 @@ func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
+	if !user.IsActive {
+		if err := revokeUserSessions(h.db, user.ID, sessionRevokedDeactivated, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke user sessions"})
+			return
+		}
+		// API keys created by the user stop working with the account.
+		middleware.InvalidateAPIKeyCache()
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "User status updated successfully"})
 }
 
@@ -433,45 +665,52 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	if err := revokeUserSessions(h.db, user.ID, sessionRevokedDeleted, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke user sessions"})
+		return
+	}
+	middleware.InvalidateAPIKeyCache()
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
 func (h *UserHandler) syncUserWarehouses(userID uint, warehouseIDs []uint) error {
 	return h.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserWarehouse{}).Error; err != nil {
-			return err
-		}
+		return replaceUserWarehouses(tx, userID, warehouseIDs)
+	})
+}
 
-		if len(warehouseIDs) == 0 {
-			return nil
-		}
+// replaceUserWarehouses sets the user's warehouse assignments to warehouseIDs.
+func replaceUserWarehouses(tx *gorm.DB, userID uint, warehouseIDs []uint) error {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserWarehouse{}).Error; err != nil {
+		return err
+	}
 
-		unique := make(map[uint]struct{})
-		for _, id := range warehouseIDs {
-			if id == 0 {
-				continue
-			}
-			unique[id] = struct{}{}
-		}
+	if len(warehouseIDs) == 0 {
+		return nil
+	}
 
-		if len(unique) == 0 {
-			return nil
+	unique := make(map[uint]struct{})
+	for _, id := range warehouseIDs {
+		if id == 0 {
+			continue
 		}
+		unique[id] = struct{}{}
+	}
 
-		records := make([]models.UserWarehouse, 0, len(unique))
-		for id := range unique {
-			records = append(records, models.UserWarehouse{
-				UserID:      userID,
-				WarehouseID: id,
-			})
-		}
+	if len(unique) == 0 {
+		return nil
+	}
 
-		if err := tx.Create(&records).Error; err != nil {
-			return err
-		}
+	records := make([]models.UserWarehouse, 0, len(unique))
+	for id := range unique {
+		records = append(records, models.UserWarehouse{
+			UserID:      userID,
+			WarehouseID: id,
+		})
+	}
 
-		return nil
-	})
+	return tx.Create(&records).Error
 }
 
 const defaultRoleColor = "#2563EB"
@@ -662,6 +901,9 @@ func (h *UserHandler) CreateRole(c *gin.Context) {
 		Description: strings.TrimSpace(req.Description),
 		Color:       color,
 	}
+	if req.WarehouseScoped != nil {
+		role.WarehouseScoped = *req.WarehouseScoped
+	}
 
 	tx := h.db.Begin()
 	if err := tx.Create(&role).Error; err != nil {
@@ -691,6 +933,8 @@ func (h *UserHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
+	middleware.InvalidatePermissionCache(role.ID)
+
 	if err := h.db.Preload("Menus").Preload("Permissions").First(&role, role.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load role"})
 		return
@@ -753,12 +997,16 @@ func (h *UserHandler) UpdateRole(c *gin.Context) {
 	role.Name = name
 	role.Description = strings.TrimSpace(req.Description)
 	role.Color = color
+	if req.WarehouseScoped != nil {
+		role.WarehouseScoped = *req.WarehouseScoped
+	}
 
 	tx := h.db.Begin()
 	if err := tx.Model(&role).Updates(map[string]interface{}{
-		"name":        role.Name,
-		"description": role.Description,
-		"color":       role.Color,
+		"name":             role.Name,
+		"description":      role.Description,
+		"color":            role.Color,
+		"warehouse_scoped": role.WarehouseScoped,
 	}).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(strings.ToLower(err.Error()), "duplicate") {
@@ -786,6 +1034,8 @@ func (h *UserHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	middleware.InvalidatePermissionCache(role.ID)
+
 	if err := h.db.Preload("Menus").Preload("Permissions").First(&role, role.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load role"})
 		return
@@ -855,6 +1105,8 @@ func (h *UserHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
+	middleware.InvalidatePermissionCache(role.ID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
 }
 
@@ -863,45 +1115,9 @@ func (h *UserHandler) GetRoleMenuOptions(c *gin.Context) {
 }
 
 func (h *UserHandler) GetRolePermissionOptions(c *gin.Context) {
-	for _, def := range permissionDefinitions {
-		var perm models.Permission
-		err := h.db.Unscoped().Where("name = ?", def.Name).First(&perm).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			perm = models.Permission{
-				Name:        def.Name,
-				Description: def.Description,
-				Module:      def.Module,
-				Action:      def.Action,
-			}
-			if err := h.db.Create(&perm).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure permission definitions"})
-				return
-			}
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure permission definitions"})
-			return
-		} else {
-			updates := make(map[string]interface{})
-			if perm.Description != def.Description {
-				updates["description"] = def.Description
-			}
-			if perm.Module != def.Module {
-				updates["module"] = def.Module
-			}
-			if perm.Action != def.Action {
-				updates["action"] = def.Action
-			}
-			if perm.DeletedAt.Valid {
-				updates["deleted_at"] = nil
-			}
-
-			if len(updates) > 0 {
-				if err := h.db.Unscoped().Model(&perm).Updates(updates).Error; err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure permission definitions"})
-					return
-				}
-			}
-		}
+	if err := SyncPermissions(h.db); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure permission definitions"})
+		return
 	}
 
 	var permissions []models.Permission
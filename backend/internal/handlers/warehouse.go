@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"tatapps/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -17,26 +18,74 @@ func NewWarehouseHandler(db *gorm.DB) *WarehouseHandler {
 	return &WarehouseHandler{db: db}
 }
 
+var warehouseListSort = listSort{
+	table: "warehouses",
+	fields: map[string]string{
+		"id":         "warehouses.id",
+		"code":       "warehouses.code",
+		"name":       "warehouses.name",
+		"city":       "warehouses.city",
+		"created_at": "warehouses.created_at",
+	},
+	defaultSort: "id",
+}
+
 func (h *WarehouseHandler) GetAll(c *gin.Context) {
+	params, err := parseListParams(c, warehouseListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
+	query := scope.warehouses(h.db.Model(&models.Warehouse{}))
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("(code ILIKE ? OR name ILIKE ? OR city ILIKE ?)", like, like, like)
+	}
+	if active := strings.TrimSpace(c.Query("is_active")); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch warehouses",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	var warehouses []models.Warehouse
-	if err := h.db.Preload("Manager").Find(&warehouses).Error; err != nil {
+	if err := params.apply(query.Preload("Manager"), warehouseListSort).Find(&warehouses).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch warehouses",
 			"message": err.Error(),
 		})
 		return
 	}
+	keep, meta := params.finish(c, total, len(warehouses), func(i int) uint { return warehouses[i].ID })
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": warehouses,
+		"data": warehouses[:keep],
+		"meta": meta,
 	})
 }
 
 func (h *WarehouseHandler) GetByID(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
 	var warehouse models.Warehouse
-	if err := h.db.Preload("Manager").First(&warehouse, id).Error; err != nil {
+	if err := scope.warehouses(h.db.Preload("Manager")).First(&warehouse, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Warehouse not found",
 		})
@@ -69,8 +118,13 @@ func (h *WarehouseHandler) Create(c *gin.Context) {
 func (h *WarehouseHandler) Update(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+
 	var warehouse models.Warehouse
-	if err := h.db.First(&warehouse, id).Error; err != nil {
+	if err := scope.warehouses(h.db).First(&warehouse, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Warehouse not found"})
 		return
 	}
@@ -94,6 +148,15 @@ func (h *WarehouseHandler) Update(c *gin.Context) {
 func (h *WarehouseHandler) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 
+	scope, ok := requireWarehouseScope(h.db, c)
+	if !ok {
+		return
+	}
+	if !scope.allows(uint(id)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Warehouse not found"})
+		return
+	}
+
 	if err := h.db.Delete(&models.Warehouse{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
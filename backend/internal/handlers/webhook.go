@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	db         *gorm.DB
+	dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookHandler(db *gorm.DB, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{db: db, dispatcher: dispatcher}
+}
+
+type webhookRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// webhookResponse adds the decoded event filters to a subscription.
+type webhookResponse struct {
+	models.WebhookSubscription
+	Events []string `json:"events"`
+}
+
+func newWebhookResponse(subscription models.WebhookSubscription) webhookResponse {
+	events := []string{}
+	_ = json.Unmarshal([]byte(subscription.Events), &events)
+	return webhookResponse{WebhookSubscription: subscription, Events: events}
+}
+
+// sanitizeWebhookRequest validates the URL and event filters and returns the encoded filters.
+func sanitizeWebhookRequest(req webhookRequest) (string, error) {
+	target, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+
+	filters := make([]string, 0, len(req.Events))
+	seen := make(map[string]struct{})
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !webhook.ValidFilter(event) {
+			return "", fmt.Errorf("unknown event: %s", event)
+		}
+		if _, ok := seen[event]; ok {
+			continue
+		}
+		seen[event] = struct{}{}
+		filters = append(filters, event)
+	}
+	if len(filters) == 0 {
+		return "", errors.New("at least one event is required")
+	}
+	encoded, _ := json.Marshal(filters)
+	return string(encoded), nil
+}
+
+func (h *WebhookHandler) findSubscription(c *gin.Context) (*models.WebhookSubscription, bool) {
+	var subscription models.WebhookSubscription
+	if err := h.db.First(&subscription, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		return nil, false
+	}
+	return &subscription, true
+}
+
+// ListWebhookEvents returns the event names subscriptions can filter on.
+func (h *WebhookHandler) ListWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": webhook.Events})
+}
+
+// ListWebhooks returns every subscription. Secrets are never shown again.
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	if err := h.db.Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch webhooks",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	data := make([]webhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		data = append(data, newWebhookResponse(subscription))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateWebhook adds a subscription. The signing secret is only part of this response.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := sanitizeWebhookRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+
+	subscription := models.WebhookSubscription{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		URL:         strings.TrimSpace(req.URL),
+		Secret:      secret,
+		Events:      events,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedByID: c.GetUint("user_id"),
+	}
+	if err := h.db.Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+	// Create stores the zero value of IsActive as the column default.
+	if !subscription.IsActive {
+		h.db.Model(&subscription).Update("is_active", false)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully. Store the secret now, it will not be shown again",
+		"secret":  secret,
+		"data":    newWebhookResponse(subscription),
+	})
+}
+
+// UpdateWebhook replaces the name, URL, event filters and active flag.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	subscription, ok := h.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := sanitizeWebhookRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":        strings.TrimSpace(req.Name),
+		"description": strings.TrimSpace(req.Description),
+		"url":         strings.TrimSpace(req.URL),
+		"events":      events,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if err := h.db.Model(subscription).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.db.First(subscription, subscription.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"data":    newWebhookResponse(*subscription),
+	})
+}
+
+// DeleteWebhook removes a subscription and cancels its pending deliveries.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	subscription, ok := h.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", subscription.ID, webhook.StatusPending).
+			Updates(map[string]interface{}{"status": webhook.StatusFailed, "error": "webhook deleted"}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// RotateWebhookSecret issues a new signing secret; the old one stops working at once.
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	subscription, ok := h.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+	if err := h.db.Model(subscription).Update("secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate webhook secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook secret rotated. Store the secret now, it will not be shown again",
+		"secret":  secret,
+	})
+}
+
+// TestWebhook queues a "ping" event for the subscription.
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	subscription, ok := h.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Ping(*subscription)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue test event",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Test event queued",
+		"data":    delivery,
+	})
+}
+
+var webhookDeliveryListSort = listSort{
+	table:       "webhook_deliveries",
+	fields:      map[string]string{"id": "webhook_deliveries.id", "created_at": "webhook_deliveries.created_at"},
+	defaultSort: "created_at",
+	defaultDesc: true,
+}
+
+// ListWebhookDeliveries returns the delivery log of a subscription, newest first.
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	subscription, ok := h.findSubscription(c)
+	if !ok {
+		return
+	}
+	params, err := parseListParams(c, webhookDeliveryListSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscription.ID)
+	switch status := c.Query("status"); status {
+	case "":
+	case webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use pending, delivered or failed"})
+		return
+	}
+	if event := strings.TrimSpace(c.Query("event")); event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	total, err := countList(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := params.apply(query, webhookDeliveryListSort).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+	keep, meta := params.finish(c, total, len(deliveries), func(i int) uint { return deliveries[i].ID })
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries[:keep], "meta": meta})
+}
+
+// RedeliverWebhook queues a delivery again, e.g. after the receiver was fixed.
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	var delivery models.WebhookDelivery
+	if err := h.db.Where("subscription_id = ?", c.Param("id")).First(&delivery, c.Param("deliveryId")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook delivery"})
+		return
+	}
+	if delivery.Status == webhook.StatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Delivery is already queued"})
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	if err := h.db.First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	if err := h.dispatcher.Redeliver(&delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue delivery",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued"})
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"tatapps/internal/models"
+	"tatapps/internal/services/realtime"
+	"tatapps/internal/services/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook payloads carry a stable summary of each entity rather than the API
+// response, so receivers are not affected by preloaded relations.
+
+func webhookItemData(item models.InventoryItem) gin.H {
+	return gin.H{
+		"id":           item.ID,
+		"sn":           item.SN,
+		"name":         item.Name,
+		"category":     item.Category,
+		"unit":         item.Unit,
+		"warehouse_id": item.WarehouseID,
+		"quantity":     item.Quantity,
+		"min_stock":    item.MinStock,
+		"max_stock":    item.MaxStock,
+	}
+}
+
+func webhookTransactionData(transaction models.InventoryTransaction) gin.H {
+	return gin.H{
+		"id":                transaction.ID,
+		"type":              transaction.Type,
+		"item_id":           transaction.ItemID,
+		"quantity":          transaction.Quantity,
+		"entry_unit":        transaction.EntryUnit,
+		"entry_quantity":    transaction.EntryQuantity,
+		"from_warehouse_id": transaction.FromWarehouseID,
+		"to_warehouse_id":   transaction.ToWarehouseID,
+		"from_location_id":  transaction.FromLocationID,
+		"to_location_id":    transaction.ToLocationID,
+		"reservation_id":    transaction.ReservationID,
+		"reference":         transaction.Reference,
+		"notes":             transaction.Notes,
+		"created_by_id":     transaction.CreatedByID,
+		"created_at":        transaction.CreatedAt,
+	}
+}
+
+func webhookPOData(po models.PurchaseOrder, previousStatus string) gin.H {
+	return gin.H{
+		"id":               po.ID,
+		"po_number":        po.PONumber,
+		"po_date":          po.PODate,
+		"supplier_name":    po.SupplierName,
+		"status":           po.Status,
+		"previous_status":  previousStatus,
+		"priority":         po.Priority,
+		"total_amount":     po.TotalAmount,
+		"warehouse_id":     po.WarehouseID,
+		"project_id":       po.ProjectID,
+		"requested_by_id":  po.RequestedByID,
+		"approved_by_id":   po.ApprovedByID,
+		"approved_at":      po.ApprovedAt,
+		"rejection_reason": po.RejectionReason,
+	}
+}
+
+// webhookEmployeeData leaves out identity numbers, addresses and salary.
+func webhookEmployeeData(employee models.Employee) gin.H {
+	return gin.H{
+		"id":              employee.ID,
+		"employee_code":   employee.EmployeeCode,
+		"full_name":       employee.FullName,
+		"email":           employee.Email,
+		"phone":           employee.Phone,
+		"department":      employee.Department,
+		"job_title":       employee.JobTitle,
+		"employment_type": employee.EmploymentType,
+		"status":          employee.Status,
+		"join_date":       employee.JoinDate,
+		"division_id":     employee.DivisionID,
+		"position_id":     employee.PositionID,
+		"warehouse_id":    employee.WarehouseID,
+	}
+}
+
+// lowStockCrossed reports whether a stock change took the item from above its
+// minimum stock to at or below it.
+func lowStockCrossed(before, after, minStock float64) bool {
+	return minStock > 0 && before > minStock && after <= minStock
+}
+
+// publishStockChange sends a transaction event to webhooks and the stream and,
+// when the item crossed its minimum stock, a low-stock event and inbox
+// notification.
+func (h *InventoryHandler) publishStockChange(event string, transaction models.InventoryTransaction, item models.InventoryItem, quantityBefore float64) {
+	action := "created"
+	if event == webhook.EventTransactionDeleted {
+		action = "deleted"
+	}
+	h.webhooks.Publish(event, gin.H{
+		"transaction":     webhookTransactionData(transaction),
+		"item":            webhookItemData(item),
+		"quantity_before": quantityBefore,
+	})
+	h.stream.Publish(realtime.EventStockTransaction, gin.H{
+		"action":          action,
+		"transaction":     webhookTransactionData(transaction),
+		"item":            webhookItemData(item),
+		"quantity_before": quantityBefore,
+	}, realtime.Target{WarehouseIDs: stockEventWarehouses(transaction, item)})
+	if lowStockCrossed(quantityBefore, item.Quantity, item.MinStock) {
+		h.webhooks.Publish(webhook.EventItemLowStock, gin.H{"item": webhookItemData(item)})
+		streamLowStock(h.stream, item)
+		notifyLowStock(h.inbox, item)
+	}
+}
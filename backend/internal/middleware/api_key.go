@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, so they can be told apart from access tokens.
+const APIKeyPrefix = "tak_"
+
+// APIKeyIDContextKey holds the ID of the API key that authenticated the request.
+const APIKeyIDContextKey = "api_key_id"
+
+const apiKeyAccessContextKey = "api_key_access"
+
+// apiKeyCacheTTL bounds how long a key is trusted without a lookup. Edits and
+// revocations on this instance invalidate the cache right away.
+const apiKeyCacheTTL = 30 * time.Second
+
+// apiKeyUsageInterval throttles the last-used bookkeeping to one write per key.
+const apiKeyUsageInterval = time.Minute
+
+type apiKeyState struct {
+	id           uint
+	valid        bool
+	userID       uint
+	email        string
+	roleID       uint
+	permissions  map[string]struct{}
+	warehouseIDs []uint
+	expiresAt    *time.Time
+	loadedAt     time.Time
+	usedAt       time.Time
+}
+
+var apiKeyCache = struct {
+	sync.RWMutex
+	keys map[string]*apiKeyState
+}{keys: make(map[string]*apiKeyState)}
+
+// HashAPIKey returns the stored form of a plain API key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// InvalidateAPIKeyCache drops the cached state of the given keys, or of every
+// key when called without IDs.
+func InvalidateAPIKeyCache(keyIDs ...uint) {
+	apiKeyCache.Lock()
+	defer apiKeyCache.Unlock()
+	if len(keyIDs) == 0 {
+		apiKeyCache.keys = make(map[string]*apiKeyState)
+		return
+	}
+	for hash, state := range apiKeyCache.keys {
+		for _, id := range keyIDs {
+			if state.id == id {
+				delete(apiKeyCache.keys, hash)
+				break
+			}
+		}
+	}
+}
+
+// APIKeyWarehouseScope returns the warehouse restriction of an API key request.
+// ok is false when the request was authenticated with an access token.
+func APIKeyWarehouseScope(c *gin.Context) (ids []uint, scoped bool, ok bool) {
+	value, exists := c.Get(apiKeyAccessContextKey)
+	if !exists {
+		return nil, false, false
+	}
+	access, ok := value.(apiKeyAccess)
+	if !ok {
+		return nil, false, false
+	}
+	// The key's warehouse list only narrows its scope; it grants nothing.
+	return access.warehouseIDs, access.warehouseScoped || len(access.warehouseIDs) > 0, true
+}
+
+// RequireUserSession rejects API keys on endpoints that act on the signed-in
+// user, such as the profile, sessions and key management.
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetUint(APIKeyIDContextKey) != 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available to API keys"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKeyAccess is the effective access of the key on the current request.
+type apiKeyAccess struct {
+	roleAccess
+	warehouseIDs []uint
+}
+
+// authenticateAPIKey validates an API key and fills the request context the same
+// way an access token does.
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, key string) {
+	state, err := loadAPIKey(db, HashAPIKey(key))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		c.Abort()
+		return
+	}
+	if !state.valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API key"})
+		c.Abort()
+		return
+	}
+	if state.expiresAt != nil && !time.Now().Before(*state.expiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has expired"})
+		c.Abort()
+		return
+	}
+
+	access := apiKeyAccess{warehouseIDs: state.warehouseIDs}
+	if state.roleID != 0 {
+		role, err := loadRoleAccess(db, state.roleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+			c.Abort()
+			return
+		}
+		if !role.exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API key"})
+			c.Abort()
+			return
+		}
+		access.roleAccess = role
+	} else {
+		access.roleAccess = roleAccess{exists: true, permissions: state.permissions}
+	}
+
+	touchAPIKey(db, state, c.ClientIP())
+
+	c.Set("user_id", state.userID)
+	c.Set("email", state.email)
+	c.Set("role_id", state.roleID)
+	c.Set(APIKeyIDContextKey, state.id)
+	c.Set(apiKeyAccessContextKey, access)
+
+	c.Next()
+}
+
+func loadAPIKey(db *gorm.DB, hash string) (*apiKeyState, error) {
+	apiKeyCache.RLock()
+	state, ok := apiKeyCache.keys[hash]
+	apiKeyCache.RUnlock()
+	if ok && time.Since(state.loadedAt) < apiKeyCacheTTL {
+		return state, nil
+	}
+
+	var row struct {
+		ID          uint
+		RoleID      *uint
+		CreatedByID uint
+		Email       string
+		ExpiresAt   *time.Time
+	}
+	result := db.Table("api_keys").
+		Select("api_keys.id", "api_keys.role_id", "api_keys.created_by_id", "users.email", "api_keys.expires_at").
+		Joins("JOIN users ON users.id = api_keys.created_by_id AND users.deleted_at IS NULL").
+		Where("api_keys.key_hash = ? AND api_keys.deleted_at IS NULL AND api_keys.revoked_at IS NULL AND users.is_active = ?", hash, true).
+		Limit(1).
+		Scan(&row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	fresh := &apiKeyState{
+		id:          row.ID,
+		valid:       result.RowsAffected > 0,
+		userID:      row.CreatedByID,
+		email:       row.Email,
+		expiresAt:   row.ExpiresAt,
+		permissions: make(map[string]struct{}),
+		loadedAt:    time.Now(),
+	}
+	if state != nil {
+		fresh.usedAt = state.usedAt
+	}
+	if fresh.valid {
+		if row.RoleID != nil {
+			fresh.roleID = *row.RoleID
+		} else {
+			var names []string
+			if err := db.
+				Table("api_key_permissions").
+				Joins("JOIN permissions ON permissions.id = api_key_permissions.permission_id").
+				Where("api_key_permissions.api_key_id = ? AND permissions.deleted_at IS NULL", row.ID).
+				Pluck("permissions.name", &names).Error; err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				fresh.permissions[name] = struct{}{}
+			}
+		}
+		if err := db.
+			Table("api_key_warehouses").
+			Where("api_key_id = ?", row.ID).
+			Order("warehouse_id").
+			Pluck("warehouse_id", &fresh.warehouseIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Unknown keys are not cached, so guessing cannot grow the cache.
+	if fresh.valid {
+		apiKeyCache.Lock()
+		apiKeyCache.keys[hash] = fresh
+		apiKeyCache.Unlock()
+	}
+	return fresh, nil
+}
+
+// touchAPIKey records when and from where the key was last used.
+func touchAPIKey(db *gorm.DB, state *apiKeyState, ip string) {
+	now := time.Now()
+	apiKeyCache.Lock()
+	due := now.Sub(state.usedAt) >= apiKeyUsageInterval
+	if due {
+		state.usedAt = now
+	}
+	apiKeyCache.Unlock()
+	if !due {
+		return
+	}
+	if err := db.Table("api_keys").Where("id = ?", state.id).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	}).Error; err != nil {
+		log.Printf("[api-key] failed to record usage of key %d: %v", state.id, err)
+	}
+}
@@ -0,0 +1,344 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"tatapps/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxAuditCapture bounds how much of a response body is kept to find created IDs.
+const maxAuditCapture = 1 << 20
+
+// AuditEntity describes the entity an audited route changes.
+type AuditEntity struct {
+	Type  string      // entity type stored in the log, e.g. "item"
+	Model interface{} // GORM model snapshotted before and after the request; nil skips snapshots
+	Param string      // route param holding the entity ID; defaults to "id"
+
+	// ContextKey takes the ID from the gin context instead, e.g. "user_id" for profile routes.
+	ContextKey string
+	// IDFromResponse reads the ID from the response only, for creates nested under
+	// a parent route such as /items/:id/serials.
+	IDFromResponse bool
+	// Singleton audits the first row of the table, e.g. site settings.
+	Singleton bool
+	// Snapshot replaces the default row snapshot, e.g. to include associations.
+	Snapshot func(db *gorm.DB, id string) (map[string]interface{}, error)
+}
+
+// auditRedactedFields are never written to the audit log.
+var auditRedactedFields = []string{"password", "secret", "token_hash", "code_hash", "key_hash", "api_key"}
+
+// auditIgnoredChanges are bookkeeping columns left out of the change set.
+var auditIgnoredChanges = map[string]struct{}{"created_at": {}, "updated_at": {}}
+
+// Audit records successful requests against entity, with the action taken from
+// the HTTP method (POST create, PUT/PATCH update, DELETE delete).
+func Audit(db *gorm.DB, entity AuditEntity) gin.HandlerFunc {
+	return AuditAction(db, entity, "")
+}
+
+// AuditAction is Audit with an explicit action such as "approve".
+func AuditAction(db *gorm.DB, entity AuditEntity, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		action := action
+		if action == "" {
+			action = auditActionForMethod(c.Request.Method)
+		}
+
+		ids := auditIDsFromRequest(db, c, entity)
+		before := make(map[string]map[string]interface{}, len(ids))
+		for _, id := range ids {
+			before[id] = auditSnapshot(db, entity, id)
+		}
+
+		var writer *auditWriter
+		if len(ids) == 0 && entity.Model != nil {
+			writer = &auditWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		if writer != nil {
+			ids = auditIDsFromResponse(writer.body.Bytes())
+		}
+		if len(ids) == 0 {
+			// Bulk operations such as imports are still logged, without an entity ID.
+			ids = []string{""}
+		}
+
+		for _, id := range ids {
+			var after map[string]interface{}
+			if id != "" && action != "delete" {
+				after = auditSnapshot(db, entity, id)
+			}
+			RecordAudit(db, c, action, entity.Type, id, before[id], after)
+		}
+	}
+}
+
+// RecordAudit writes one audit entry for the current request. Failures are
+// logged and never fail the request.
+func RecordAudit(db *gorm.DB, c *gin.Context, action, entityType, entityID string, before, after map[string]interface{}) {
+	entry := models.AuditLog{
+		ActorEmail: c.GetString("email"),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     auditJSON(before),
+		After:      auditJSON(after),
+		Changes:    auditJSON(auditChanges(before, after)),
+		Method:     c.Request.Method,
+		Path:       auditTruncate(c.Request.URL.Path, 255),
+		StatusCode: c.Writer.Status(),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  auditTruncate(c.Request.UserAgent(), 255),
+	}
+	if userID := c.GetUint("user_id"); userID != 0 {
+		entry.ActorID = &userID
+	}
+	if keyID := c.GetUint(APIKeyIDContextKey); keyID != 0 {
+		entry.APIKeyID = &keyID
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("[audit] failed to record %s %s %s: %v", action, entityType, entityID, err)
+	}
+}
+
+// SnapshotRow loads a row of model as a column map, with secrets removed.
+func SnapshotRow(db *gorm.DB, model interface{}, id string) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	result := db.Model(model).Where("id = ?", id).Limit(1).Find(&row)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return row, nil
+}
+
+func auditActionForMethod(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "update"
+	}
+}
+
+// auditIDsFromRequest resolves the IDs known before the handler runs: the route
+// param, the context key, the singleton row, or the "ids" of a batch delete.
+func auditIDsFromRequest(db *gorm.DB, c *gin.Context, entity AuditEntity) []string {
+	if entity.IDFromResponse {
+		return nil
+	}
+	if entity.ContextKey != "" {
+		if value, ok := c.Get(entity.ContextKey); ok {
+			return []string{fmt.Sprint(value)}
+		}
+		return nil
+	}
+
+	param := entity.Param
+	if param == "" {
+		param = "id"
+	}
+	if id := c.Param(param); id != "" {
+		return []string{id}
+	}
+
+	if entity.Singleton && entity.Model != nil {
+		var ids []uint
+		if err := db.Model(entity.Model).Order("id").Limit(1).Pluck("id", &ids).Error; err == nil && len(ids) > 0 {
+			return []string{fmt.Sprint(ids[0])}
+		}
+		return nil
+	}
+
+	if c.Request.Method == http.MethodDelete && c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return nil
+		}
+		var payload struct {
+			IDs []uint `json:"ids"`
+		}
+		if json.Unmarshal(body, &payload) == nil {
+			ids := make([]string, 0, len(payload.IDs))
+			for _, id := range payload.IDs {
+				ids = append(ids, fmt.Sprint(id))
+			}
+			return ids
+		}
+	}
+	return nil
+}
+
+// auditIDsFromResponse finds created IDs in "data", "user" or the top-level object.
+func auditIDsFromResponse(body []byte) []string {
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(body, &envelope) != nil {
+		return nil
+	}
+	for _, key := range []string{"data", "user"} {
+		raw, ok := envelope[key]
+		if !ok {
+			continue
+		}
+		var one struct {
+			ID json.Number `json:"id"`
+		}
+		if json.Unmarshal(raw, &one) == nil && one.ID != "" {
+			return []string{one.ID.String()}
+		}
+		var many []struct {
+			ID json.Number `json:"id"`
+		}
+		if json.Unmarshal(raw, &many) == nil {
+			ids := make([]string, 0, len(many))
+			for _, row := range many {
+				if row.ID != "" {
+					ids = append(ids, row.ID.String())
+				}
+			}
+			return ids
+		}
+	}
+	var top struct {
+		ID json.Number `json:"id"`
+	}
+	if json.Unmarshal(body, &top) == nil && top.ID != "" {
+		return []string{top.ID.String()}
+	}
+	return nil
+}
+
+func auditSnapshot(db *gorm.DB, entity AuditEntity, id string) map[string]interface{} {
+	if entity.Model == nil && entity.Snapshot == nil {
+		return nil
+	}
+	var (
+		row map[string]interface{}
+		err error
+	)
+	if entity.Snapshot != nil {
+		row, err = entity.Snapshot(db, id)
+	} else {
+		row, err = SnapshotRow(db, entity.Model, id)
+	}
+	if err != nil {
+		log.Printf("[audit] failed to snapshot %s %s: %v", entity.Type, id, err)
+		return nil
+	}
+	return redactAuditFields(row)
+}
+
+func redactAuditFields(row map[string]interface{}) map[string]interface{} {
+	for key := range row {
+		lower := strings.ToLower(key)
+		for _, field := range auditRedactedFields {
+			if strings.Contains(lower, field) {
+				delete(row, key)
+				break
+			}
+		}
+	}
+	return row
+}
+
+// auditChanges lists the fields whose values differ between the snapshots.
+func auditChanges(before, after map[string]interface{}) map[string]interface{} {
+	if before == nil && after == nil {
+		return nil
+	}
+	changes := make(map[string]interface{})
+	keys := make(map[string]struct{}, len(before)+len(after))
+	for key := range before {
+		keys[key] = struct{}{}
+	}
+	for key := range after {
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		if _, ignored := auditIgnoredChanges[key]; ignored {
+			continue
+		}
+		from, to := normalizeAuditValue(before[key]), normalizeAuditValue(after[key])
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+		changes[key] = map[string]interface{}{"from": before[key], "to": after[key]}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// normalizeAuditValue compares values by their JSON form so that e.g. int32 and
+// int64 columns, or equal timestamps, are treated as the same.
+func normalizeAuditValue(value interface{}) interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if json.Unmarshal(raw, &normalized) != nil {
+		return value
+	}
+	return normalized
+}
+
+func auditJSON(value map[string]interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func auditTruncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
+
+// auditWriter keeps a copy of the response so created IDs can be read back.
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= maxAuditCapture {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.body.Len()+len(s) <= maxAuditCapture {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
@@ -7,10 +7,18 @@ import (
 	"tatapps/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware validates the access token and its server-side session. API
+// keys are accepted as a bearer token or in the X-API-Key header.
+func AuthMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			authenticateAPIKey(c, db, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -27,6 +35,11 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		token := parts[1]
+		if strings.HasPrefix(token, APIKeyPrefix) {
+			authenticateAPIKey(c, db, token)
+			return
+		}
+
 		claims, err := utils.ValidateToken(token, cfg.JWTSecret)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -34,45 +47,25 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		active, err := sessionActive(db, claims.SessionID, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role_id", claims.RoleID)
 		c.Set("role_name", claims.RoleName)
+		c.Set("session_id", claims.SessionID)
 
 		c.Next()
 	}
 }
-
-// RoleMiddleware checks if user has required role
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		roleName, exists := c.Get("role_name")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
-			c.Abort()
-			return
-		}
-
-		roleStr := roleName.(string)
-		for _, role := range allowedRoles {
-			if roleStr == role {
-				c.Next()
-				return
-			}
-		}
-
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
-	}
-}
-
-// AdminOnly middleware - only for admin role
-func AdminOnly() gin.HandlerFunc {
-	return RoleMiddleware("admin")
-}
-
-// AdminOrManager middleware - for admin or manager roles
-func AdminOrManager() gin.HandlerFunc {
-	return RoleMiddleware("admin", "manager")
-}
@@ -1,8 +1,8 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -28,54 +28,63 @@ func requirePermissions(c *gin.Context, db *gorm.DB, permissions []string, allow
 		return
 	}
 
-	roleName := ""
-	if value, ok := c.Get("role_name"); ok {
-		if name, isString := value.(string); isString {
-			roleName = name
-			if strings.EqualFold(name, "admin") {
-				c.Next()
-				return
-			}
-		}
-	}
-
-	roleID, ok := extractRoleID(c)
-	if !ok {
+	access, err := requestAccess(c, db)
+	if errors.Is(err, errRoleNotInContext) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
 		c.Abort()
 		return
 	}
-
-	var count int64
-	if err := db.
-		Table("role_permissions").
-		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
-		Where("role_permissions.role_id = ?", roleID).
-		Where("permissions.name IN ?", permissions).
-		Distinct("permissions.name").
-		Count(&count).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		c.Abort()
 		return
 	}
 
-	if allowAny {
-		if count == 0 && !strings.EqualFold(roleName, "employee") {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-			c.Abort()
-			return
-		}
-	} else {
-		if count != int64(len(permissions)) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-			c.Abort()
-			return
+	granted := 0
+	for _, permission := range permissions {
+		if _, ok := access.permissions[permission]; ok {
+			granted++
 		}
 	}
 
+	allowed := granted == len(permissions)
+	if allowAny {
+		allowed = granted > 0
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+		return
+	}
+
 	c.Next()
 }
 
+// HasPermission reports whether the current request holds the permission, using
+// the same role or API key access as RequirePermission.
+func HasPermission(c *gin.Context, db *gorm.DB, permission string) (bool, error) {
+	access, err := requestAccess(c, db)
+	if err != nil {
+		return false, err
+	}
+	_, ok := access.permissions[permission]
+	return ok, nil
+}
+
+var errRoleNotInContext = errors.New("role not found in context")
+
+// requestAccess returns the access of the API key or, for access tokens, of the user's role.
+func requestAccess(c *gin.Context, db *gorm.DB) (roleAccess, error) {
+	if key, ok := c.Get(apiKeyAccessContextKey); ok {
+		return key.(apiKeyAccess).roleAccess, nil
+	}
+	roleID, ok := extractRoleID(c)
+	if !ok {
+		return roleAccess{}, errRoleNotInContext
+	}
+	return loadRoleAccess(db, roleID)
+}
+
 func extractRoleID(c *gin.Context) (uint, bool) {
 	roleIDValue, exists := c.Get("role_id")
 	if !exists {
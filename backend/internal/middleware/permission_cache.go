@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// permissionCacheTTL bounds how long a role's permissions are reused. Role edits
+// invalidate the cache right away; the TTL covers edits made by other instances.
+const permissionCacheTTL = time.Minute
+
+// roleAccess is the cached access of a single role.
+type roleAccess struct {
+	exists          bool
+	warehouseScoped bool
+	permissions     map[string]struct{}
+	loadedAt        time.Time
+}
+
+var permissionCache = struct {
+	sync.RWMutex
+	roles map[uint]roleAccess
+}{roles: make(map[uint]roleAccess)}
+
+// InvalidatePermissionCache drops the cached access of the given roles, or of
+// every role when called without IDs.
+func InvalidatePermissionCache(roleIDs ...uint) {
+	permissionCache.Lock()
+	defer permissionCache.Unlock()
+	if len(roleIDs) == 0 {
+		permissionCache.roles = make(map[uint]roleAccess)
+		return
+	}
+	for _, id := range roleIDs {
+		delete(permissionCache.roles, id)
+	}
+}
+
+// RoleWarehouseScoped reports whether users of the role are limited to their
+// assigned warehouses. exists is false for unknown roles.
+func RoleWarehouseScoped(db *gorm.DB, roleID uint) (scoped bool, exists bool, err error) {
+	access, err := loadRoleAccess(db, roleID)
+	if err != nil {
+		return false, false, err
+	}
+	return access.warehouseScoped, access.exists, nil
+}
+
+// RoleHasAnyPermission reports whether the role holds at least one of the permissions.
+func RoleHasAnyPermission(db *gorm.DB, roleID uint, permissions ...string) (bool, error) {
+	access, err := loadRoleAccess(db, roleID)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if _, ok := access.permissions[permission]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func loadRoleAccess(db *gorm.DB, roleID uint) (roleAccess, error) {
+	permissionCache.RLock()
+	access, ok := permissionCache.roles[roleID]
+	permissionCache.RUnlock()
+	if ok && time.Since(access.loadedAt) < permissionCacheTTL {
+		return access, nil
+	}
+
+	var role struct {
+		ID              uint
+		WarehouseScoped bool
+	}
+	result := db.Table("roles").
+		Select("id", "warehouse_scoped").
+		Where("id = ? AND deleted_at IS NULL", roleID).
+		Limit(1).
+		Scan(&role)
+	if result.Error != nil {
+		return roleAccess{}, result.Error
+	}
+
+	access = roleAccess{
+		exists:          result.RowsAffected > 0,
+		warehouseScoped: role.WarehouseScoped,
+		permissions:     make(map[string]struct{}),
+		loadedAt:        time.Now(),
+	}
+	if access.exists {
+		var names []string
+		if err := db.
+			Table("role_permissions").
+			Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+			Where("role_permissions.role_id = ? AND permissions.deleted_at IS NULL", roleID).
+			Pluck("permissions.name", &names).Error; err != nil {
+			return roleAccess{}, err
+		}
+		for _, name := range names {
+			access.permissions[name] = struct{}{}
+		}
+	}
+
+	permissionCache.Lock()
+	permissionCache.roles[roleID] = access
+	permissionCache.Unlock()
+	return access, nil
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter counts hits per key in fixed windows. It is in-memory, so limits
+// apply per server instance.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+	swept   time.Time
+}
+
+type rateBucket struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, buckets: make(map[string]*rateBucket)}
+}
+
+// Allow records a hit for key and reports whether it is within the limit, and
+// otherwise how long until the window resets.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > l.window {
+		for k, bucket := range l.buckets {
+			if now.After(bucket.resetAt) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok || now.After(bucket.resetAt) {
+		bucket = &rateBucket{resetAt: now.Add(l.window)}
+		l.buckets[key] = bucket
+	}
+	bucket.count++
+	if bucket.count > l.limit {
+		return false, bucket.resetAt.Sub(now)
+	}
+	return true, 0
+}
+
+// RateLimitByIP rejects requests from a client IP above limit per window with 429.
+func RateLimitByIP(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := NewRateLimiter(limit, window)
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(c.FullPath() + "|" + c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
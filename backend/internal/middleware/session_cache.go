@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionCacheTTL bounds how long an active session is trusted without a lookup.
+// Revocations on this instance invalidate the cache right away.
+const sessionCacheTTL = 30 * time.Second
+
+type sessionState struct {
+	userID   uint
+	active   bool
+	loadedAt time.Time
+}
+
+var sessionCache = struct {
+	sync.RWMutex
+	sessions map[uint]sessionState
+}{sessions: make(map[uint]sessionState)}
+
+// InvalidateSessionCache drops the cached state of the given sessions.
+func InvalidateSessionCache(sessionIDs ...uint) {
+	sessionCache.Lock()
+	defer sessionCache.Unlock()
+	for _, id := range sessionIDs {
+		delete(sessionCache.sessions, id)
+	}
+}
+
+// sessionActive reports whether the session exists, belongs to userID and is
+// neither revoked nor expired.
+func sessionActive(db *gorm.DB, sessionID, userID uint) (bool, error) {
+	sessionCache.RLock()
+	state, ok := sessionCache.sessions[sessionID]
+	sessionCache.RUnlock()
+	if ok && time.Since(state.loadedAt) < sessionCacheTTL {
+		return state.active && state.userID == userID, nil
+	}
+
+	var session struct {
+		UserID uint
+	}
+	result := db.Table("user_sessions").
+		Select("user_id").
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND deleted_at IS NULL", sessionID, time.Now()).
+		Limit(1).
+		Scan(&session)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	state = sessionState{userID: session.UserID, active: result.RowsAffected > 0, loadedAt: time.Now()}
+	sessionCache.Lock()
+	sessionCache.sessions[sessionID] = state
+	sessionCache.Unlock()
+	return state.active && state.userID == userID, nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey authenticates a machine-to-machine integration. Only the sha256 of the
+// key is stored; the plain key is shown once when it is created. A key is bound
+// either to a role or to an explicit permission subset, and requests made with
+// it are attributed to the admin who created it.
+type APIKey struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `json:"description"`
+	Prefix      string `gorm:"size:16;index" json:"prefix"` // leading characters of the key, to recognise it
+	KeyHash     string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+
+	RoleID      *uint        `gorm:"index" json:"role_id"`
+	Role        *Role        `gorm:"foreignKey:RoleID" json:"-"`
+	Permissions []Permission `gorm:"many2many:api_key_permissions;" json:"-"` // used when RoleID is nil
+	// Warehouses limits the key to these warehouses. Without any the key is
+	// unrestricted, unless its role is warehouse scoped.
+	Warehouses []Warehouse `gorm:"many2many:api_key_warehouses;" json:"-"`
+
+	CreatedByID uint       `gorm:"not null;index" json:"created_by_id"`
+	CreatedBy   User       `gorm:"foreignKey:CreatedByID" json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:64" json:"last_used_ip"`
+	RevokedAt   *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog records one change made through the API. Before and After hold the
+// entity as stored in the database; Changes holds only the fields that differ.
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	ActorID    *uint  `gorm:"index" json:"actor_id,omitempty"`
+	ActorEmail string `gorm:"size:255" json:"actor_email"`
+	APIKeyID   *uint  `gorm:"index" json:"api_key_id,omitempty"` // set when the actor authenticated with an API key
+	Action     string `gorm:"size:50;index" json:"action"`       // create, update, delete, approve, ...
+	EntityType string `gorm:"size:50;index:idx_audit_entity" json:"entity_type"`
+	EntityID   string `gorm:"size:64;index:idx_audit_entity" json:"entity_id"`
+
+	Before  json.RawMessage `gorm:"type:jsonb" json:"before,omitempty"`
+	After   json.RawMessage `gorm:"type:jsonb" json:"after,omitempty"`
+	Changes json.RawMessage `gorm:"type:jsonb" json:"changes,omitempty"` // {"field": {"from": ..., "to": ...}}
+
+	Method     string `gorm:"size:10" json:"method"`
+	Path       string `gorm:"size:255" json:"path"`
+	StatusCode int    `json:"status_code"`
+	IPAddress  string `gorm:"size:64" json:"ip_address"`
+	UserAgent  string `gorm:"size:255" json:"user_agent"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImportBatch keeps a previewed inventory import until the user commits it by token.
+type ImportBatch struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Token       string     `gorm:"size:64;uniqueIndex;not null" json:"token"`
+	Filename    string     `json:"filename"`
+	Status      string     `gorm:"size:20;default:'pending';index" json:"status"` // pending, committed
+	Rows        string     `gorm:"type:jsonb" json:"-"`                           // parsed rows
+	Summary     string     `gorm:"type:jsonb" json:"-"`                           // result after commit
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CommittedAt *time.Time `json:"committed_at,omitempty"`
+
+	CreatedByID uint `gorm:"not null;index" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Job is a unit of background work (imports, exports, backups) picked up by the job runner.
+type Job struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Type     string `gorm:"size:50;not null;index" json:"type"`
+	Status   string `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, running, completed, failed
+	Progress int    `gorm:"default:0" json:"progress"`                              // 0-100
+	Payload  string `gorm:"type:jsonb" json:"-"`
+	Result   string `gorm:"type:jsonb" json:"-"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `gorm:"default:0" json:"attempts"`
+
+	// Downloadable output, stored on disk below the job storage directory
+	FileName    string `json:"file_name,omitempty"`
+	FilePath    string `json:"-"`
+	ContentType string `json:"content_type,omitempty"`
+	FileSize    int64  `json:"file_size,omitempty"`
+
+	RunAfter   time.Time  `gorm:"index" json:"run_after"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// The worker running the job renews this while it works; once it passes,
+	// the job is considered abandoned.
+	LeaseExpiresAt *time.Time `gorm:"index" json:"-"`
+
+	CreatedByID uint `gorm:"not null;index" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
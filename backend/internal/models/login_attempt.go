@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginAttempt is one password or second-factor check made at login.
+// UserID is nil when the email does not belong to any account.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Email     string `gorm:"size:255;index" json:"email"`
+	UserID    *uint  `gorm:"index" json:"user_id,omitempty"`
+	IPAddress string `gorm:"size:64;index" json:"ip_address"`
+	UserAgent string `gorm:"size:255" json:"user_agent"`
+	Success   bool   `gorm:"default:false" json:"success"`
+	Reason    string `gorm:"size:50" json:"reason,omitempty"` // invalid_password, invalid_two_factor, locked, ...
+}
@@ -14,11 +14,11 @@ type Notification struct {
 
 	// Notification Details
 	Type    string `gorm:"not null" json:"type"` // email, whatsapp, system
-	Channel string `json:"channel"`              // email, whatsapp, both
+	Channel string `json:"channel"`              // email, whatsapp, both, in_app
 	Module  string `json:"module"`               // warehouse, employee, lead, project, po
 
 	// Recipient
-	UserID *uint  `json:"user_id,omitempty"`
+	UserID *uint  `gorm:"uniqueIndex:idx_notifications_user_reminder" json:"user_id,omitempty"`
 	User   *User  `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Email  string `json:"email"`
 	Phone  string `json:"phone"`
@@ -28,6 +28,10 @@ type Notification struct {
 	Message string `json:"message"`
 	Data    string `gorm:"type:jsonb" json:"data"` // JSON data for additional info
 
+	// ReminderKey makes an in-app notification unique per user, so a reminder is
+	// delivered once even when several instances raise it.
+	ReminderKey *string `gorm:"size:100;uniqueIndex:idx_notifications_user_reminder" json:"-"`
+
 	// Status
 	Status   string     `gorm:"default:'pending'" json:"status"` // pending, sent, failed, read
 	SentAt   *time.Time `json:"sent_at,omitempty"`
@@ -35,8 +39,9 @@ type Notification struct {
 	ErrorMsg string     `json:"error_msg"`
 
 	// Retry
-	RetryCount int `gorm:"default:0" json:"retry_count"`
-	MaxRetries int `gorm:"default:3" json:"max_retries"`
+	RetryCount    int        `gorm:"default:0" json:"retry_count"`
+	MaxRetries    int        `gorm:"default:3" json:"max_retries"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"` // when a pending email/WhatsApp message is sent next
 }
 
 // NotificationSetting stores user's notification preferences
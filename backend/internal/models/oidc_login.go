@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OIDCLogin tracks one single sign-on attempt: the PKCE verifier and nonce
+// while the browser is at the identity provider, then the one-time ticket the
+// frontend exchanges for a session.
+type OIDCLogin struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	RedirectPath string    `gorm:"size:255" json:"redirect_path"`
+	IPAddress    string    `gorm:"size:64" json:"ip_address"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	CallbackAt   *time.Time `json:"callback_at,omitempty"`
+	UserID       *uint      `gorm:"index" json:"user_id,omitempty"`
+	TicketHash   *string    `gorm:"size:64;uniqueIndex" json:"-"`
+	TicketExpiry *time.Time `json:"ticket_expiry,omitempty"`
+	ExchangedAt  *time.Time `json:"exchanged_at,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordReset is a single-use reset credential: a link token sent by email or
+// a short code sent by WhatsApp.
+type PasswordReset struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	Channel   string     `gorm:"size:20;not null" json:"channel"` // email, whatsapp
+	TokenHash string     `gorm:"size:64;not null;index" json:"-"`
+	Attempts  int        `gorm:"default:0" json:"attempts"` // failed code entries
+	RequestIP string     `gorm:"size:64" json:"request_ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
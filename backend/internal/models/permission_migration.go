@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PermissionMigration records a one-time permission grant applied on upgrade, so
+// each runs exactly once whatever version a database is upgraded from.
+type PermissionMigration struct {
+	Version   uint      `gorm:"primarykey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
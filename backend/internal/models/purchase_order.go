@@ -65,6 +65,9 @@ type POItem struct {
 	POID         uint    `gorm:"not null" json:"po_id"`
 	PO           PurchaseOrder `gorm:"foreignKey:POID" json:"-"`
 	
+	ItemID       *uint          `json:"item_id,omitempty"`
+	Item         *InventoryItem `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+
 	ItemName     string  `gorm:"not null" json:"item_name"`
 	ItemCode     string  `json:"item_code"`
 	Description  string  `json:"description"`
@@ -72,6 +75,8 @@ type POItem struct {
 	Quantity     float64 `gorm:"not null" json:"quantity"`
 	UnitPrice    float64 `gorm:"not null" json:"unit_price"`
 	TotalPrice   float64 `gorm:"not null" json:"total_price"`
+	BaseUnit     string  `json:"base_unit"`
+	BaseQuantity float64 `json:"base_quantity"` // Quantity normalised to the item's base unit
 	
 	ReceivedQty  float64 `gorm:"default:0" json:"received_qty"`
 	Notes        string  `json:"notes"`
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a single-use backup code for two-factor login.
+type RecoveryCode struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	User     User       `gorm:"foreignKey:UserID" json:"-"`
+	CodeHash string     `gorm:"size:64;not null;index" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
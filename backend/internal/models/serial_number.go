@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SerialNumber is one physical unit of a serialised inventory item.
+type SerialNumber struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ItemID uint           `gorm:"not null;uniqueIndex:idx_item_serial" json:"item_id"`
+	Item   *InventoryItem `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	Serial string         `gorm:"size:100;not null;uniqueIndex:idx_item_serial" json:"serial"`
+
+	Status      string     `gorm:"size:20;not null;default:'in_stock';index" json:"status"` // in_stock, issued, in_repair, scrapped
+	WarehouseID *uint      `gorm:"index" json:"warehouse_id,omitempty"`                     // set while in stock
+	Warehouse   *Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+	CustodianID *uint      `gorm:"index" json:"custodian_id,omitempty"` // set while issued
+	Custodian   *Employee  `gorm:"foreignKey:CustodianID" json:"custodian,omitempty"`
+	Notes       string     `json:"notes"`
+
+	Movements []SerialMovement `gorm:"foreignKey:SerialID" json:"movements,omitempty"`
+}
+
+// SerialMovement records every status, warehouse or custodian change of a serial.
+type SerialMovement struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	SerialID uint   `gorm:"not null;index" json:"serial_id"`
+	Action   string `gorm:"size:20;not null" json:"action"` // register, issue, return, repair, scrap, transfer
+
+	FromStatus      string     `gorm:"size:20" json:"from_status"`
+	ToStatus        string     `gorm:"size:20" json:"to_status"`
+	FromWarehouseID *uint      `json:"from_warehouse_id,omitempty"`
+	FromWarehouse   *Warehouse `gorm:"foreignKey:FromWarehouseID" json:"from_warehouse,omitempty"`
+	ToWarehouseID   *uint      `json:"to_warehouse_id,omitempty"`
+	ToWarehouse     *Warehouse `gorm:"foreignKey:ToWarehouseID" json:"to_warehouse,omitempty"`
+	FromCustodianID *uint      `json:"from_custodian_id,omitempty"`
+	FromCustodian   *Employee  `gorm:"foreignKey:FromCustodianID" json:"from_custodian,omitempty"`
+	ToCustodianID   *uint      `json:"to_custodian_id,omitempty"`
+	ToCustodian     *Employee  `gorm:"foreignKey:ToCustodianID" json:"to_custodian,omitempty"`
+
+	TransactionID *uint  `gorm:"index" json:"transaction_id,omitempty"` // stock transaction created by the movement
+	Reference     string `json:"reference"`
+	Notes         string `json:"notes"`
+	CreatedByID   uint   `gorm:"not null" json:"created_by_id"`
+	CreatedBy     User   `gorm:"foreignKey:CreatedByID" json:"created_by"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockReservation earmarks inventory for a project or outbound document so it
+// cannot be issued to anything else.
+type StockReservation struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ItemID    uint           `gorm:"not null;index" json:"item_id"`
+	Item      *InventoryItem `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	ProjectID *uint          `gorm:"index" json:"project_id,omitempty"`
+	Project   *Project       `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	Reference string         `gorm:"index" json:"reference"` // SO/PO number or other outbound document
+
+	Quantity          float64    `gorm:"not null" json:"quantity"`
+	FulfilledQuantity float64    `gorm:"default:0" json:"fulfilled_quantity"`
+	Status            string     `gorm:"default:'active';index" json:"status"` // active, fulfilled, cancelled
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	Notes             string     `json:"notes"`
+
+	CreatedByID uint `gorm:"not null" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID" json:"created_by"`
+}
+
+// Remaining returns the quantity still held by the reservation.
+func (r StockReservation) Remaining() float64 {
+	remaining := r.Quantity - r.FulfilledQuantity
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfMeasure is a catalogue entry such as pcs, box or kg.
+type UnitOfMeasure struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Code        string `gorm:"size:20;uniqueIndex;not null" json:"code"`
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `json:"description"`
+	IsActive    bool   `gorm:"default:true" json:"is_active"`
+}
+
+// ItemUnitConversion defines how many base units (InventoryItem.Unit) one UnitCode holds,
+// e.g. UnitCode "box" with Factor 24 on an item counted in pcs.
+type ItemUnitConversion struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ItemID   uint    `gorm:"not null;uniqueIndex:idx_item_unit" json:"item_id"`
+	UnitCode string  `gorm:"size:20;not null;uniqueIndex:idx_item_unit" json:"unit_code"`
+	Factor   float64 `gorm:"not null" json:"factor"`
+}
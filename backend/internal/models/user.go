@@ -21,6 +21,22 @@ type User struct {
 	RoleID   uint   `gorm:"not null" json:"role_id"`
 	Role     Role   `gorm:"foreignKey:RoleID" json:"role"`
 
+	// AuthProvider is "local" for password users and "oidc" once the account is
+	// linked to the identity provider, identified by ExternalSubject (the "sub" claim).
+	AuthProvider    string  `gorm:"size:20;default:local" json:"auth_provider"`
+	ExternalSubject *string `gorm:"size:255;uniqueIndex" json:"-"`
+
+	// Two-factor authentication (TOTP). The secret is kept while enrolment is pending.
+	TwoFactorEnabled   bool       `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret    string     `gorm:"size:64" json:"-"`
+	TwoFactorLastStep  int64      `gorm:"default:0" json:"-"` // last accepted time step, blocks code replay
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at,omitempty"`
+
+	// Login throttling. LockedUntil covers both the short backoff after a failure
+	// and the lockout once FailedLoginCount reaches the configured limit.
+	FailedLoginCount int        `gorm:"default:0" json:"failed_login_count"`
+	LockedUntil      *time.Time `json:"locked_until,omitempty"`
+
 	Warehouses []UserWarehouse `gorm:"foreignKey:UserID" json:"warehouses,omitempty"`
 }
 
@@ -30,11 +46,13 @@ type Role struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Name        string       `gorm:"uniqueIndex;not null" json:"name"` // admin, employee, manager, etc
-	Description string       `json:"description"`
-	Color       string       `gorm:"size:20;default:#2563EB" json:"color"`
-	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
-	Menus       []RoleMenu   `gorm:"foreignKey:RoleID" json:"menus"`
+	Name        string `gorm:"uniqueIndex;not null" json:"name"` // admin, employee, manager, etc
+	Description string `json:"description"`
+	Color       string `gorm:"size:20;default:#2563EB" json:"color"`
+	// WarehouseScoped limits users of the role to the warehouses assigned to them.
+	WarehouseScoped bool         `gorm:"not null;default:false" json:"warehouse_scoped"`
+	Permissions     []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+	Menus           []RoleMenu   `gorm:"foreignKey:RoleID" json:"menus"`
 }
 
 type Permission struct {
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserInvite lets an admin pre-assign a role and warehouses to an email address.
+// The invitee creates the account with the emailed token.
+type UserInvite struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Email        string `gorm:"size:255;not null;index" json:"email"`
+	TokenHash    string `gorm:"size:64;uniqueIndex;not null" json:"-"` // sha256 of the emailed token
+	RoleID       uint   `gorm:"not null" json:"role_id"`
+	Role         Role   `gorm:"foreignKey:RoleID" json:"role"`
+	WarehouseIDs string `gorm:"type:jsonb" json:"-"` // []uint
+
+	InvitedByID    uint       `gorm:"not null;index" json:"invited_by_id"`
+	InvitedBy      User       `gorm:"foreignKey:InvitedByID" json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uint      `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
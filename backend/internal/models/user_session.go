@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSession is a login session backed by a rotating refresh token. Access
+// tokens carry the session ID and stop working once the session is revoked.
+type UserSession struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	User              User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash         string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // sha256 of the current refresh token
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"`                // last rotated token, used to detect reuse
+	UserAgent         string     `gorm:"size:255" json:"user_agent"`
+	IPAddress         string     `gorm:"size:64" json:"ip_address"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokeReason      string     `gorm:"size:50" json:"revoke_reason,omitempty"` // logout, logout_all, password_changed, role_changed, deactivated, deleted, reuse
+}
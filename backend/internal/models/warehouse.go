@@ -49,8 +49,16 @@ type InventoryItem struct {
 	UnitPrice   float64 `gorm:"default:0" json:"unit_price"`
 	IsActive    bool    `gorm:"default:true" json:"is_active"`
 
+	// Serialised items track each unit as a SerialNumber; Quantity counts units in stock.
+	IsSerialized bool `gorm:"default:false" json:"is_serialized"`
+
+	// Computed from active StockReservation rows
+	ReservedQuantity  float64 `gorm:"-" json:"reserved_quantity"`
+	AvailableQuantity float64 `gorm:"-" json:"available_quantity"`
+
 	// Relations
 	Transactions []InventoryTransaction `gorm:"foreignKey:ItemID" json:"transactions,omitempty"`
+	Conversions  []ItemUnitConversion   `gorm:"foreignKey:ItemID" json:"conversions,omitempty"`
 }
 
 type InventoryTransaction struct {
@@ -62,13 +70,23 @@ type InventoryTransaction struct {
 	ItemID uint          `gorm:"not null" json:"item_id"`
 	Item   InventoryItem `gorm:"foreignKey:ItemID" json:"item"`
 
-	Type            string     `gorm:"not null" json:"type"` // in, out, transfer, adjustment
-	Quantity        float64    `gorm:"not null" json:"quantity"`
+	Type            string     `gorm:"not null" json:"type"`     // in, out, transfer, adjustment, bin_move
+	Quantity        float64    `gorm:"not null" json:"quantity"` // always in the item's base unit
+	EntryUnit       string     `gorm:"size:20" json:"entry_unit"`
+	EntryQuantity   float64    `json:"entry_quantity"`
 	FromWarehouseID *uint      `json:"from_warehouse_id,omitempty"`
 	FromWarehouse   *Warehouse `gorm:"foreignKey:FromWarehouseID" json:"from_warehouse,omitempty"`
 	ToWarehouseID   *uint      `json:"to_warehouse_id,omitempty"`
 	ToWarehouse     *Warehouse `gorm:"foreignKey:ToWarehouseID" json:"to_warehouse,omitempty"`
 
+	FromLocationID *uint              `json:"from_location_id,omitempty"`
+	FromLocation   *WarehouseLocation `gorm:"foreignKey:FromLocationID" json:"from_location,omitempty"`
+	ToLocationID   *uint              `json:"to_location_id,omitempty"`
+	ToLocation     *WarehouseLocation `gorm:"foreignKey:ToLocationID" json:"to_location,omitempty"`
+
+	ReservationID *uint             `json:"reservation_id,omitempty"`
+	Reservation   *StockReservation `gorm:"foreignKey:ReservationID" json:"reservation,omitempty"`
+
 	Reference   string `json:"reference"` // PO number, transfer note, etc
 	Notes       string `json:"notes"`
 	CreatedByID uint   `gorm:"not null" json:"created_by_id"`
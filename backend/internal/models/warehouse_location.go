@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WarehouseLocation is a zone, aisle or bin nested inside a warehouse.
+// Path stores the full code chain (e.g. "A/03/B12") so pick lists can sort by it.
+type WarehouseLocation struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	WarehouseID uint               `gorm:"not null;index" json:"warehouse_id"`
+	Warehouse   *Warehouse         `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+	ParentID    *uint              `gorm:"index" json:"parent_id,omitempty"`
+	Parent      *WarehouseLocation `gorm:"foreignKey:ParentID" json:"-"`
+
+	Type      string `gorm:"size:20;not null" json:"type"` // zone, aisle, bin
+	Code      string `gorm:"size:50;not null" json:"code"`
+	Name      string `json:"name"`
+	Path      string `gorm:"size:255;index" json:"path"`
+	SortOrder int    `gorm:"default:0" json:"sort_order"`
+	IsActive  bool   `gorm:"default:true" json:"is_active"`
+}
+
+// InventoryBinStock holds the quantity of an inventory item stored in a single bin.
+type InventoryBinStock struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ItemID     uint              `gorm:"not null;uniqueIndex:idx_item_bin" json:"item_id"`
+	Item       *InventoryItem    `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	LocationID uint              `gorm:"not null;uniqueIndex:idx_item_bin;index" json:"location_id"`
+	Location   WarehouseLocation `gorm:"foreignKey:LocationID" json:"location"`
+	Quantity   float64           `gorm:"default:0" json:"quantity"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription receives the events matching its filters as signed HTTP
+// POST requests. Events is a JSON array of event names or "prefix.*" patterns.
+type WebhookSubscription struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `json:"description"`
+	URL         string `gorm:"size:500;not null" json:"url"`
+	Secret      string `gorm:"size:100;not null" json:"-"` // HMAC-SHA256 signing key
+	Events      string `gorm:"type:jsonb;not null" json:"-"`
+	IsActive    bool   `gorm:"default:true" json:"is_active"`
+
+	CreatedByID uint `gorm:"not null;index" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
+
+// WebhookDelivery is one event queued for one subscription, together with the
+// outcome of its latest attempt.
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	SubscriptionID uint                `gorm:"not null;index" json:"subscription_id"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"-"`
+	EventID        string              `gorm:"size:64;index" json:"event_id"` // shared by the deliveries of one event
+	Event          string              `gorm:"size:100;index" json:"event"`
+	Payload        string              `gorm:"type:text" json:"payload"` // signed as sent, so key order is kept
+
+	Status        string     `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, delivered, failed
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts   int        `gorm:"default:8" json:"max_attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+
+	ResponseStatus int    `json:"response_status,omitempty"`
+	ResponseBody   string `gorm:"type:text" json:"response_body,omitempty"` // truncated
+	Error          string `json:"error,omitempty"`
+	DurationMs     int64  `json:"duration_ms"`
+}
@@ -1,65 +1,110 @@
 package routes
 
 import (
+	"time"
+
 	"tatapps/internal/config"
 	"tatapps/internal/handlers"
 	"tatapps/internal/middleware"
+	"tatapps/internal/services/jobs"
 	"tatapps/internal/services/notification"
+	"tatapps/internal/services/realtime"
+	"tatapps/internal/services/webhook"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, notifService *notification.NotificationService) {
+func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, notifService *notification.NotificationService, jobRunner *jobs.Runner, webhooks *webhook.Dispatcher, stream *realtime.Hub) {
 	// CORS middleware
 	router.Use(middleware.CORSMiddleware(cfg.FrontendURL))
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, cfg, notifService)
 	warehouseHandler := handlers.NewWarehouseHandler(db)
-	poHandler := handlers.NewPOHandler(db)
-	inventoryHandler := handlers.NewInventoryHandler(db)
+	poHandler := handlers.NewPOHandler(db, webhooks, notifService.Inbox)
+	inventoryHandler := handlers.NewInventoryHandler(db, webhooks, notifService.Inbox, stream)
+	reservationHandler := handlers.NewReservationHandler(db)
+	locationHandler := handlers.NewLocationHandler(db)
+	unitHandler := handlers.NewUnitHandler(db)
+	serialHandler := handlers.NewSerialHandler(db)
 	categoryHandler := handlers.NewCategoryHandler(db)
 	userHandler := handlers.NewUserHandler(db, notifService, cfg)
-	employeeHandler := handlers.NewEmployeeHandler(db)
+	employeeHandler := handlers.NewEmployeeHandler(db, webhooks)
 	settingsHandler := handlers.NewSettingsHandler(db, notifService, cfg)
+	jobHandler := handlers.NewJobHandler(db, cfg, jobRunner, stream)
+	auditHandler := handlers.NewAuditHandler(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db, webhooks)
+	inboxHandler := handlers.NewInboxHandler(db)
+	outboxHandler := handlers.NewOutboxHandler(db, notifService.Outbox)
+	streamHandler := handlers.NewStreamHandler(db, cfg, stream)
 
 	// Public routes
 	public := router.Group("/api/v1")
 	{
 		public.POST("/auth/login", authHandler.Login)
 		public.POST("/auth/register", authHandler.Register)
+		public.POST("/auth/refresh", authHandler.RefreshToken)
+		public.GET("/auth/invites/:token", authHandler.GetInvite)
+		public.POST("/auth/invites/accept", authHandler.AcceptInvite)
+		public.POST("/auth/password/forgot", middleware.RateLimitByIP(5, 15*time.Minute), authHandler.ForgotPassword)
+		public.POST("/auth/password/reset", middleware.RateLimitByIP(10, 15*time.Minute), authHandler.ResetPassword)
+		public.POST("/auth/2fa/verify", middleware.RateLimitByIP(10, 5*time.Minute), authHandler.VerifyTwoFactor)
+		public.POST("/auth/2fa/enroll", middleware.RateLimitByIP(10, 5*time.Minute), authHandler.EnrollTwoFactor)
+		public.POST("/auth/2fa/enroll/confirm", middleware.RateLimitByIP(10, 5*time.Minute), authHandler.ConfirmTwoFactorEnrollment)
+		public.GET("/auth/oidc/config", authHandler.GetOIDCConfig)
+		public.GET("/auth/oidc/login", middleware.RateLimitByIP(20, 5*time.Minute), authHandler.StartOIDCLogin)
+		public.GET("/auth/oidc/callback", authHandler.OIDCCallback)
+		public.POST("/auth/oidc/exchange", middleware.RateLimitByIP(20, 5*time.Minute), authHandler.ExchangeOIDCTicket)
 		public.GET("/settings/site", settingsHandler.GetSiteSettings)
 	}
 
 	// Protected routes
 	protected := router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(cfg))
+	protected.Use(middleware.AuthMiddleware(cfg, db))
 	{
-		// Profile
-		protected.GET("/auth/profile", authHandler.GetProfile)
-		protected.PUT("/users/profile", authHandler.UpdateProfile)
-		protected.PUT("/users/change-password", authHandler.ChangePassword)
+		// Profile (user sessions only, API keys are rejected)
+		userOnly := middleware.RequireUserSession()
+		protected.GET("/auth/profile", userOnly, authHandler.GetProfile)
+		protected.POST("/auth/logout", userOnly, authHandler.Logout)
+		protected.POST("/auth/logout-all", userOnly, authHandler.LogoutAll)
+		protected.GET("/auth/sessions", userOnly, authHandler.ListSessions)
+		protected.GET("/auth/2fa", userOnly, authHandler.GetTwoFactorStatus)
+		protected.POST("/auth/2fa/setup", userOnly, authHandler.SetupTwoFactor)
+		protected.POST("/auth/2fa/enable", userOnly, middleware.RateLimitByIP(10, 5*time.Minute), middleware.AuditAction(db, handlers.AuditProfile, "enable_two_factor"), authHandler.EnableTwoFactor)
+		protected.POST("/auth/2fa/disable", userOnly, middleware.RateLimitByIP(10, 5*time.Minute), middleware.AuditAction(db, handlers.AuditProfile, "disable_two_factor"), authHandler.DisableTwoFactor)
+		protected.POST("/auth/2fa/recovery-codes", userOnly, middleware.RateLimitByIP(10, 5*time.Minute), authHandler.RegenerateRecoveryCodes)
+		protected.PUT("/users/profile", userOnly, middleware.Audit(db, handlers.AuditProfile), authHandler.UpdateProfile)
+		protected.PUT("/users/change-password", userOnly, middleware.AuditAction(db, handlers.AuditProfile, "change_password"), authHandler.ChangePassword)
 
 		// Warehouses
 		warehouses := protected.Group("/warehouses")
 		{
-			warehouses.GET("", warehouseHandler.GetAll)
-			warehouses.GET("/:id", warehouseHandler.GetByID)
-			warehouses.POST("", middleware.AdminOrManager(), warehouseHandler.Create)
-			warehouses.PUT("/:id", middleware.AdminOrManager(), warehouseHandler.Update)
-			warehouses.DELETE("/:id", middleware.AdminOnly(), warehouseHandler.Delete)
+			warehouses.GET("", middleware.RequirePermission(db, "warehouse.view"), warehouseHandler.GetAll)
+			warehouses.GET("/:id", middleware.RequirePermission(db, "warehouse.view"), warehouseHandler.GetByID)
+			warehouses.POST("", middleware.RequirePermission(db, "warehouse.create"), middleware.Audit(db, handlers.AuditWarehouse), warehouseHandler.Create)
+			warehouses.PUT("/:id", middleware.RequirePermission(db, "warehouse.update"), middleware.Audit(db, handlers.AuditWarehouse), warehouseHandler.Update)
+			warehouses.DELETE("/:id", middleware.RequirePermission(db, "warehouse.delete"), middleware.Audit(db, handlers.AuditWarehouse), warehouseHandler.Delete)
+
+			// Zones, aisles and bins
+			warehouses.GET("/:id/locations", middleware.RequirePermission(db, "warehouse.view"), locationHandler.ListLocations)
+			warehouses.POST("/:id/locations", middleware.RequirePermission(db, "warehouse.update"), middleware.Audit(db, handlers.AuditNewLocation), locationHandler.CreateLocation)
+			warehouses.PUT("/:id/locations/:locationId", middleware.RequirePermission(db, "warehouse.update"), middleware.Audit(db, handlers.AuditLocation), locationHandler.UpdateLocation)
+			warehouses.DELETE("/:id/locations/:locationId", middleware.RequirePermission(db, "warehouse.update"), middleware.Audit(db, handlers.AuditLocation), locationHandler.DeleteLocation)
+			warehouses.GET("/:id/locations/:locationId/stock", middleware.RequirePermission(db, "inventory.view"), locationHandler.GetLocationStock)
+			warehouses.POST("/:id/pick-list", middleware.RequirePermission(db, "inventory.view"), locationHandler.BuildPickList)
 		}
 
 		// Purchase Orders
 		purchaseOrders := protected.Group("/purchase-orders")
 		{
-			purchaseOrders.GET("", poHandler.GetAll)
-			purchaseOrders.GET("/:id", poHandler.GetByID)
-			purchaseOrders.POST("", poHandler.Create)
-			purchaseOrders.PUT("/:id", poHandler.Update)
-			purchaseOrders.POST("/:id/approve", middleware.AdminOrManager(), poHandler.Approve)
-			purchaseOrders.POST("/:id/reject", middleware.AdminOrManager(), poHandler.Reject)
+			purchaseOrders.GET("", middleware.RequirePermission(db, "po.view"), poHandler.GetAll)
+			purchaseOrders.GET("/:id", middleware.RequirePermission(db, "po.view"), poHandler.GetByID)
+			purchaseOrders.POST("", middleware.RequirePermission(db, "po.create"), middleware.Audit(db, handlers.AuditPO), poHandler.Create)
+			purchaseOrders.PUT("/:id", middleware.RequirePermission(db, "po.update"), middleware.Audit(db, handlers.AuditPO), poHandler.Update)
+			purchaseOrders.POST("/:id/approve", middleware.RequirePermission(db, "po.approve"), middleware.AuditAction(db, handlers.AuditPO, "approve"), poHandler.Approve)
+			purchaseOrders.POST("/:id/reject", middleware.RequirePermission(db, "po.approve"), middleware.AuditAction(db, handlers.AuditPO, "reject"), poHandler.Reject)
 		}
 
 		// Inventory
@@ -67,21 +112,47 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, notifServi
 		{
 			inventory.GET("", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetAllItems)
 			inventory.GET("/low-stock", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetLowStockItems)
+			inventory.GET("/as-of", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetStockAsOf)
 			inventory.GET("/transactions", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetAllTransactions)
-			inventory.DELETE("/transactions/:id", middleware.RequirePermission(db, "inventory.delete"), inventoryHandler.DeleteTransaction)
+			inventory.GET("/serials", middleware.RequirePermission(db, "inventory.view"), serialHandler.ListSerials)
+			inventory.GET("/serials/:id", middleware.RequirePermission(db, "inventory.view"), serialHandler.GetSerial)
+			inventory.POST("/serials/:id/move", middleware.RequirePermission(db, "inventory.update"), middleware.AuditAction(db, handlers.AuditSerial, "move"), serialHandler.MoveSerial)
+			inventory.GET("/units", middleware.RequirePermission(db, "inventory.view"), unitHandler.ListUnits)
+			inventory.POST("/units", middleware.RequirePermission(db, "inventory.update"), middleware.Audit(db, handlers.AuditUnit), unitHandler.CreateUnit)
+			inventory.PUT("/units/:id", middleware.RequirePermission(db, "inventory.update"), middleware.Audit(db, handlers.AuditUnit), unitHandler.UpdateUnit)
+			inventory.DELETE("/units/:id", middleware.RequirePermission(db, "inventory.delete"), middleware.Audit(db, handlers.AuditUnit), unitHandler.DeleteUnit)
+			inventory.GET("/reservations", middleware.RequirePermission(db, "inventory.view"), reservationHandler.ListReservations)
+			inventory.POST("/reservations", middleware.RequirePermission(db, "inventory.update"), middleware.Audit(db, handlers.AuditReservation), reservationHandler.CreateReservation)
+			inventory.PUT("/reservations/:id", middleware.RequirePermission(db, "inventory.update"), middleware.Audit(db, handlers.AuditReservation), reservationHandler.UpdateReservation)
+			inventory.POST("/reservations/:id/cancel", middleware.RequirePermission(db, "inventory.update"), middleware.AuditAction(db, handlers.AuditReservation, "cancel"), reservationHandler.CancelReservation)
+			inventory.DELETE("/transactions/:id", middleware.RequirePermission(db, "inventory.delete"), middleware.Audit(db, handlers.AuditTransaction), inventoryHandler.DeleteTransaction)
 			inventory.GET("/import/template", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.DownloadImportTemplate)
-			inventory.POST("/import/csv", middleware.RequirePermission(db, "inventory.create"), inventoryHandler.ImportItemsFromCSV)
+			inventory.GET("/import/template/xlsx", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.DownloadImportTemplateXLSX)
+			inventory.POST("/import/csv", middleware.RequirePermission(db, "inventory.create"), middleware.AuditAction(db, handlers.AuditImport, "import"), inventoryHandler.ImportItemsFromCSV)
+			inventory.POST("/import/xlsx", middleware.RequirePermission(db, "inventory.create"), middleware.AuditAction(db, handlers.AuditImport, "import"), inventoryHandler.ImportItemsFromXLSX)
+			inventory.POST("/import/preview", middleware.RequirePermission(db, "inventory.create"), inventoryHandler.PreviewImport)
+			inventory.POST("/import/commit", middleware.RequirePermission(db, "inventory.create"), middleware.AuditAction(db, handlers.AuditImport, "import"), inventoryHandler.CommitImport)
+			inventory.POST("/import/jobs", middleware.RequirePermission(db, "inventory.create"), middleware.AuditAction(db, handlers.AuditImport, "import"), jobHandler.EnqueueImport)
 			inventory.GET("/export/csv", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportItemsToCSV)
+			inventory.GET("/export/xlsx", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportItemsToXLSX)
 			inventory.GET("/export/pdf", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportItemsToPDF)
+			inventory.POST("/export/pdf/jobs", middleware.RequirePermission(db, "inventory.view"), jobHandler.EnqueueItemsPDF)
 			inventory.GET("/transactions/export/csv", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportTransactionsToCSV)
+			inventory.GET("/transactions/export/xlsx", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportTransactionsToXLSX)
 			inventory.GET("/transactions/export/pdf", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.ExportTransactionsToPDF)
+			inventory.POST("/transactions/export/pdf/jobs", middleware.RequirePermission(db, "inventory.view"), jobHandler.EnqueueTransactionsPDF)
 			inventory.GET("/items/:id", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetItemByID)
 			inventory.GET("/items/:id/transactions", middleware.RequirePermission(db, "inventory.view"), inventoryHandler.GetItemTransactions)
-			inventory.POST("/items", middleware.RequirePermission(db, "inventory.create"), inventoryHandler.CreateItem)
-			inventory.DELETE("/items", middleware.RequirePermission(db, "inventory.delete"), inventoryHandler.DeleteItemsBatch)
-			inventory.PUT("/items/:id", middleware.RequirePermission(db, "inventory.update"), inventoryHandler.UpdateItem)
-			inventory.DELETE("/items/:id", middleware.RequirePermission(db, "inventory.delete"), inventoryHandler.DeleteItem)
-			inventory.POST("/items/:id/transactions", middleware.RequireAnyPermission(db, "inventory.update", "inventory.create"), inventoryHandler.RecordTransaction)
+			inventory.GET("/items/:id/bins", middleware.RequirePermission(db, "inventory.view"), locationHandler.GetItemBins)
+			inventory.GET("/items/:id/serials", middleware.RequirePermission(db, "inventory.view"), serialHandler.ListItemSerials)
+			inventory.POST("/items/:id/serials", middleware.RequireAnyPermission(db, "inventory.update", "inventory.create"), middleware.Audit(db, handlers.AuditNewSerials), serialHandler.RegisterSerials)
+			inventory.GET("/items/:id/units", middleware.RequirePermission(db, "inventory.view"), unitHandler.GetItemUnits)
+			inventory.PUT("/items/:id/units", middleware.RequirePermission(db, "inventory.update"), middleware.AuditAction(db, handlers.AuditItemUnits, "update_units"), unitHandler.SetItemUnits)
+			inventory.POST("/items", middleware.RequirePermission(db, "inventory.create"), middleware.Audit(db, handlers.AuditItem), inventoryHandler.CreateItem)
+			inventory.DELETE("/items", middleware.RequirePermission(db, "inventory.delete"), middleware.Audit(db, handlers.AuditItem), inventoryHandler.DeleteItemsBatch)
+			inventory.PUT("/items/:id", middleware.RequirePermission(db, "inventory.update"), middleware.Audit(db, handlers.AuditItem), inventoryHandler.UpdateItem)
+			inventory.DELETE("/items/:id", middleware.RequirePermission(db, "inventory.delete"), middleware.Audit(db, handlers.AuditItem), inventoryHandler.DeleteItem)
+			inventory.POST("/items/:id/transactions", middleware.RequireAnyPermission(db, "inventory.update", "inventory.create"), middleware.Audit(db, handlers.AuditItemTxn), inventoryHandler.RecordTransaction)
 		}
 
 		// Categories
@@ -89,81 +160,145 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, notifServi
 		{
 			categories.GET("", middleware.RequirePermission(db, "category.view"), categoryHandler.GetAllCategories)
 			categories.GET("/:id", middleware.RequirePermission(db, "category.view"), categoryHandler.GetCategoryByID)
-			categories.POST("", middleware.RequirePermission(db, "category.create"), categoryHandler.CreateCategory)
-			categories.PUT("/:id", middleware.RequirePermission(db, "category.update"), categoryHandler.UpdateCategory)
-			categories.DELETE("/:id", middleware.RequirePermission(db, "category.delete"), categoryHandler.DeleteCategory)
+			categories.POST("", middleware.RequirePermission(db, "category.create"), middleware.Audit(db, handlers.AuditCategory), categoryHandler.CreateCategory)
+			categories.PUT("/:id", middleware.RequirePermission(db, "category.update"), middleware.Audit(db, handlers.AuditCategory), categoryHandler.UpdateCategory)
+			categories.DELETE("/:id", middleware.RequirePermission(db, "category.delete"), middleware.Audit(db, handlers.AuditCategory), categoryHandler.DeleteCategory)
 		}
 
 		// Settings
 		settings := protected.Group("/settings")
 		{
-			settings.GET("/site/admin", middleware.AdminOnly(), settingsHandler.GetSiteSettingsAdmin)
-			settings.GET("/notifications", settingsHandler.GetNotificationSettings)
-			settings.PUT("/notifications", settingsHandler.UpdateNotificationSettings)
-			settings.PUT("/site", middleware.AdminOnly(), settingsHandler.UpdateSiteSettings)
-			settings.GET("/database/backup", middleware.AdminOnly(), settingsHandler.BackupDatabase)
-			settings.POST("/database/restore", middleware.AdminOnly(), settingsHandler.RestoreDatabase)
+			settings.GET("/site/admin", middleware.RequirePermission(db, "settings.manage"), settingsHandler.GetSiteSettingsAdmin)
+			settings.GET("/notifications", userOnly, settingsHandler.GetNotificationSettings)
+			settings.PUT("/notifications", userOnly, middleware.Audit(db, handlers.AuditNotificationSettings), settingsHandler.UpdateNotificationSettings)
+			settings.PUT("/site", middleware.RequirePermission(db, "settings.manage"), middleware.Audit(db, handlers.AuditSite), settingsHandler.UpdateSiteSettings)
+			settings.GET("/database/backup", middleware.RequirePermission(db, "backup.run"), settingsHandler.BackupDatabase)
+			settings.POST("/database/backup/jobs", middleware.RequirePermission(db, "backup.run"), jobHandler.EnqueueDatabaseBackup)
+			settings.POST("/database/restore", middleware.RequirePermission(db, "backup.run"), middleware.AuditAction(db, handlers.AuditDatabase, "restore"), settingsHandler.RestoreDatabase)
 
 			// User management
 			users := settings.Group("/users")
 			{
 				users.GET("", middleware.RequirePermission(db, "employee.view"), userHandler.GetAll)
+				users.GET("/invites", middleware.RequirePermission(db, "employee.view"), userHandler.ListInvites)
+				users.POST("/invites", middleware.RequirePermission(db, "employee.create"), middleware.Audit(db, handlers.AuditInvite), userHandler.CreateInvite)
+				users.POST("/invites/:id/resend", middleware.RequirePermission(db, "employee.create"), middleware.AuditAction(db, handlers.AuditInvite, "resend"), userHandler.ResendInvite)
+				users.DELETE("/invites/:id", middleware.RequirePermission(db, "employee.create"), middleware.AuditAction(db, handlers.AuditInvite, "revoke"), userHandler.RevokeInvite)
 				users.GET("/:id", middleware.RequirePermission(db, "employee.view"), userHandler.GetByID)
-				users.POST("", middleware.RequirePermission(db, "employee.create"), userHandler.CreateUser)
-				users.PUT("/:id", middleware.RequirePermission(db, "employee.update"), userHandler.UpdateUser)
-				users.PUT("/:id/status", middleware.RequirePermission(db, "employee.update"), userHandler.UpdateUserStatus)
-				users.DELETE("/:id", middleware.RequirePermission(db, "employee.delete"), userHandler.DeleteUser)
+				users.POST("", middleware.RequirePermission(db, "employee.create"), middleware.Audit(db, handlers.AuditUser), userHandler.CreateUser)
+				users.PUT("/:id", middleware.RequirePermission(db, "employee.update"), middleware.Audit(db, handlers.AuditUser), userHandler.UpdateUser)
+				users.PUT("/:id/status", middleware.RequirePermission(db, "employee.update"), middleware.AuditAction(db, handlers.AuditUser, "update_status"), userHandler.UpdateUserStatus)
+				users.DELETE("/:id/two-factor", middleware.RequirePermission(db, "employee.update"), middleware.AuditAction(db, handlers.AuditUser, "reset_two_factor"), userHandler.ResetUserTwoFactor)
+				users.POST("/:id/unlock", middleware.RequirePermission(db, "employee.update"), middleware.AuditAction(db, handlers.AuditUser, "unlock"), userHandler.UnlockUser)
+				users.GET("/login-attempts", middleware.RequirePermission(db, "employee.update"), userHandler.ListLoginAttempts)
+				users.DELETE("/:id", middleware.RequirePermission(db, "employee.delete"), middleware.Audit(db, handlers.AuditUser), userHandler.DeleteUser)
+			}
+
+			apiKeys := settings.Group("/api-keys", userOnly)
+			{
+				apiKeys.GET("", middleware.RequirePermission(db, "api_key.manage"), apiKeyHandler.ListAPIKeys)
+				apiKeys.POST("", middleware.RequirePermission(db, "api_key.manage"), middleware.Audit(db, handlers.AuditNewAPIKey), apiKeyHandler.CreateAPIKey)
+				apiKeys.PUT("/:id", middleware.RequirePermission(db, "api_key.manage"), middleware.Audit(db, handlers.AuditAPIKey), apiKeyHandler.UpdateAPIKey)
+				apiKeys.DELETE("/:id", middleware.RequirePermission(db, "api_key.manage"), middleware.AuditAction(db, handlers.AuditAPIKey, "revoke"), apiKeyHandler.RevokeAPIKey)
+			}
+
+			webhookRoutes := settings.Group("/webhooks")
+			{
+				webhookRoutes.GET("/events", middleware.RequirePermission(db, "webhook.manage"), webhookHandler.ListWebhookEvents)
+				webhookRoutes.GET("", middleware.RequirePermission(db, "webhook.manage"), webhookHandler.ListWebhooks)
+				webhookRoutes.POST("", middleware.RequirePermission(db, "webhook.manage"), middleware.Audit(db, handlers.AuditNewWebhook), webhookHandler.CreateWebhook)
+				webhookRoutes.PUT("/:id", middleware.RequirePermission(db, "webhook.manage"), middleware.Audit(db, handlers.AuditWebhook), webhookHandler.UpdateWebhook)
+				webhookRoutes.DELETE("/:id", middleware.RequirePermission(db, "webhook.manage"), middleware.Audit(db, handlers.AuditWebhook), webhookHandler.DeleteWebhook)
+				webhookRoutes.POST("/:id/rotate-secret", middleware.RequirePermission(db, "webhook.manage"), middleware.AuditAction(db, handlers.AuditWebhook, "rotate_secret"), webhookHandler.RotateWebhookSecret)
+				webhookRoutes.POST("/:id/test", middleware.RequirePermission(db, "webhook.manage"), webhookHandler.TestWebhook)
+				webhookRoutes.GET("/:id/deliveries", middleware.RequirePermission(db, "webhook.manage"), webhookHandler.ListWebhookDeliveries)
+				webhookRoutes.POST("/:id/deliveries/:deliveryId/redeliver", middleware.RequirePermission(db, "webhook.manage"), webhookHandler.RedeliverWebhook)
 			}
 
 			roles := settings.Group("/roles")
 			{
-				roles.GET("", middleware.AdminOnly(), userHandler.GetRoles)
-				roles.GET("/menu-options", middleware.AdminOnly(), userHandler.GetRoleMenuOptions)
-				roles.GET("/permission-options", middleware.AdminOnly(), userHandler.GetRolePermissionOptions)
-				roles.POST("", middleware.AdminOnly(), userHandler.CreateRole)
-				roles.PUT("/:id", middleware.AdminOnly(), userHandler.UpdateRole)
-				roles.DELETE("/:id", middleware.AdminOnly(), userHandler.DeleteRole)
+				roles.GET("", middleware.RequirePermission(db, "role.manage"), userHandler.GetRoles)
+				roles.GET("/menu-options", middleware.RequirePermission(db, "role.manage"), userHandler.GetRoleMenuOptions)
+				roles.GET("/permission-options", middleware.RequirePermission(db, "role.manage"), userHandler.GetRolePermissionOptions)
+				roles.POST("", middleware.RequirePermission(db, "role.manage"), middleware.Audit(db, handlers.AuditRole), userHandler.CreateRole)
+				roles.PUT("/:id", middleware.RequirePermission(db, "role.manage"), middleware.Audit(db, handlers.AuditRole), userHandler.UpdateRole)
+				roles.DELETE("/:id", middleware.RequirePermission(db, "role.manage"), middleware.Audit(db, handlers.AuditRole), userHandler.DeleteRole)
 			}
 		}
 
 		// Roles
-		protected.GET("/roles", userHandler.GetRoles)
+		protected.GET("/roles", middleware.RequireAnyPermission(db, "role.manage", "employee.view"), userHandler.GetRoles)
 
 		// Employees
 		employees := protected.Group("/employees")
 		{
 			employees.GET("", middleware.RequirePermission(db, "employee.view"), employeeHandler.ListEmployees)
 			employees.GET("/:id", middleware.RequirePermission(db, "employee.view"), employeeHandler.GetEmployee)
-			employees.POST("", middleware.RequirePermission(db, "employee.create"), employeeHandler.CreateEmployee)
-			employees.PUT("/:id", middleware.RequirePermission(db, "employee.update"), employeeHandler.UpdateEmployee)
-			employees.DELETE("", middleware.RequirePermission(db, "employee.delete"), employeeHandler.DeleteEmployeesBatch)
-			employees.DELETE("/:id", middleware.RequirePermission(db, "employee.delete"), employeeHandler.DeleteEmployee)
+			employees.POST("", middleware.RequirePermission(db, "employee.create"), middleware.Audit(db, handlers.AuditEmployee), employeeHandler.CreateEmployee)
+			employees.PUT("/:id", middleware.RequirePermission(db, "employee.update"), middleware.Audit(db, handlers.AuditEmployee), employeeHandler.UpdateEmployee)
+			employees.DELETE("", middleware.RequirePermission(db, "employee.delete"), middleware.Audit(db, handlers.AuditEmployee), employeeHandler.DeleteEmployeesBatch)
+			employees.DELETE("/:id", middleware.RequirePermission(db, "employee.delete"), middleware.Audit(db, handlers.AuditEmployee), employeeHandler.DeleteEmployee)
 
 			divisions := employees.Group("/divisions")
 			{
 				divisions.GET("", middleware.RequirePermission(db, "employee.view"), employeeHandler.ListDivisions)
-				divisions.POST("", middleware.RequirePermission(db, "employee.create"), employeeHandler.CreateDivision)
-				divisions.PUT(":id", middleware.RequirePermission(db, "employee.update"), employeeHandler.UpdateDivision)
-				divisions.DELETE(":id", middleware.RequirePermission(db, "employee.delete"), employeeHandler.DeleteDivision)
+				divisions.POST("", middleware.RequirePermission(db, "employee.create"), middleware.Audit(db, handlers.AuditDivision), employeeHandler.CreateDivision)
+				divisions.PUT(":id", middleware.RequirePermission(db, "employee.update"), middleware.Audit(db, handlers.AuditDivision), employeeHandler.UpdateDivision)
+				divisions.DELETE(":id", middleware.RequirePermission(db, "employee.delete"), middleware.Audit(db, handlers.AuditDivision), employeeHandler.DeleteDivision)
 			}
 
 			positions := employees.Group("/positions")
 			{
 				positions.GET("", middleware.RequirePermission(db, "employee.view"), employeeHandler.ListPositions)
-				positions.POST("", middleware.RequirePermission(db, "employee.create"), employeeHandler.CreatePosition)
-				positions.PUT(":id", middleware.RequirePermission(db, "employee.update"), employeeHandler.UpdatePosition)
-				positions.DELETE(":id", middleware.RequirePermission(db, "employee.delete"), employeeHandler.DeletePosition)
+				positions.POST("", middleware.RequirePermission(db, "employee.create"), middleware.Audit(db, handlers.AuditPosition), employeeHandler.CreatePosition)
+				positions.PUT(":id", middleware.RequirePermission(db, "employee.update"), middleware.Audit(db, handlers.AuditPosition), employeeHandler.UpdatePosition)
+				positions.DELETE(":id", middleware.RequirePermission(db, "employee.delete"), middleware.Audit(db, handlers.AuditPosition), employeeHandler.DeletePosition)
 			}
 		}
 
+		// Audit log
+		auditLogs := protected.Group("/audit-logs")
+		{
+			auditLogs.GET("", middleware.RequirePermission(db, "audit.view"), auditHandler.ListAuditLogs)
+			auditLogs.GET("/export/csv", middleware.RequirePermission(db, "audit.view"), auditHandler.ExportAuditLogsToCSV)
+			auditLogs.GET("/entities/:type/:id", middleware.RequirePermission(db, "audit.view"), auditHandler.GetEntityHistory)
+		}
+
+		// Background jobs
+		jobRoutes := protected.Group("/jobs", userOnly)
+		{
+			jobRoutes.GET("", jobHandler.ListJobs)
+			jobRoutes.GET("/:id", jobHandler.GetJob)
+			jobRoutes.GET("/:id/download", jobHandler.DownloadJobResult)
+		}
+
 		// Notifications
 		notifications := protected.Group("/notifications")
 		{
-			notifications.POST("/test", settingsHandler.SendTestNotification)
-			notifications.POST("/check-low-stock", settingsHandler.CheckLowStock)
-			notifications.GET("/history", settingsHandler.GetNotificationHistory)
+			notifications.POST("/test", middleware.RequirePermission(db, "notification.send"), settingsHandler.SendTestNotification)
+			notifications.POST("/check-low-stock", middleware.RequirePermission(db, "notification.send"), settingsHandler.CheckLowStock)
+			notifications.GET("/history", middleware.RequirePermission(db, "notification.send"), settingsHandler.GetNotificationHistory)
+
+			inbox := notifications.Group("/inbox", userOnly)
+			{
+				inbox.GET("", inboxHandler.ListInbox)
+				inbox.GET("/unread-count", inboxHandler.GetUnreadCount)
+				inbox.POST("/read-all", inboxHandler.MarkAllRead)
+				inbox.POST("/:id/read", inboxHandler.MarkRead)
+				inbox.DELETE("/:id", inboxHandler.DeleteNotification)
+			}
+
+			outbox := notifications.Group("/outbox", middleware.RequirePermission(db, "notification.manage"))
+			{
+				outbox.GET("", outboxHandler.ListOutbox)
+				outbox.POST("/requeue-failed", middleware.AuditAction(db, handlers.AuditOutbox, "requeue"), outboxHandler.RequeueFailed)
+				outbox.POST("/:id/requeue", middleware.AuditAction(db, handlers.AuditOutboxMsg, "requeue"), outboxHandler.RequeueMessage)
+			}
 		}
 
+		// Real-time events (Server-Sent Events)
+		protected.GET("/stream", streamHandler.Stream)
+
 		// Additional routes for other modules can be added here
 		// - Employees
 		// - Leads
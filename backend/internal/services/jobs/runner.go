@@ -0,0 +1,513 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/notification"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+
+	pollInterval        = 5 * time.Second
+	maintenanceInterval = time.Minute
+	maxAttempts         = 3
+	// Result files are removed after this long; the job row is kept.
+	resultRetention = 7 * 24 * time.Hour
+)
+
+// A running job holds a lease that its worker renews every leaseRenewal. Once
+// the lease has lapsed the worker is assumed to have crashed and the job is
+// queued again.
+var (
+	leaseDuration = 2 * time.Minute
+	leaseRenewal  = 30 * time.Second
+)
+
+// ErrUnknownType is returned by Enqueue when no handler is registered for the job type.
+var ErrUnknownType = errors.New("unknown job type")
+
+// ProgressFunc reports completion of the running job as a percentage.
+type ProgressFunc func(percent int)
+
+// Handler performs one job and returns what should be stored as its result.
+type Handler func(ctx context.Context, job *models.Job, progress ProgressFunc) (*Result, error)
+
+// Result is the outcome of a job. Data, when set, is written to disk and can be
+// downloaded; Summary is stored as JSON on the job row.
+type Result struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+	Summary     interface{}
+}
+
+// Runner is a Postgres-backed job queue. Workers claim pending jobs with
+// FOR UPDATE SKIP LOCKED, so several API instances can share one queue.
+type Runner struct {
+	db         *gorm.DB
+	notifier   *notification.NotificationService
+	workers    int
+	storageDir string
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRunner constructs a runner. Register handlers, then call Start.
+func NewRunner(db *gorm.DB, notifier *notification.NotificationService, workers int, storageDir string) *Runner {
+	if workers < 1 {
+		workers = 1
+	}
+	if strings.TrimSpace(storageDir) == "" {
+		storageDir = "./storage/jobs"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		db:         db,
+		notifier:   notifier,
+		workers:    workers,
+		storageDir: storageDir,
+		handlers:   make(map[string]Handler),
+		ctx:        ctx,
+		cancel:     cancel,
+		wake:       make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Register installs the handler for a job type.
+func (r *Runner) Register(jobType string, handler Handler) {
+	r.mu.Lock()
+	r.handlers[jobType] = handler
+	r.mu.Unlock()
+}
+
+func (r *Runner) handler(jobType string) (Handler, bool) {
+	r.mu.RLock()
+	handler, ok := r.handlers[jobType]
+	r.mu.RUnlock()
+	return handler, ok
+}
+
+// Enqueue stores a pending job for userID and wakes an idle worker.
+func (r *Runner) Enqueue(jobType string, userID uint, payload interface{}) (*models.Job, error) {
+	if _, ok := r.handler(jobType); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode job payload: %w", err)
+	}
+
+	job := models.Job{
+		Type:        jobType,
+		Status:      StatusPending,
+		Payload:     string(encoded),
+		Result:      "null",
+		RunAfter:    time.Now(),
+		CreatedByID: userID,
+	}
+	if err := r.db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return &job, nil
+}
+
+// ResultPath returns the absolute location of a job's result file.
+func (r *Runner) ResultPath(job *models.Job) string {
+	if job.FilePath == "" {
+		return ""
+	}
+	return filepath.Join(r.storageDir, job.FilePath)
+}
+
+// Start launches the workers and the maintenance loop.
+func (r *Runner) Start() {
+	r.maintain()
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.maintain()
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the workers to finish and waits for them or for ctx to expire.
+func (r *Runner) Stop(ctx context.Context) {
+	close(r.quit)
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.cancel()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.quit:
+			return
+		default:
+		}
+
+		job, err := r.claim()
+		if err != nil {
+			log.Printf("[jobs] failed to claim job: %v", err)
+		}
+		if job != nil {
+			r.execute(job)
+			continue
+		}
+
+		select {
+		case <-r.wake:
+		case <-ticker.C:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// claim marks the oldest runnable job as running and returns it, or nil when the queue is empty.
+func (r *Runner) claim() (*models.Job, error) {
+	var claimed *models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var job models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_after <= ?", StatusPending, time.Now()).
+			Order("id ASC").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		lease := now.Add(leaseDuration)
+		job.Status = StatusRunning
+		job.Progress = 0
+		job.Attempts++
+		job.StartedAt = &now
+		job.LeaseExpiresAt = &lease
+		job.Error = ""
+		if err := tx.Model(&job).Updates(map[string]interface{}{
+			"status":           job.Status,
+			"progress":         job.Progress,
+			"attempts":         job.Attempts,
+			"started_at":       job.StartedAt,
+			"lease_expires_at": job.LeaseExpiresAt,
+			"error":            job.Error,
+		}).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+// owned limits an update to the job while this worker still holds it: a job
+// requeued after its lease lapsed has been claimed again with more attempts.
+func owned(db *gorm.DB, job *models.Job) *gorm.DB {
+	return db.Model(&models.Job{}).Where("id = ? AND status = ? AND attempts = ?", job.ID, StatusRunning, job.Attempts)
+}
+
+// renewLease extends the job's lease every leaseRenewal until ctx is done. It
+// calls lost when the job no longer belongs to this worker.
+func (r *Runner) renewLease(ctx context.Context, job *models.Job, lost func()) {
+	ticker := time.NewTicker(leaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := owned(r.db, job).Update("lease_expires_at", time.Now().Add(leaseDuration))
+		if result.Error != nil {
+			log.Printf("[jobs] failed to renew lease of job %d: %v", job.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			log.Printf("[jobs] job %d lost its lease, stopping it", job.ID)
+			lost()
+			return
+		}
+	}
+}
+
+func (r *Runner) execute(job *models.Job) {
+	handler, ok := r.handler(job.Type)
+	if !ok {
+		r.fail(job, fmt.Errorf("%w: %s", ErrUnknownType, job.Type))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	go r.renewLease(ctx, job, cancel)
+
+	lastProgress := 0
+	progress := func(percent int) {
+		if percent < 0 {
+			percent = 0
+		}
+		if percent > 99 {
+			percent = 99
+		}
+		if percent == lastProgress {
+			return
+		}
+		lastProgress = percent
+		if err := owned(r.db, job).Update("progress", percent).Error; err != nil {
+			log.Printf("[jobs] failed to update progress of job %d: %v", job.ID, err)
+		}
+	}
+
+	result, err := r.run(ctx, handler, job, progress)
+	if err != nil {
+		r.fail(job, err)
+		return
+	}
+	r.complete(job, result)
+}
+
+// run calls the handler, turning a panic into a job failure.
+func (r *Runner) run(ctx context.Context, handler Handler, job *models.Job, progress ProgressFunc) (result *Result, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job, progress)
+}
+
+func (r *Runner) complete(job *models.Job, result *Result) {
+	updates := map[string]interface{}{
+		"status":           StatusCompleted,
+		"progress":         100,
+		"finished_at":      time.Now(),
+		"lease_expires_at": nil,
+	}
+
+	if result != nil {
+		summary, err := json.Marshal(result.Summary)
+		if err != nil {
+			r.fail(job, fmt.Errorf("encode job result: %w", err))
+			return
+		}
+		updates["result"] = string(summary)
+
+		if result.Data != nil {
+			relative, err := r.writeResult(job.ID, result.FileName, result.Data)
+			if err != nil {
+				r.fail(job, err)
+				return
+			}
+			updates["file_name"] = result.FileName
+			updates["file_path"] = relative
+			updates["content_type"] = result.ContentType
+			updates["file_size"] = int64(len(result.Data))
+		}
+	}
+
+	saved := owned(r.db, job).Updates(updates)
+	if saved.Error != nil {
+		log.Printf("[jobs] failed to mark job %d completed: %v", job.ID, saved.Error)
+		return
+	}
+	if saved.RowsAffected == 0 {
+		log.Printf("[jobs] job %d finished after losing its lease; result discarded", job.ID)
+		return
+	}
+	r.notify(job)
+}
+
+func (r *Runner) fail(job *models.Job, cause error) {
+	log.Printf("[jobs] job %d (%s) failed: %v", job.ID, job.Type, cause)
+	saved := owned(r.db, job).Updates(map[string]interface{}{
+		"status":           StatusFailed,
+		"error":            cause.Error(),
+		"finished_at":      time.Now(),
+		"lease_expires_at": nil,
+	})
+	if saved.Error != nil {
+		log.Printf("[jobs] failed to mark job %d failed: %v", job.ID, saved.Error)
+		return
+	}
+	if saved.RowsAffected == 0 {
+		return
+	}
+	job.Status = StatusFailed
+	job.Error = cause.Error()
+	r.notify(job)
+}
+
+func (r *Runner) writeResult(jobID uint, fileName string, data []byte) (string, error) {
+	name := filepath.Base(strings.TrimSpace(fileName))
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		name = "result"
+	}
+	relative := filepath.Join(fmt.Sprintf("%d", jobID), name)
+	target := filepath.Join(r.storageDir, relative)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return "", fmt.Errorf("create job storage: %w", err)
+	}
+	if err := os.WriteFile(target, data, 0o640); err != nil {
+		return "", fmt.Errorf("write job result: %w", err)
+	}
+	return relative, nil
+}
+
+// notify queues an email to the job owner and records the outcome in the notification history.
+func (r *Runner) notify(job *models.Job) {
+	var user models.User
+	if err := r.db.First(&user, job.CreatedByID).Error; err != nil {
+		log.Printf("[jobs] failed to load owner of job %d: %v", job.ID, err)
+		return
+	}
+
+	var current models.Job
+	if err := r.db.First(&current, job.ID).Error; err != nil {
+		log.Printf("[jobs] failed to reload job %d: %v", job.ID, err)
+		return
+	}
+
+	title := fmt.Sprintf("Job #%d %s %s", current.ID, jobLabel(current.Type), current.Status)
+	message := fmt.Sprintf("Your %s job #%d has %s.", jobLabel(current.Type), current.ID, current.Status)
+	if current.Status == StatusFailed {
+		message += "\n\nError: " + current.Error
+	} else if current.FilePath != "" {
+		message += fmt.Sprintf("\n\nDownload %s from /api/v1/jobs/%d/download.", current.FileName, current.ID)
+	}
+
+	emailSent := false
+	if r.notifier != nil && strings.TrimSpace(user.Email) != "" {
+		if _, err := r.notifier.Outbox.QueueEmail(notification.EmailData{
+			To:      user.Email,
+			Subject: title,
+			Body:    message,
+			IsHTML:  false,
+		}, "job", &user.ID); err != nil {
+			log.Printf("[jobs] failed to queue email for owner of job %d: %v", job.ID, err)
+		} else {
+			emailSent = true
+		}
+	}
+
+	if err := r.db.Create(&models.NotificationHistory{
+		UserID:    user.ID,
+		Type:      "job",
+		Title:     title,
+		Message:   message,
+		EmailSent: emailSent,
+	}).Error; err != nil {
+		log.Printf("[jobs] failed to record notification history for job %d: %v", job.ID, err)
+	}
+}
+
+func jobLabel(jobType string) string {
+	return strings.ReplaceAll(jobType, "_", " ")
+}
+
+// maintain requeues jobs whose lease has lapsed and removes expired result files
+// and import previews.
+func (r *Runner) maintain() {
+	now := time.Now()
+	// Jobs claimed before leases existed have none; they are judged by updated_at.
+	abandoned := r.db.Where("lease_expires_at < ? OR (lease_expires_at IS NULL AND updated_at < ?)", now, now.Add(-leaseDuration))
+
+	if err := r.db.Model(&models.Job{}).
+		Where("status = ? AND attempts < ?", StatusRunning, maxAttempts).
+		Where(abandoned).
+		Updates(map[string]interface{}{"status": StatusPending, "run_after": now, "lease_expires_at": nil}).Error; err != nil {
+		log.Printf("[jobs] failed to requeue stale jobs: %v", err)
+	}
+	if err := r.db.Model(&models.Job{}).
+		Where("status = ? AND attempts >= ?", StatusRunning, maxAttempts).
+		Where(abandoned).
+		Updates(map[string]interface{}{
+			"status":           StatusFailed,
+			"error":            "job abandoned by worker",
+			"finished_at":      now,
+			"lease_expires_at": nil,
+		}).Error; err != nil {
+		log.Printf("[jobs] failed to fail stale jobs: %v", err)
+	}
+
+	// Import previews hold every parsed row; drop them once they can no longer be committed.
+	if err := r.db.Unscoped().Where("expires_at < ?", now).Delete(&models.ImportBatch{}).Error; err != nil {
+		log.Printf("[jobs] failed to purge expired import previews: %v", err)
+	}
+
+	var expired []models.Job
+	if err := r.db.
+		Where("file_path <> '' AND finished_at < ?", time.Now().Add(-resultRetention)).
+		Find(&expired).Error; err != nil {
+		log.Printf("[jobs] failed to load expired job results: %v", err)
+		return
+	}
+	for i := range expired {
+		if err := os.RemoveAll(filepath.Dir(r.ResultPath(&expired[i]))); err != nil {
+			log.Printf("[jobs] failed to remove result of job %d: %v", expired[i].ID, err)
+			continue
+		}
+		r.db.Model(&expired[i]).Update("file_path", "")
+	}
+}
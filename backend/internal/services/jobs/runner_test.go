@@ -0,0 +1,169 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"tatapps/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestRunner(t *testing.T) *Runner {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Job{}, &models.NotificationHistory{}, &models.ImportBatch{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Email: "owner@example.com", Password: "x", FullName: "Owner", IsActive: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	renewal := leaseRenewal
+	leaseRenewal = 10 * time.Millisecond
+	t.Cleanup(func() { leaseRenewal = renewal })
+	return NewRunner(db, nil, 1, t.TempDir())
+}
+
+func (r *Runner) reload(t *testing.T, id uint) models.Job {
+	t.Helper()
+	var job models.Job
+	if err := r.db.First(&job, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestMaintainRequeuesOnlyLapsedLeases(t *testing.T) {
+	r := newTestRunner(t)
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	jobs := map[string]*models.Job{
+		"lapsed":    {Type: "test", Status: StatusRunning, Attempts: 1, LeaseExpiresAt: &past},
+		"held":      {Type: "test", Status: StatusRunning, Attempts: 1, LeaseExpiresAt: &future},
+		"legacy":    {Type: "test", Status: StatusRunning, Attempts: 1},
+		"exhausted": {Type: "test", Status: StatusRunning, Attempts: maxAttempts, LeaseExpiresAt: &past},
+	}
+	for _, job := range jobs {
+		job.Payload, job.Result, job.CreatedByID = "{}", "null", 1
+		if err := r.db.Create(job).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A long job with a held lease has not reported progress for a long time.
+	r.db.Model(jobs["held"]).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+	r.db.Model(jobs["legacy"]).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+	r.maintain()
+
+	want := map[string]string{"lapsed": StatusPending, "held": StatusRunning, "legacy": StatusPending, "exhausted": StatusFailed}
+	for name, status := range want {
+		if got := r.reload(t, jobs[name].ID).Status; got != status {
+			t.Errorf("%s job: expected %s, got %s", name, status, got)
+		}
+	}
+}
+
+func TestRunningJobRenewsItsLease(t *testing.T) {
+	r := newTestRunner(t)
+	r.Register("test", func(ctx context.Context, job *models.Job, progress ProgressFunc) (*Result, error) {
+		claimed := *job.LeaseExpiresAt
+		deadline := time.After(2 * time.Second)
+		for {
+			select {
+			case <-deadline:
+				return nil, fmt.Errorf("lease was never renewed")
+			case <-time.After(5 * time.Millisecond):
+			}
+			var current models.Job
+			r.db.First(&current, job.ID)
+			if current.LeaseExpiresAt != nil && current.LeaseExpiresAt.After(claimed) {
+				return &Result{Summary: "renewed"}, nil
+			}
+		}
+	})
+	queued, err := r.Enqueue("test", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := r.claim()
+	if err != nil || job == nil || job.ID != queued.ID {
+		t.Fatalf("claim returned %v, %v", job, err)
+	}
+	r.execute(job)
+
+	done := r.reload(t, job.ID)
+	if done.Status != StatusCompleted || done.LeaseExpiresAt != nil {
+		t.Fatalf("expected a completed job without lease, got %s (%s)", done.Status, done.Error)
+	}
+}
+
+func TestJobStopsWhenItsLeaseIsLost(t *testing.T) {
+	r := newTestRunner(t)
+	started := make(chan struct{})
+	r.Register("test", func(ctx context.Context, job *models.Job, progress ProgressFunc) (*Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if _, err := r.Enqueue("test", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	job, err := r.claim()
+	if err != nil || job == nil {
+		t.Fatalf("claim returned %v, %v", job, err)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		r.execute(job)
+		close(finished)
+	}()
+	<-started
+
+	// Another worker requeued and claimed the job after the lease lapsed.
+	r.db.Model(&models.Job{}).Where("id = ?", job.ID).Update("attempts", job.Attempts+1)
+
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job kept running after losing its lease")
+	}
+	current := r.reload(t, job.ID)
+	if current.Status != StatusRunning || current.Error != "" {
+		t.Fatalf("stale worker overwrote the job: %s (%s)", current.Status, current.Error)
+	}
+}
+
+func TestMaintainPurgesExpiredImportPreviews(t *testing.T) {
+	r := newTestRunner(t)
+	batches := map[string]time.Time{
+		"expired": time.Now().Add(-time.Minute),
+		"live":    time.Now().Add(time.Hour),
+	}
+	for token, expiresAt := range batches {
+		batch := models.ImportBatch{Token: token, Rows: "[]", Summary: "{}", ExpiresAt: expiresAt, CreatedByID: 1}
+		if err := r.db.Create(&batch).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r.maintain()
+
+	var tokens []string
+	r.db.Unscoped().Model(&models.ImportBatch{}).Pluck("token", &tokens)
+	if len(tokens) != 1 || tokens[0] != "live" {
+		t.Fatalf("expected only the live preview to remain, got %v", tokens)
+	}
+}
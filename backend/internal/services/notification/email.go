@@ -3,6 +3,8 @@ package notification
 import (
 	"crypto/tls"
 	"fmt"
+	"html"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -22,6 +24,9 @@ type EmailData struct {
 	Subject string
 	Body    string
 	IsHTML  bool
+	// Sensitive marks a body carrying a secret such as a reset link; the
+	// outbox keeps it for delivery but never lists it.
+	Sensitive bool
 }
 
 func (s *EmailService) SendEmail(data EmailData) error {
@@ -56,6 +61,11 @@ func (s *EmailService) SendEmail(data EmailData) error {
 
 // Email Templates
 func (s *EmailService) SendWelcomeEmail(to, name string) error {
+	return s.SendEmail(WelcomeEmail(to, name))
+}
+
+// WelcomeEmail renders the welcome email, e.g. for the outbox.
+func WelcomeEmail(to, name string) EmailData {
 	body := fmt.Sprintf(`
 		<html>
 		<body>
@@ -67,14 +77,98 @@ func (s *EmailService) SendWelcomeEmail(to, name string) error {
 			<p>Best regards,<br>TatApps Team</p>
 		</body>
 		</html>
-	`, name)
+	`, html.EscapeString(name))
 
-	return s.SendEmail(EmailData{
+	return EmailData{
 		To:      to,
 		Subject: "Welcome to TatApps",
 		Body:    body,
 		IsHTML:  true,
-	})
+	}
+}
+
+func (s *EmailService) SendInviteEmail(to, inviterName, roleName, link string, expiresAt time.Time) error {
+	return s.SendEmail(InviteEmail(to, inviterName, roleName, link, expiresAt))
+}
+
+// InviteEmail renders the invitation email. The body holds the invite link.
+func InviteEmail(to, inviterName, roleName, link string, expiresAt time.Time) EmailData {
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>You're invited to TatApps</h2>
+			<p>%s invited you to join TatApps as <strong>%s</strong>.</p>
+			<p><a href="%s">Accept the invitation and create your account</a></p>
+			<p>This invitation expires on %s.</p>
+			<br>
+			<p>Best regards,<br>TatApps Team</p>
+		</body>
+		</html>
+	`, html.EscapeString(inviterName), html.EscapeString(roleName), link, expiresAt.Format("02 Jan 2006 15:04 MST"))
+
+	return EmailData{
+		To:        to,
+		Subject:   "Invitation to TatApps",
+		Body:      body,
+		IsHTML:    true,
+		Sensitive: true,
+	}
+}
+
+func (s *EmailService) SendPasswordResetEmail(to, name, link string, expiresAt time.Time) error {
+	return s.SendEmail(PasswordResetEmail(to, name, link, expiresAt))
+}
+
+// PasswordResetEmail renders the password reset email. The body holds the reset link.
+func PasswordResetEmail(to, name, link string, expiresAt time.Time) EmailData {
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Reset your password</h2>
+			<p>Hi %s,</p>
+			<p>We received a request to reset your TatApps password.</p>
+			<p><a href="%s">Choose a new password</a></p>
+			<p>This link can be used once and expires on %s. If you did not request it, you can ignore this email.</p>
+			<br>
+			<p>Best regards,<br>TatApps Team</p>
+		</body>
+		</html>
+	`, html.EscapeString(name), link, expiresAt.Format("02 Jan 2006 15:04 MST"))
+
+	return EmailData{
+		To:        to,
+		Subject:   "Reset your TatApps password",
+		Body:      body,
+		IsHTML:    true,
+		Sensitive: true,
+	}
+}
+
+func (s *EmailService) SendAccountLockedEmail(to, name, ipAddress string, lockedUntil time.Time) error {
+	return s.SendEmail(AccountLockedEmail(to, name, ipAddress, lockedUntil))
+}
+
+// AccountLockedEmail renders the lockout notice, e.g. for the outbox.
+func AccountLockedEmail(to, name, ipAddress string, lockedUntil time.Time) EmailData {
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Your account has been temporarily locked</h2>
+			<p>Hi %s,</p>
+			<p>We locked your TatApps account after several failed sign-in attempts. The last attempt came from IP address <strong>%s</strong>.</p>
+			<p>You can sign in again after %s. If these attempts were not made by you, reset your password and contact your administrator.</p>
+			<br>
+			<p>Best regards,<br>TatApps Team</p>
+		</body>
+		</html>
+	`, html.EscapeString(name), html.EscapeString(ipAddress), lockedUntil.Format("02 Jan 2006 15:04 MST"))
+
+	return EmailData{
+		To:      to,
+		Subject: "Your TatApps account has been locked",
+		Body:    body,
+		IsHTML:  true,
+	}
 }
 
 func (s *EmailService) SendPOApprovalRequest(to, poNumber, requesterName string, amount float64) error {
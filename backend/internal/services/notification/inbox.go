@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"encoding/json"
+	"time"
+
+	"tatapps/internal/models"
+	"tatapps/internal/services/realtime"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// models.Notification holds both in-app notifications (type "system") and the
+// outbox of email and WhatsApp messages.
+const (
+	TypeSystem   = "system"
+	TypeEmail    = "email"
+	TypeWhatsApp = "whatsapp"
+	ChannelInApp = "in_app"
+
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusRead    = "read"
+)
+
+// InboxService writes system notifications into the in-app inbox of users.
+type InboxService struct {
+	parent *NotificationService
+	stream *realtime.Hub
+}
+
+func NewInboxService(parent *NotificationService) *InboxService {
+	return &InboxService{
+		parent: parent,
+	}
+}
+
+// SetStream makes new notifications show up on the live stream of their users.
+func (s *InboxService) SetStream(hub *realtime.Hub) {
+	s.stream = hub
+}
+
+// InboxMessage is one in-app notification. Data is stored as JSON and lets the
+// frontend link to the related record.
+type InboxMessage struct {
+	Module  string
+	Subject string
+	Message string
+	Data    map[string]interface{}
+	// ReminderKey, when set, delivers the message to each user only once; later
+	// messages with the same key are dropped, also when raised by another instance.
+	ReminderKey string
+}
+
+// Notify delivers the message to every given user. Zero and duplicate IDs are
+// ignored, as are users that already received msg.ReminderKey.
+func (s *InboxService) Notify(userIDs []uint, msg InboxMessage) error {
+	if s == nil || s.parent.db == nil {
+		return nil
+	}
+
+	data := "{}"
+	if len(msg.Data) > 0 {
+		encoded, err := json.Marshal(msg.Data)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+
+	now := time.Now()
+	seen := make(map[uint]struct{}, len(userIDs))
+	rows := make([]models.Notification, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == 0 {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		userID := id
+		row := models.Notification{
+			Type:    TypeSystem,
+			Channel: ChannelInApp,
+			Module:  msg.Module,
+			UserID:  &userID,
+			Subject: msg.Subject,
+			Message: msg.Message,
+			Data:    data,
+			Status:  StatusSent,
+			SentAt:  &now,
+		}
+		if msg.ReminderKey != "" {
+			key := msg.ReminderKey
+			row.ReminderKey = &key
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if msg.ReminderKey == "" {
+		if err := s.parent.db.Create(&rows).Error; err != nil {
+			return err
+		}
+	} else {
+		// One insert per user so that only the rows actually written are published.
+		created := rows[:0]
+		for _, row := range rows {
+			result := s.parent.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, row)
+			}
+		}
+		rows = created
+	}
+
+	for _, row := range rows {
+		s.stream.Publish(realtime.EventNotification, map[string]interface{}{
+			"id":         row.ID,
+			"module":     row.Module,
+			"subject":    row.Subject,
+			"message":    row.Message,
+			"created_at": row.CreatedAt,
+		}, realtime.Target{UserIDs: []uint{*row.UserID}})
+	}
+	return nil
+}
+
+// UsersWithPermission returns the active users whose role grants one of the
+// permissions. A non-zero warehouseID leaves out users of warehouse-scoped
+// roles that are not assigned to that warehouse.
+func (s *InboxService) UsersWithPermission(warehouseID uint, permissions ...string) ([]uint, error) {
+	query := s.parent.db.Table("users").
+		Joins("JOIN roles ON roles.id = users.role_id AND roles.deleted_at IS NULL").
+		Where("users.deleted_at IS NULL AND users.is_active = ?", true).
+		Where("roles.id IN (SELECT role_permissions.role_id FROM role_permissions JOIN permissions ON permissions.id = role_permissions.permission_id WHERE permissions.name IN ? AND permissions.deleted_at IS NULL)", permissions)
+	return pluckScopedUsers(query, warehouseID)
+}
+
+// LowStockSubscribers returns the active users that enabled low stock alerts and
+// can see the warehouse.
+func (s *InboxService) LowStockSubscribers(warehouseID uint) ([]uint, error) {
+	query := s.parent.db.Table("users").
+		Joins("JOIN roles ON roles.id = users.role_id AND roles.deleted_at IS NULL").
+		Joins("JOIN notification_settings ON notification_settings.user_id = users.id AND notification_settings.deleted_at IS NULL").
+		Where("users.deleted_at IS NULL AND users.is_active = ? AND notification_settings.enabled = ?", true, true)
+	return pluckScopedUsers(query, warehouseID)
+}
+
+func pluckScopedUsers(query *gorm.DB, warehouseID uint) ([]uint, error) {
+	if warehouseID != 0 {
+		query = query.Where("(roles.warehouse_scoped = ? OR users.id IN (SELECT user_id FROM user_warehouses WHERE warehouse_id = ? AND deleted_at IS NULL))", false, warehouseID)
+	}
+	var ids []uint
+	if err := query.Distinct().Pluck("users.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
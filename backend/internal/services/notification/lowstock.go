@@ -31,7 +31,9 @@ func ComputeLowStockEntries(items []models.InventoryItem) []LowStockEntry {
 	for _, item := range items {
 		quantity := item.Quantity
 		minStock := item.MinStock
-		hasSN := strings.TrimSpace(item.SN) != ""
+		// Serialised items keep one master row per warehouse whose Quantity
+		// already counts units in stock, so they are checked individually.
+		hasSN := strings.TrimSpace(item.SN) != "" && !item.IsSerialized
 		unit := strings.TrimSpace(item.Unit)
 		warehouseName := strings.TrimSpace(item.Warehouse.Name)
 
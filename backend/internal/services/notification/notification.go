@@ -16,6 +16,8 @@ type NotificationService struct {
 
 	Email    *EmailService
 	WhatsApp *WhatsAppService
+	Inbox    *InboxService
+	Outbox   *OutboxService
 }
 
 func NewNotificationService(cfg *config.Config, db *gorm.DB) *NotificationService {
@@ -25,6 +27,8 @@ func NewNotificationService(cfg *config.Config, db *gorm.DB) *NotificationServic
 	}
 	service.Email = NewEmailService(service)
 	service.WhatsApp = NewWhatsAppService(service)
+	service.Inbox = NewInboxService(service)
+	service.Outbox = NewOutboxService(service)
 	service.refreshSiteSetting()
 	return service
 }
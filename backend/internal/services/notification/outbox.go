@@ -0,0 +1,270 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"tatapps/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxPollInterval = 10 * time.Second
+	// outboxClaimLease hides a message from other workers while it is sent; a
+	// worker that dies mid-send releases it once the lease expires.
+	outboxClaimLease  = 2 * time.Minute
+	outboxBaseBackoff = time.Minute
+	outboxMaxBackoff  = time.Hour
+	outboxMaxRetries  = 3
+)
+
+// OutboxService persists outgoing email and WhatsApp messages as pending
+// notifications and sends them from a background worker, retrying failures.
+// Workers on several API instances share the queue.
+type OutboxService struct {
+	parent *NotificationService
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewOutboxService(parent *NotificationService) *OutboxService {
+	return &OutboxService{
+		parent: parent,
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+}
+
+// outboxData is stored in Notification.Data for outgoing messages.
+type outboxData struct {
+	HTML      bool `json:"html,omitempty"`
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// RedactedMessage replaces the body of sensitive messages when they are listed.
+const RedactedMessage = "[redacted]"
+
+// Redact hides the body of a message that carries a secret, such as a reset
+// code, invite link or temporary password. Delivery still uses the stored body.
+func Redact(row *models.Notification) {
+	var data outboxData
+	_ = json.Unmarshal([]byte(row.Data), &data)
+	if data.Sensitive {
+		row.Message = RedactedMessage
+	}
+}
+
+// QueueEmail stores an email for delivery. userID is the recipient, when known.
+func (s *OutboxService) QueueEmail(email EmailData, module string, userID *uint) (*models.Notification, error) {
+	data, _ := json.Marshal(outboxData{HTML: email.IsHTML, Sensitive: email.Sensitive})
+	return s.enqueue(models.Notification{
+		Type:    TypeEmail,
+		Channel: TypeEmail,
+		Module:  module,
+		UserID:  userID,
+		Email:   email.To,
+		Subject: email.Subject,
+		Message: email.Body,
+		Data:    string(data),
+	})
+}
+
+// QueueWhatsApp stores a WhatsApp message for delivery. userID is the recipient, when known.
+func (s *OutboxService) QueueWhatsApp(message WhatsAppMessage, module string, userID *uint) (*models.Notification, error) {
+	data, _ := json.Marshal(outboxData{Sensitive: message.Sensitive})
+	return s.enqueue(models.Notification{
+		Type:    TypeWhatsApp,
+		Channel: TypeWhatsApp,
+		Module:  module,
+		UserID:  userID,
+		Phone:   message.Phone,
+		Message: message.Message,
+		Data:    string(data),
+	})
+}
+
+func (s *OutboxService) enqueue(row models.Notification) (*models.Notification, error) {
+	now := time.Now()
+	row.Status = StatusPending
+	row.MaxRetries = outboxMaxRetries
+	row.NextAttemptAt = &now
+	if err := s.parent.db.Create(&row).Error; err != nil {
+		return nil, err
+	}
+	s.signal()
+	return &row, nil
+}
+
+// Requeue resets the retries of failed messages and sends them again. It
+// returns the number of messages queued.
+func (s *OutboxService) Requeue(query *gorm.DB) (int64, error) {
+	result := query.
+		Where("type IN ? AND status = ?", []string{TypeEmail, TypeWhatsApp}, StatusFailed).
+		Updates(map[string]interface{}{
+			"status":          StatusPending,
+			"retry_count":     0,
+			"next_attempt_at": time.Now(),
+			"error_msg":       "",
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		s.signal()
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *OutboxService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the delivery worker.
+func (s *OutboxService) Start() {
+	s.wg.Add(1)
+	go s.work()
+}
+
+// Stop signals the worker to finish and waits for it or for ctx to expire.
+func (s *OutboxService) Stop(ctx context.Context) {
+	close(s.quit)
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (s *OutboxService) work() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+
+		row, err := s.claim()
+		if err != nil {
+			log.Printf("[outbox] failed to claim message: %v", err)
+		}
+		if row != nil {
+			s.deliver(row)
+			continue
+		}
+
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// claim leases the oldest due message and returns it, or nil when none is due.
+func (s *OutboxService) claim() (*models.Notification, error) {
+	var claimed *models.Notification
+	err := s.parent.db.Transaction(func(tx *gorm.DB) error {
+		var row models.Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ? AND status = ? AND next_attempt_at <= ?", []string{TypeEmail, TypeWhatsApp}, StatusPending, time.Now()).
+			Order("next_attempt_at ASC, id ASC").
+			First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&row).Update("next_attempt_at", time.Now().Add(outboxClaimLease)).Error; err != nil {
+			return err
+		}
+		claimed = &row
+		return nil
+	})
+	return claimed, err
+}
+
+// deliver sends the message and records the outcome. Failures are retried with
+// backoff until MaxRetries retries were used up.
+func (s *OutboxService) deliver(row *models.Notification) {
+	err := s.send(row)
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		updates["status"] = StatusSent
+		updates["sent_at"] = now
+		updates["error_msg"] = ""
+		updates["next_attempt_at"] = nil
+	case row.RetryCount >= row.MaxRetries:
+		updates["status"] = StatusFailed
+		updates["error_msg"] = err.Error()
+		updates["next_attempt_at"] = nil
+		log.Printf("[outbox] %s message %d failed after %d attempts: %v", row.Type, row.ID, row.RetryCount+1, err)
+	default:
+		updates["retry_count"] = row.RetryCount + 1
+		updates["error_msg"] = err.Error()
+		updates["next_attempt_at"] = now.Add(outboxBackoff(row.RetryCount + 1))
+	}
+
+	if err := s.parent.db.Model(row).Updates(updates).Error; err != nil {
+		log.Printf("[outbox] failed to record message %d: %v", row.ID, err)
+	}
+}
+
+func (s *OutboxService) send(row *models.Notification) error {
+	switch row.Type {
+	case TypeEmail:
+		var data outboxData
+		_ = json.Unmarshal([]byte(row.Data), &data)
+		return s.parent.Email.SendEmail(EmailData{
+			To:      row.Email,
+			Subject: row.Subject,
+			Body:    row.Message,
+			IsHTML:  data.HTML,
+		})
+	case TypeWhatsApp:
+		return s.parent.WhatsApp.SendMessage(WhatsAppMessage{
+			Phone:   row.Phone,
+			Message: row.Message,
+		})
+	default:
+		return fmt.Errorf("unsupported notification type %q", row.Type)
+	}
+}
+
+// outboxBackoff is the delay before the given retry: one minute, doubling up to an hour.
+func outboxBackoff(retry int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < retry && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay
+}
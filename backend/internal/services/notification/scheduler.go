@@ -2,6 +2,7 @@ package notification
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strconv"
 	"strings"
@@ -21,7 +22,8 @@ type scheduledJob struct {
 }
 
 // LowStockScheduler polls user notification preferences and dispatches low stock
-// alerts according to their chosen schedule.
+// alerts according to their chosen schedule. It also reminds lead owners of due
+// follow-ups in their inbox.
 type LowStockScheduler struct {
 	db         *gorm.DB
 	notifier   *NotificationService
@@ -85,6 +87,9 @@ func (s *LowStockScheduler) Stop(ctx context.Context) {
 func (s *LowStockScheduler) tick() {
 	now := time.Now().UTC()
 
+	s.notifyLeadAssignments(now)
+	s.remindLeadFollowUps(now)
+
 	var settings []models.NotificationSetting
 	if err := s.db.
 		Where("enabled = ?", true).
@@ -126,19 +131,11 @@ func (s *LowStockScheduler) computeDueJobs(settings []models.NotificationSetting
 	due := make([]scheduledJob, 0, len(settings))
 	for i := range settings {
 		setting := &settings[i]
+		// The in-app inbox always receives the alert, so settings without
+		// WhatsApp or email channels are still scheduled.
 		if !setting.Enabled {
 			continue
 		}
-		if !setting.WhatsAppEnabled && !setting.EmailEnabled {
-			continue
-		}
-		if setting.WhatsAppEnabled && NormalizeWhatsAppRecipients(setting.WhatsAppNumber) == "" && !setting.EmailEnabled {
-			// No usable recipients.
-			continue
-		}
-		if setting.EmailEnabled && strings.TrimSpace(setting.EmailAddress) == "" && !setting.WhatsAppEnabled {
-			continue
-		}
 
 		loc := s.location(setting.TimeZone)
 		runTime, ok := s.nextRunTime(setting, now.In(loc))
@@ -229,10 +226,21 @@ func (s *LowStockScheduler) handleJob(job scheduledJob, entries []LowStockEntry,
 		return
 	}
 
+	if err := s.notifier.Inbox.Notify([]uint{job.setting.UserID}, InboxMessage{
+		Module:  "warehouse",
+		Subject: "Low Stock Alert - " + strconv.Itoa(len(entries)) + " Items",
+		Message: strconv.Itoa(len(entries)) + " items are at or below their minimum stock.",
+		Data:    map[string]interface{}{"items": entries},
+	}); err != nil {
+		log.Printf("[scheduler] failed to write low stock inbox notification for user %d: %v", job.setting.UserID, err)
+	}
+
+	// WhatsApp and email go through the outbox, which retries failed sends.
 	var (
-		errors       []string
-		whatsAppSent bool
-		emailSent    bool
+		errors         []string
+		whatsAppQueued bool
+		emailQueued    bool
+		userID         = job.setting.UserID
 	)
 
 	if job.setting.WhatsAppEnabled {
@@ -241,13 +249,13 @@ func (s *LowStockScheduler) handleJob(job scheduledJob, entries []LowStockEntry,
 			errors = append(errors, "WhatsApp: no valid phone numbers configured")
 		} else {
 			for _, phone := range recipients {
-				if err := s.notifier.WhatsApp.SendMessage(WhatsAppMessage{
+				if _, err := s.notifier.Outbox.QueueWhatsApp(WhatsAppMessage{
 					Phone:   phone,
 					Message: message,
-				}); err != nil {
+				}, "warehouse", &userID); err != nil {
 					errors = append(errors, "WhatsApp ("+phone+"): "+err.Error())
 				} else {
-					whatsAppSent = true
+					whatsAppQueued = true
 				}
 			}
 		}
@@ -258,23 +266,23 @@ func (s *LowStockScheduler) handleJob(job scheduledJob, entries []LowStockEntry,
 		if count := len(entries); count > 0 {
 			subject = subject + " - " + strconv.Itoa(count) + " Items"
 		}
-		if err := s.notifier.Email.SendEmail(EmailData{
+		if _, err := s.notifier.Outbox.QueueEmail(EmailData{
 			To:      job.setting.EmailAddress,
 			Subject: subject,
 			Body:    message,
 			IsHTML:  false,
-		}); err != nil {
+		}, "warehouse", &userID); err != nil {
 			errors = append(errors, "Email: "+err.Error())
 		} else {
-			emailSent = true
+			emailQueued = true
 		}
 	}
 
 	if len(errors) > 0 {
-		log.Printf("[scheduler] errors sending low stock notification for user %d: %v", job.setting.UserID, strings.Join(errors, "; "))
+		log.Printf("[scheduler] errors queueing low stock notification for user %d: %v", job.setting.UserID, strings.Join(errors, "; "))
 	}
 
-	if !whatsAppSent && !emailSent {
+	if !whatsAppQueued && !emailQueued {
 		return
 	}
 
@@ -283,8 +291,8 @@ func (s *LowStockScheduler) handleJob(job scheduledJob, entries []LowStockEntry,
 		Type:         "low_stock",
 		Title:        "Low Stock Alert",
 		Message:      message,
-		WhatsAppSent: whatsAppSent,
-		EmailSent:    emailSent,
+		WhatsAppSent: whatsAppQueued,
+		EmailSent:    emailQueued,
 	}).Error; err != nil {
 		log.Printf("[scheduler] failed to record notification history for user %d: %v", job.setting.UserID, err)
 	}
@@ -322,3 +330,121 @@ func (s *LowStockScheduler) location(tz string) *time.Location {
 	s.locCacheMu.Unlock()
 	return loaded
 }
+
+// leadReminderWindow bounds how far back due follow-ups are still reminded, so
+// old leads do not flood the inboxes on the first run.
+const leadReminderWindow = 7 * 24 * time.Hour
+
+// leadAssignmentWindow is how far back created or reassigned leads are announced
+// to their assignee.
+const leadAssignmentWindow = time.Hour
+
+// sentLeadReminders returns the reminder keys of lead notifications raised
+// since the given time. Deleted ones still count, so removing a notification
+// from the inbox does not bring it back. The unique (user_id, reminder_key)
+// index is what keeps instances from both sending one; this only skips work.
+func (s *LowStockScheduler) sentLeadReminders(since time.Time) (map[string]struct{}, error) {
+	var sent []string
+	if err := s.db.Unscoped().Model(&models.Notification{}).
+		Where("type = ? AND module = ? AND reminder_key IS NOT NULL AND created_at > ?", TypeSystem, "lead", since).
+		Pluck("reminder_key", &sent).Error; err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{}, len(sent))
+	for _, key := range sent {
+		keys[key] = struct{}{}
+	}
+	return keys, nil
+}
+
+func leadDisplayName(lead models.Lead) string {
+	if name := strings.TrimSpace(lead.CompanyName); name != "" {
+		return name
+	}
+	return lead.ContactPerson
+}
+
+// remindLeadFollowUps notifies the assignee of every open lead whose follow-up
+// date has passed. Each follow-up date is reminded once.
+func (s *LowStockScheduler) remindLeadFollowUps(now time.Time) {
+	var leads []models.Lead
+	if err := s.db.
+		Where("next_follow_up_date > ? AND next_follow_up_date <= ?", now.Add(-leadReminderWindow), now).
+		Where("status NOT IN ?", []string{"won", "lost"}).
+		Find(&leads).Error; err != nil {
+		log.Printf("[scheduler] failed to load due lead follow-ups: %v", err)
+		return
+	}
+	if len(leads) == 0 {
+		return
+	}
+
+	reminded, err := s.sentLeadReminders(now.Add(-leadReminderWindow))
+	if err != nil {
+		log.Printf("[scheduler] failed to load sent lead reminders: %v", err)
+		return
+	}
+
+	for _, lead := range leads {
+		key := fmt.Sprintf("follow_up:%d:%d", lead.ID, lead.NextFollowUpDate.Unix())
+		if _, ok := reminded[key]; ok {
+			continue
+		}
+		name := leadDisplayName(lead)
+		if err := s.notifier.Inbox.Notify([]uint{lead.AssignedToID}, InboxMessage{
+			Module:  "lead",
+			Subject: "Follow up: " + name,
+			Message: fmt.Sprintf("Lead %s (contact: %s) is due for a follow-up.", name, lead.ContactPerson),
+			Data: map[string]interface{}{
+				"lead_id":             lead.ID,
+				"next_follow_up_date": lead.NextFollowUpDate,
+			},
+			ReminderKey: key,
+		}); err != nil {
+			log.Printf("[scheduler] failed to remind follow-up of lead %d: %v", lead.ID, err)
+		}
+	}
+}
+
+// notifyLeadAssignments tells assignees about open leads that were created or
+// handed to them recently. Each lead is announced once per assignee.
+func (s *LowStockScheduler) notifyLeadAssignments(now time.Time) {
+	since := now.Add(-leadAssignmentWindow)
+	var leads []models.Lead
+	if err := s.db.
+		Where("updated_at > ?", since).
+		Where("status NOT IN ?", []string{"won", "lost"}).
+		Find(&leads).Error; err != nil {
+		log.Printf("[scheduler] failed to load recently assigned leads: %v", err)
+		return
+	}
+	if len(leads) == 0 {
+		return
+	}
+
+	announced, err := s.sentLeadReminders(since)
+	if err != nil {
+		log.Printf("[scheduler] failed to load sent lead assignments: %v", err)
+		return
+	}
+
+	for _, lead := range leads {
+		key := fmt.Sprintf("assigned:%d:%d", lead.ID, lead.AssignedToID)
+		if _, ok := announced[key]; ok {
+			continue
+		}
+		name := leadDisplayName(lead)
+		if err := s.notifier.Inbox.Notify([]uint{lead.AssignedToID}, InboxMessage{
+			Module:  "lead",
+			Subject: "Lead assigned: " + name,
+			Message: fmt.Sprintf("Lead %s (contact: %s) was assigned to you.", name, lead.ContactPerson),
+			Data: map[string]interface{}{
+				"lead_id": lead.ID,
+				"status":  lead.Status,
+			},
+			ReminderKey: key,
+		}); err != nil {
+			log.Printf("[scheduler] failed to notify assignee of lead %d: %v", lead.ID, err)
+		}
+	}
+}
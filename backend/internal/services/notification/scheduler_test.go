@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestScheduler(t *testing.T, db *gorm.DB) *LowStockScheduler {
+	t.Helper()
+	return NewLowStockScheduler(db, NewNotificationService(&config.Config{}, db))
+}
+
+func TestLeadNotificationsAreSentOnceAcrossInstances(t *testing.T) {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Project{}, &models.Lead{}, &models.Notification{}, &models.SiteSetting{}); err != nil {
+		t.Fatal(err)
+	}
+	seller := models.User{Email: "seller@example.com", Password: "x", FullName: "Seller", IsActive: true}
+	if err := db.Create(&seller).Error; err != nil {
+		t.Fatal(err)
+	}
+	due := time.Now().Add(-time.Hour)
+	lead := models.Lead{CompanyName: "Acme", ContactPerson: "Ana", Status: "new", AssignedToID: seller.ID, NextFollowUpDate: &due}
+	if err := db.Create(&lead).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Two API instances tick at the same time.
+	first, second := newTestScheduler(t, db), newTestScheduler(t, db)
+	now := time.Now().UTC()
+	for _, s := range []*LowStockScheduler{first, second, first} {
+		s.notifyLeadAssignments(now)
+		s.remindLeadFollowUps(now)
+	}
+
+	var subjects []string
+	db.Model(&models.Notification{}).Where("user_id = ?", seller.ID).Order("subject").Pluck("subject", &subjects)
+	if len(subjects) != 2 || subjects[0] != "Follow up: Acme" || subjects[1] != "Lead assigned: Acme" {
+		t.Fatalf("expected one assignment and one follow-up notification, got %v", subjects)
+	}
+
+	// An instance that loaded the sent keys before the other one wrote its row
+	// still cannot add a second notification.
+	raced := InboxMessage{Module: "lead", Subject: "Lead assigned: Acme", ReminderKey: fmt.Sprintf("assigned:%d:%d", lead.ID, seller.ID)}
+	if err := second.notifier.Inbox.Notify([]uint{seller.ID}, raced); err != nil {
+		t.Fatalf("duplicate reminder should be dropped quietly: %v", err)
+	}
+	var total int64
+	db.Model(&models.Notification{}).Where("user_id = ?", seller.ID).Count(&total)
+	if total != 2 {
+		t.Fatalf("expected the raced reminder to be dropped, got %d notifications", total)
+	}
+
+	// Handing the lead to someone else announces it to the new assignee.
+	buyer := models.User{Email: "buyer@example.com", Password: "x", FullName: "Buyer", IsActive: true}
+	if err := db.Create(&buyer).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&lead).Update("assigned_to_id", buyer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	second.notifyLeadAssignments(time.Now().UTC())
+	var count int64
+	db.Model(&models.Notification{}).Where("user_id = ? AND subject = ?", buyer.ID, "Lead assigned: Acme").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected the new assignee to be notified once, got %d", count)
+	}
+}
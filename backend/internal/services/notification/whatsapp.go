@@ -24,6 +24,9 @@ func NewWhatsAppService(parent *NotificationService) *WhatsAppService {
 type WhatsAppMessage struct {
 	Phone   string
 	Message string
+	// Sensitive marks a message carrying a secret such as a reset code; the
+	// outbox keeps it for delivery but never lists it.
+	Sensitive bool
 }
 
 type WARequest struct {
@@ -81,6 +84,27 @@ func (s *WhatsAppService) SendMessage(data WhatsAppMessage) error {
 }
 
 // WhatsApp Message Templates
+func (s *WhatsAppService) SendPasswordResetCode(phone, code string, validMinutes int) error {
+	return s.SendMessage(PasswordResetCodeMessage(phone, code, validMinutes))
+}
+
+// PasswordResetCodeMessage renders the WhatsApp message carrying a reset code.
+func PasswordResetCodeMessage(phone, code string, validMinutes int) WhatsAppMessage {
+	message := fmt.Sprintf(
+		"🔐 *TatApps Password Reset*\n\n"+
+			"Your reset code is *%s*.\n"+
+			"It is valid for %d minutes and can be used once.\n\n"+
+			"If you did not request this, ignore this message.",
+		code, validMinutes,
+	)
+
+	return WhatsAppMessage{
+		Phone:     phone,
+		Message:   message,
+		Sensitive: true,
+	}
+}
+
 func (s *WhatsAppService) SendPOApprovalRequest(phone, poNumber, requesterName string, amount float64) error {
 	message := fmt.Sprintf(`
 *🔔 PO Approval Required*
@@ -0,0 +1,291 @@
+// Package oidctest is a minimal OpenID Connect provider for local development
+// and automated tests of SSO login. It signs in whoever submits its login form
+// and must never be exposed publicly.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-oidc-key"
+
+// Values of the email_verified authorize parameter. Anything else asserts a
+// verified address.
+const (
+	EmailUnverified  = "false"
+	EmailNotAsserted = "omit"
+)
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	emailVerified string
+	name          string
+	groups        []string
+	expiresAt     time.Time
+}
+
+// Server is the provider. Issuer may be set after construction, for example
+// to the URL of an httptest.Server, as long as it happens before the first
+// request.
+type Server struct {
+	Issuer   string
+	ClientID string
+	Groups   string // default groups offered on the login form
+
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!doctype html>
+<html><body style="font-family:sans-serif;max-width:420px;margin:40px auto">
+<h2>Mock OIDC login</h2>
+<form method="post" action="/authorize">
+{{range $key, $value := .Params}}<input type="hidden" name="{{$key}}" value="{{$value}}">
+{{end}}<p><label>Email<br><input name="email" type="email" required style="width:100%"></label></p>
+<p><label>Email verified<br><select name="email_verified" style="width:100%">
+<option value="true">Verified</option>
+<option value="false">Not verified</option>
+<option value="omit">Not asserted</option>
+</select></label></p>
+<p><label>Name<br><input name="name" style="width:100%"></label></p>
+<p><label>Groups (comma-separated)<br><input name="groups" value="{{.Groups}}" style="width:100%"></label></p>
+<button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+// New returns a provider with a freshly generated signing key.
+func New(issuer, clientID, groups string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Issuer:   strings.TrimRight(issuer, "/"),
+		ClientID: clientID,
+		Groups:   groups,
+		key:      key,
+		codes:    make(map[string]authorization),
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc("/authorize", s.authorize)
+	s.mux.HandleFunc("/token", s.token)
+	s.mux.HandleFunc("/jwks", s.jwks)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile", "groups"},
+	})
+}
+
+// authorize shows the login form on GET. A GET with login_hint, or the form
+// POST, approves the request and redirects back with a code. The optional
+// email_verified parameter controls that claim in the issued ID token.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	if params.Get("response_type") != "code" || params.Get("client_id") != s.ClientID || params.Get("redirect_uri") == "" {
+		http.Error(w, "unsupported response_type, unknown client_id or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params.Get("code_challenge") == "" || params.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	email := params.Get("email")
+	if r.Method == http.MethodGet {
+		email = params.Get("login_hint")
+	}
+	if email == "" {
+		hidden := make(map[string]string)
+		for _, key := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			hidden[key] = params.Get(key)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginForm.Execute(w, map[string]interface{}{"Params": hidden, "Groups": s.Groups})
+		return
+	}
+
+	groupsValue := s.Groups
+	if _, ok := params["groups"]; ok {
+		groupsValue = params.Get("groups")
+	}
+	var groups []string
+	for _, group := range strings.Split(groupsValue, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	code, err := randomHex(16)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.codes[code] = authorization{
+		clientID:      params.Get("client_id"),
+		redirectURI:   params.Get("redirect_uri"),
+		codeChallenge: params.Get("code_challenge"),
+		nonce:         params.Get("nonce"),
+		email:         strings.ToLower(email),
+		emailVerified: params.Get("email_verified"),
+		name:          params.Get("name"),
+		groups:        groups,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	redirect, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", params.Get("state"))
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	auth, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	clientID := r.PostForm.Get("client_id")
+	if basicID, _, hasBasic := r.BasicAuth(); hasBasic {
+		clientID, _ = url.QueryUnescape(basicID)
+	}
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(auth.expiresAt):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown or expired code"})
+		return
+	case clientID != auth.clientID || r.PostForm.Get("redirect_uri") != auth.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "client_id or redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.codeChallenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	subject := sha256.Sum256([]byte(auth.email))
+	claims := jwt.MapClaims{
+		"iss":    s.Issuer,
+		"aud":    auth.clientID,
+		"sub":    hex.EncodeToString(subject[:8]),
+		"iat":    now.Unix(),
+		"exp":    now.Add(5 * time.Minute).Unix(),
+		"nonce":  auth.nonce,
+		"email":  auth.email,
+		"name":   auth.name,
+		"groups": auth.groups,
+	}
+	switch auth.emailVerified {
+	case EmailNotAsserted:
+	case EmailUnverified:
+		claims["email_verified"] = false
+	default:
+		claims["email_verified"] = true
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken, err := randomHex(16)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,142 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"tatapps/internal/config"
+	"tatapps/internal/services/oidc"
+	"tatapps/internal/services/oidc/oidctest"
+)
+
+const testRedirectURL = "http://app.test/api/v1/auth/oidc/callback"
+
+func newMockProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+	mock, err := oidctest.New("", "tatapps", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	mock.Issuer = server.URL
+
+	return mock, oidc.NewProvider(&config.Config{
+		OIDCIssuerURL:   server.URL,
+		OIDCClientID:    "tatapps",
+		OIDCRedirectURL: testRedirectURL,
+		OIDCScopes:      []string{"openid", "email", "profile", "groups"},
+		OIDCGroupsClaim: "groups",
+	})
+}
+
+// approve follows the authorization URL with a login hint and returns the
+// redirect back to the application.
+func approve(t *testing.T, authURL string, params url.Values) *url.URL {
+	t.Helper()
+	target, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func signIn(t *testing.T, provider *oidc.Provider, params url.Values) (*oidc.Claims, error) {
+	t.Helper()
+	ctx := context.Background()
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := approve(t, authURL, params)
+	if got := callback.Query().Get("state"); got != "state-1" {
+		t.Fatalf("state %q was not echoed back", got)
+	}
+	return provider.Exchange(ctx, callback.Query().Get("code"), verifier, "nonce-1")
+}
+
+func TestProviderExchange(t *testing.T) {
+	_, provider := newMockProvider(t)
+
+	claims, err := signIn(t, provider, url.Values{
+		"login_hint": {"Jane@Example.com"},
+		"name":       {"Jane"},
+		"groups":     {"managers, staff"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Email != "jane@example.com" || claims.Name != "Jane" || claims.Subject == "" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if len(claims.Groups) != 2 || claims.Groups[0] != "managers" || claims.Groups[1] != "staff" {
+		t.Fatalf("unexpected groups %v", claims.Groups)
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Fatal("expected a verified email")
+	}
+}
+
+func TestProviderEmailVerified(t *testing.T) {
+	_, provider := newMockProvider(t)
+
+	claims, err := signIn(t, provider, url.Values{"login_hint": {"a@example.com"}, "email_verified": {oidctest.EmailUnverified}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.EmailVerified == nil || *claims.EmailVerified {
+		t.Fatalf("expected email_verified false, got %v", claims.EmailVerified)
+	}
+
+	claims, err = signIn(t, provider, url.Values{"login_hint": {"a@example.com"}, "email_verified": {oidctest.EmailNotAsserted}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.EmailVerified != nil {
+		t.Fatalf("expected no email_verified claim, got %v", *claims.EmailVerified)
+	}
+}
+
+func TestProviderRejectsWrongNonceAndVerifier(t *testing.T) {
+	_, provider := newMockProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := approve(t, authURL, url.Values{"login_hint": {"a@example.com"}}).Query().Get("code")
+	if _, err := provider.Exchange(ctx, code, verifier, "other-nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken for a wrong nonce, got %v", err)
+	}
+
+	code = approve(t, authURL, url.Values{"login_hint": {"a@example.com"}}).Query().Get("code")
+	if _, err := provider.Exchange(ctx, code, "wrong-verifier", "nonce"); err == nil {
+		t.Fatal("expected the token endpoint to reject a wrong PKCE verifier")
+	}
+}
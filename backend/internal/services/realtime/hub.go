@@ -0,0 +1,300 @@
+// Package realtime pushes live events to connected clients. Events are sent
+// through Postgres NOTIFY, so every API instance receives them, whichever
+// instance made the change.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// Event types sent on the stream.
+const (
+	EventStockTransaction = "stock.transaction"
+	EventStockLow         = "stock.low"
+	EventStockImport      = "stock.import"
+	EventItemDeleted      = "stock.item_deleted"
+	EventNotification     = "notification.created"
+)
+
+// Events lists every event type a client can subscribe to.
+var Events = []string{
+	EventStockTransaction,
+	EventStockLow,
+	EventStockImport,
+	EventItemDeleted,
+	EventNotification,
+}
+
+const (
+	notifyChannel = "tatapps_events"
+	// maxPayload stays below the 8000 byte limit of NOTIFY payloads. Larger
+	// events are sent without data and flagged as truncated.
+	maxPayload       = 7500
+	subscriberBuffer = 64
+	maxReconnectWait = 30 * time.Second
+)
+
+// Target decides who receives an event. Events with user IDs only reach those
+// users; other events reach clients whose warehouse scope covers one of the
+// warehouses.
+type Target struct {
+	WarehouseIDs []uint `json:"warehouse_ids,omitempty"`
+	UserIDs      []uint `json:"user_ids,omitempty"`
+}
+
+type envelope struct {
+	Target
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// Message is one event as delivered to a subscriber.
+type Message struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// Filter selects the events of one subscriber.
+type Filter struct {
+	UserID uint
+	// Stock enables warehouse events; Restricted limits them to Warehouses.
+	Stock      bool
+	Restricted bool
+	Warehouses map[uint]struct{}
+	// Types limits the event types; empty means all.
+	Types map[string]struct{}
+}
+
+func (f Filter) matches(event envelope) bool {
+	if len(f.Types) > 0 {
+		if _, ok := f.Types[event.Type]; !ok {
+			return false
+		}
+	}
+	if len(event.UserIDs) > 0 {
+		for _, id := range event.UserIDs {
+			if id == f.UserID {
+				return true
+			}
+		}
+		return false
+	}
+	if !f.Stock {
+		return false
+	}
+	if !f.Restricted {
+		return true
+	}
+	for _, id := range event.WarehouseIDs {
+		if _, ok := f.Warehouses[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives matching events until it is closed. C is closed when
+// the subscriber falls too far behind or the hub stops.
+type Subscription struct {
+	C      <-chan Message
+	events chan Message
+	filter Filter
+}
+
+// Hub publishes events through Postgres and fans them out to local subscribers.
+type Hub struct {
+	db  *gorm.DB
+	dsn string
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	sequence    atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewHub constructs a hub listening with a dedicated connection to dsn. Call
+// Start to begin receiving events.
+func NewHub(db *gorm.DB, dsn string) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Hub{
+		db:          db,
+		dsn:         dsn,
+		subscribers: make(map[*Subscription]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Publish sends an event to every instance. Call it after the change has been
+// committed. Failures are logged only; a nil hub ignores the event.
+func (h *Hub) Publish(eventType string, data interface{}, target Target) {
+	if h == nil || h.db == nil {
+		return
+	}
+
+	event := envelope{Target: target, Type: eventType}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("[realtime] failed to encode %s: %v", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[realtime] failed to encode %s: %v", eventType, err)
+		return
+	}
+	if len(payload) > maxPayload {
+		event.Data = nil
+		event.Truncated = true
+		payload, _ = json.Marshal(event)
+	}
+
+	if err := h.db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error; err != nil {
+		log.Printf("[realtime] failed to publish %s: %v", eventType, err)
+	}
+}
+
+// Subscribe registers a subscriber. Call Unsubscribe when the client goes away.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	events := make(chan Message, subscriberBuffer)
+	sub := &Subscription{C: events, events: events, filter: filter}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes the subscriber and closes its channel.
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove must be called with mu held.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+func (h *Hub) dispatch(payload string) {
+	var event envelope
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Printf("[realtime] ignoring malformed event: %v", err)
+		return
+	}
+
+	data := event.Data
+	switch {
+	case event.Truncated:
+		data = json.RawMessage(`{"truncated":true}`)
+	case len(data) == 0:
+		data = json.RawMessage(`{}`)
+	}
+	message := Message{ID: h.sequence.Add(1), Type: event.Type, Data: data}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if !sub.filter.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- message:
+		default:
+			// A client that cannot keep up is disconnected; it reconnects and reloads.
+			h.remove(sub)
+		}
+	}
+}
+
+// Start begins listening for events.
+func (h *Hub) Start() {
+	h.wg.Add(1)
+	go h.listen()
+}
+
+// Stop stops listening, closes every subscription and waits for the listener
+// or for ctx to expire.
+func (h *Hub) Stop(ctx context.Context) {
+	h.cancel()
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	h.mu.Lock()
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+	h.mu.Unlock()
+}
+
+// listen keeps a LISTEN connection open, reconnecting with backoff.
+func (h *Hub) listen() {
+	defer h.wg.Done()
+	wait := time.Second
+	for {
+		connected, err := h.listenOnce()
+		if h.ctx.Err() != nil {
+			return
+		}
+		if connected {
+			wait = time.Second
+		}
+		log.Printf("[realtime] listener stopped, reconnecting in %s: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-h.ctx.Done():
+			return
+		}
+		if wait *= 2; wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+	}
+}
+
+func (h *Hub) listenOnce() (bool, error) {
+	conn, err := pgx.Connect(h.ctx, h.dsn)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(h.ctx, "LISTEN "+notifyChannel); err != nil {
+		return false, err
+	}
+	for {
+		notification, err := conn.WaitForNotification(h.ctx)
+		if err != nil {
+			return true, err
+		}
+		h.dispatch(notification.Payload)
+	}
+}
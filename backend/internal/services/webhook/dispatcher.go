@@ -0,0 +1,447 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tatapps/internal/config"
+	"tatapps/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	pollInterval = 5 * time.Second
+	// A claimed delivery is hidden from other workers for this long, so one
+	// abandoned by a crashed worker is retried once the lease runs out.
+	claimLease   = 2 * time.Minute
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	maxBodyBytes = 2048
+
+	SignatureHeader = "X-TatApps-Signature"
+	TimestampHeader = "X-TatApps-Timestamp"
+	EventHeader     = "X-TatApps-Event"
+	DeliveryHeader  = "X-TatApps-Delivery"
+)
+
+// Events a subscription can filter on. "*" and "prefix.*" patterns are allowed too.
+const (
+	EventTransactionCreated = "inventory.transaction.created"
+	EventTransactionDeleted = "inventory.transaction.deleted"
+	EventItemLowStock       = "inventory.item.low_stock"
+	EventPOCreated          = "po.created"
+	EventPOUpdated          = "po.updated"
+	EventPOApproved         = "po.approved"
+	EventPORejected         = "po.rejected"
+	EventEmployeeCreated    = "employee.created"
+	EventEmployeeUpdated    = "employee.updated"
+	EventEmployeeDeleted    = "employee.deleted"
+	EventPing               = "ping"
+)
+
+// Events lists every event name, in the order shown to admins.
+var Events = []string{
+	EventTransactionCreated,
+	EventTransactionDeleted,
+	EventItemLowStock,
+	EventPOCreated,
+	EventPOUpdated,
+	EventPOApproved,
+	EventPORejected,
+	EventEmployeeCreated,
+	EventEmployeeUpdated,
+	EventEmployeeDeleted,
+}
+
+// ValidFilter reports whether filter names a known event or matches at least one.
+func ValidFilter(filter string) bool {
+	for _, event := range Events {
+		if Matches(filter, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the subscription filter selects event.
+func Matches(filter, event string) bool {
+	switch {
+	case filter == "*":
+		return true
+	case strings.HasSuffix(filter, ".*"):
+		return strings.HasPrefix(event, strings.TrimSuffix(filter, "*"))
+	default:
+		return filter == event
+	}
+}
+
+// Sign returns the signature header value for a payload sent at timestamp.
+// Receivers recompute HMAC-SHA256 over "<timestamp>.<body>" with the secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a signing secret for a subscription.
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Backoff is the delay before retrying after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// envelope is the JSON body of every delivery.
+type envelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher queues events as webhook deliveries in Postgres and sends them
+// from background workers. Deliveries are claimed with FOR UPDATE SKIP LOCKED,
+// so several API instances can share the queue.
+type Dispatcher struct {
+	db          *gorm.DB
+	client      *http.Client
+	workers     int
+	maxAttempts int
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDispatcher constructs a dispatcher. Call Start to begin sending.
+func NewDispatcher(db *gorm.DB, cfg *config.Config) *Dispatcher {
+	workers := cfg.WebhookWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	timeout := time.Duration(cfg.WebhookTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Dispatcher{
+		db:          db,
+		client:      &http.Client{Timeout: timeout},
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Publish queues event for every active subscription whose filters match it.
+// Call it after the change has been committed. Failures are logged and never
+// reach the caller; a nil dispatcher ignores the event.
+func (d *Dispatcher) Publish(event string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := d.db.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		log.Printf("[webhook] failed to load subscriptions for %s: %v", event, err)
+		return
+	}
+	var matched []models.WebhookSubscription
+	for _, subscription := range subscriptions {
+		if subscribed(subscription, event) {
+			matched = append(matched, subscription)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	if _, err := d.enqueue(event, data, matched); err != nil {
+		log.Printf("[webhook] failed to queue %s: %v", event, err)
+	}
+}
+
+// Ping queues a test event for a single subscription, regardless of its filters.
+func (d *Dispatcher) Ping(subscription models.WebhookSubscription) (*models.WebhookDelivery, error) {
+	deliveries, err := d.enqueue(EventPing, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"message":         "Webhook test from TatApps",
+	}, []models.WebhookSubscription{subscription})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts.
+func (d *Dispatcher) Redeliver(delivery *models.WebhookDelivery) error {
+	if err := d.db.Model(delivery).Updates(map[string]interface{}{
+		"status":          StatusPending,
+		"attempts":        0,
+		"max_attempts":    d.maxAttempts,
+		"next_attempt_at": time.Now(),
+		"error":           "",
+	}).Error; err != nil {
+		return err
+	}
+	d.signal()
+	return nil
+}
+
+func (d *Dispatcher) enqueue(event string, data interface{}, subscriptions []models.WebhookSubscription) ([]models.WebhookDelivery, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	eventID := "evt_" + hex.EncodeToString(idBytes)
+	body, err := json.Marshal(envelope{
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			Event:          event,
+			Payload:        string(body),
+			Status:         StatusPending,
+			MaxAttempts:    d.maxAttempts,
+			NextAttemptAt:  now,
+		})
+	}
+	if err := d.db.Create(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	d.signal()
+	return deliveries, nil
+}
+
+func subscribed(subscription models.WebhookSubscription, event string) bool {
+	var filters []string
+	if err := json.Unmarshal([]byte(subscription.Events), &filters); err != nil {
+		return false
+	}
+	for _, filter := range filters {
+		if Matches(filter, event) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the delivery workers.
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Stop signals the workers to finish and waits for them or for ctx to expire.
+func (d *Dispatcher) Stop(ctx context.Context) {
+	close(d.quit)
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+
+		delivery, err := d.claim()
+		if err != nil {
+			log.Printf("[webhook] failed to claim delivery: %v", err)
+		}
+		if delivery != nil {
+			d.deliver(delivery)
+			continue
+		}
+
+		select {
+		case <-d.wake:
+		case <-ticker.C:
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// claim leases the oldest due delivery and returns it, or nil when none is due.
+func (d *Dispatcher) claim() (*models.WebhookDelivery, error) {
+	var claimed *models.WebhookDelivery
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var delivery models.WebhookDelivery
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, time.Now()).
+			Order("next_attempt_at ASC, id ASC").
+			First(&delivery).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+		delivery.NextAttemptAt = time.Now().Add(claimLease)
+		if err := tx.Model(&delivery).Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+		}).Error; err != nil {
+			return err
+		}
+		claimed = &delivery
+		return nil
+	})
+	return claimed, err
+}
+
+// deliver sends one attempt and records its outcome.
+func (d *Dispatcher) deliver(delivery *models.WebhookDelivery) {
+	var subscription models.WebhookSubscription
+	if err := d.db.First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		reason := "subscription no longer exists"
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			reason = err.Error()
+		}
+		d.record(delivery, 0, "", reason, 0, true)
+		return
+	}
+
+	started := time.Now()
+	status, body, err := d.send(subscription, delivery)
+	duration := time.Since(started).Milliseconds()
+	switch {
+	case err != nil:
+		d.record(delivery, status, body, err.Error(), duration, false)
+	case status < 200 || status >= 300:
+		d.record(delivery, status, body, fmt.Sprintf("endpoint responded with HTTP %d", status), duration, false)
+	default:
+		d.record(delivery, status, body, "", duration, false)
+	}
+}
+
+func (d *Dispatcher) send(subscription models.WebhookSubscription, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TatApps-Webhook/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	return resp.StatusCode, string(excerpt), nil
+}
+
+// record stores the attempt. Failed attempts are retried with backoff until
+// MaxAttempts is reached, unless final is set.
+func (d *Dispatcher) record(delivery *models.WebhookDelivery, status int, body, failure string, duration int64, final bool) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_attempt_at": now,
+		"response_status": status,
+		"response_body":   body,
+		"error":           failure,
+		"duration_ms":     duration,
+	}
+	switch {
+	case failure == "":
+		updates["status"] = StatusDelivered
+		updates["delivered_at"] = now
+	case final || delivery.Attempts >= delivery.MaxAttempts:
+		updates["status"] = StatusFailed
+		log.Printf("[webhook] delivery %d (%s) failed after %d attempts: %s", delivery.ID, delivery.Event, delivery.Attempts, failure)
+	default:
+		updates["next_attempt_at"] = now.Add(Backoff(delivery.Attempts))
+	}
+
+	if err := d.db.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("[webhook] failed to record delivery %d: %v", delivery.ID, err)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,17 +13,20 @@ type JWTClaims struct {
 	Email    string `json:"email"`
 	RoleID   uint   `json:"role_id"`
 	RoleName string `json:"role_name"`
+	// SessionID links the access token to its server-side session.
+	SessionID uint `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, email string, roleID uint, roleName string, secret string, expiration int) (string, error) {
+func GenerateToken(userID uint, email string, roleID uint, roleName string, sessionID uint, secret string, expiration time.Duration) (string, error) {
 	claims := JWTClaims{
-		UserID:   userID,
-		Email:    email,
-		RoleID:   roleID,
-		RoleName: roleName,
+		UserID:    userID,
+		Email:     email,
+		RoleID:    roleID,
+		RoleName:  roleName,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiration))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -46,3 +50,38 @@ func ValidateToken(tokenString string, secret string) (*JWTClaims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// preAuthAudience marks tokens that only prove the password step of a login.
+const preAuthAudience = "pre-auth"
+
+// GeneratePreAuthToken issues a short-lived token for the second login step. It is
+// signed with a derived key so it can never pass as an access token.
+func GeneratePreAuthToken(userID uint, secret string, expiration time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		Audience:  jwt.ClaimStrings{preAuthAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret + "|" + preAuthAudience))
+}
+
+// ValidatePreAuthToken returns the user ID of a valid pre-auth token.
+func ValidatePreAuthToken(tokenString string, secret string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret + "|" + preAuthAudience), nil
+	}, jwt.WithAudience(preAuthAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, err
+	}
+	if !token.Valid {
+		return 0, errors.New("invalid token")
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, errors.New("invalid token")
+	}
+	return uint(userID), nil
+}
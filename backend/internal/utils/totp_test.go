@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 appendix B test vectors.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestTOTPMatchesRFC6238 checks the codes against the RFC 6238 SHA-1 vectors;
+// the RFC lists 8 digits, of which a 6-digit code is the tail.
+func TestTOTPMatchesRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, now)
+		if !ok {
+			t.Errorf("T=%d: code %s was rejected", v.unix, v.code)
+			continue
+		}
+		if step != v.unix/totpPeriod {
+			t.Errorf("T=%d: matched step %d, want %d", v.unix, step, v.unix/totpPeriod)
+		}
+	}
+}
+
+// TestTOTPAcceptsOneStepOfSkew checks that codes one step away are accepted and
+// codes two steps away are not.
+func TestTOTPAcceptsOneStepOfSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{now.Add(-totpPeriod * time.Second), true},
+		{now.Add(totpPeriod * time.Second), true},
+		{now.Add(-2 * totpPeriod * time.Second), false},
+		{now.Add(2 * totpPeriod * time.Second), false},
+	}
+	for _, tc := range cases {
+		if _, ok := ValidateTOTP(rfc6238Secret, "050471", tc.at); ok != tc.want {
+			t.Errorf("at %s: accepted = %v, want %v", tc.at.Sub(now), ok, tc.want)
+		}
+	}
+}
+
+func TestTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	if _, ok := ValidateTOTP(rfc6238Secret, "05047", now); ok {
+		t.Error("a short code was accepted")
+	}
+	if _, ok := ValidateTOTP("not base32!", "050471", now); ok {
+		t.Error("a code was accepted for an invalid secret")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, " 050 471 ", now); !ok {
+		t.Error("a code with spaces was rejected")
+	}
+}
@@ -21,16 +21,52 @@ api.interceptors.request.use(
   }
 )
 
+// Refresh requests share one in-flight call so rotated tokens are not reused
+let refreshPromise = null
+
+function refreshAccessToken() {
+  const refreshToken = localStorage.getItem('refresh_token')
+  if (!refreshToken) {
+    return Promise.reject(new Error('No refresh token'))
+  }
+  if (!refreshPromise) {
+    refreshPromise = axios
+      .post(`${api.defaults.baseURL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token)
+        localStorage.setItem('refresh_token', response.data.refresh_token)
+        return response.data.token
+      })
+      .finally(() => {
+        refreshPromise = null
+      })
+  }
+  return refreshPromise
+}
+
 // Response interceptor - handle errors
 api.interceptors.response.use(
   (response) => {
     return response
   },
-  (error) => {
+  async (error) => {
     const status = error.response?.status
+    const original = error.config
+
+    if (status === 401 && original && !original._retry && !original.url?.startsWith('/auth/')) {
+      original._retry = true
+      try {
+        const token = await refreshAccessToken()
+        original.headers.Authorization = `Bearer ${token}`
+        return api(original)
+      } catch (refreshError) {
+        // fall through to the login redirect below
+      }
+    }
 
     if (status === 401) {
       localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
       if (window.location.pathname !== '/login') {
         window.location.href = '/login'
       }
@@ -47,4 +83,21 @@ api.interceptors.response.use(
   }
 )
 
+// getAllPages follows next_cursor through a paged list endpoint and returns
+// every row; use it where a screen filters the whole list client-side
+export async function getAllPages(url, config = {}) {
+  const rows = []
+  let cursor = ''
+  do {
+    const params = { ...config.params, page_size: 200 }
+    if (cursor) {
+      params.cursor = cursor
+    }
+    const response = await api.get(url, { ...config, params })
+    rows.push(...(response.data.data || []))
+    cursor = response.data.meta?.next_cursor || ''
+  } while (cursor)
+  return rows
+}
+
 export default api
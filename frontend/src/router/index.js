@@ -8,6 +8,12 @@ const routes = [
     component: () => import('@/views/auth/Login.vue'),
     meta: { guest: true }
   },
+  {
+    path: '/auth/callback',
+    name: 'AuthCallback',
+    component: () => import('@/views/auth/AuthCallback.vue'),
+    meta: { guest: true }
+  },
   {
     path: '/error/forbidden',
     name: 'ErrorForbidden',
@@ -319,7 +325,7 @@ router.beforeEach(async (to, from, next) => {
       await authStore.getProfile()
     } catch (error) {
       // If profile fetch fails, logout and redirect to login
-      authStore.logout()
+      authStore.clearSession()
       if (to.meta.requiresAuth) {
         return next('/login')
       }
@@ -20,18 +20,33 @@ export const useAuthStore = defineStore('auth', () => {
     return set
   })
 
+  function setSession(data) {
+    token.value = data.token
+    user.value = data.user
+    localStorage.setItem('token', data.token)
+    localStorage.setItem('refresh_token', data.refresh_token)
+  }
+
   async function login(email, password) {
     try {
       const response = await api.post('/auth/login', { email, password })
-      token.value = response.data.token
-      user.value = response.data.user
-      localStorage.setItem('token', response.data.token)
+      setSession(response.data)
       return true
     } catch (error) {
       throw error
     }
   }
 
+  // Exchanges the one-time ticket from the SSO callback for a session.
+  async function loginWithSsoTicket(ticket) {
+    const response = await api.post('/auth/oidc/exchange', { ticket })
+    if (!response.data.token) {
+      throw new Error('Two-factor verification is required for this account')
+    }
+    setSession(response.data)
+    return true
+  }
+
   async function register(userData) {
     try {
       const response = await api.post('/auth/register', userData)
@@ -51,10 +66,22 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
-  function logout() {
+  function clearSession() {
     user.value = null
     token.value = null
     localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+  }
+
+  async function logout() {
+    if (token.value) {
+      try {
+        await api.post('/auth/logout')
+      } catch (error) {
+        // the session is cleared locally either way
+      }
+    }
+    clearSession()
   }
 
   async function checkAuth() {
@@ -62,7 +89,7 @@ export const useAuthStore = defineStore('auth', () => {
       try {
         await getProfile()
       } catch (error) {
-        logout()
+        clearSession()
       }
     }
   }
@@ -97,9 +124,11 @@ export const useAuthStore = defineStore('auth', () => {
     isManager,
     permissionSet,
     login,
+    loginWithSsoTicket,
     register,
     getProfile,
     logout,
+    clearSession,
     checkAuth,
     hasPermission,
     hasAnyPermission,
@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref } from 'vue'
-import api from '@/api/axios'
+import api, { getAllPages } from '@/api/axios'
 
 const DEFAULT_TIMEZONE = 'WIB (+7)'
 
@@ -83,11 +83,11 @@ export const useHRStore = defineStore('hr', () => {
       const [divisionResponse, positionResponse, employeeResponse] = await Promise.all([
         api.get('/employees/divisions'),
         api.get('/employees/positions'),
-        api.get('/employees')
+        getAllPages('/employees')
       ])
       divisions.value = (divisionResponse.data?.data || []).map(normalizeDivision)
       positions.value = (positionResponse.data?.data || []).map(normalizePosition)
-      employees.value = (employeeResponse.data?.data || []).map(normalizeEmployee)
+      employees.value = employeeResponse.map(normalizeEmployee)
       hydrated.value = true
     } finally {
       loading.value = false
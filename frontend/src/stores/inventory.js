@@ -1,5 +1,5 @@
 import { defineStore } from 'pinia'
-import axios from '@/api/axios'
+import axios, { getAllPages } from '@/api/axios'
 
 export const useInventoryStore = defineStore('inventory', {
   state: () => ({
@@ -39,8 +39,7 @@ export const useInventoryStore = defineStore('inventory', {
       this.loading = true
       this.error = null
       try {
-        const response = await axios.get('/inventory')
-        this.items = response.data.data || []
+        this.items = await getAllPages('/inventory')
         return this.items
       } catch (error) {
         this.error = error.response?.data?.message || 'Failed to fetch items'
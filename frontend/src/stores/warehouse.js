@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref } from 'vue'
-import api from '@/api/axios'
+import api, { getAllPages } from '@/api/axios'
 
 export const useWarehouseStore = defineStore('warehouse', () => {
   const warehouses = ref([])
@@ -10,8 +10,7 @@ export const useWarehouseStore = defineStore('warehouse', () => {
   async function fetchWarehouses() {
     loading.value = true
     try {
-      const response = await api.get('/warehouses')
-      warehouses.value = response.data.data || []
+      warehouses.value = await getAllPages('/warehouses')
       return warehouses.value
     } catch (error) {
       console.error('Fetch warehouses error:', error)